@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"debezium_server/pkg/backup"
+	"debezium_server/pkg/cdc/avro"
+	"debezium_server/pkg/configtemplate"
+	debezium_client "debezium_server/pkg/debezium-client"
+	"debezium_server/pkg/redact"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+type varFlags map[string]string
+
+func (v varFlags) String() string {
+	return fmt.Sprint(map[string]string(v))
+}
+
+func (v varFlags) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("variable %q must be in the form key=value", s)
+	}
+	v[key] = value
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "render":
+		err = render(os.Args[2:])
+	case "export":
+		err = export(os.Args[2:])
+	case "import":
+		err = importArchive(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	log.Fatal(`usage:
+  connectorctl render -template FILE [-vars FILE] [-var key=value]...
+                      [-avro-registry URL [-avro-user-info USER:PASSWORD]]
+  connectorctl export -url URL[,URL] [-out FILE] [-format json|tar|tar.gz] [-connector NAME]...
+  connectorctl import -url URL[,URL] [-in FILE] [-connector NAME]... [-rename old=new]...
+                      [-override key=value]... [-vars FILE] [-var key=value]... [-no-offsets]`)
+}
+
+type listFlags []string
+
+func (l *listFlags) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlags) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// connectFlags are the flags shared by the commands that talk to Kafka Connect.
+type connectFlags struct {
+	urls     string
+	timeout  time.Duration
+	token    string
+	username string
+	password string
+}
+
+func (c *connectFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.urls, "url", os.Getenv("DEBEZIUM_BASE_URL"), "comma separated Kafka Connect worker URLs")
+	fs.DurationVar(&c.timeout, "timeout", 30*time.Second, "timeout of each Kafka Connect request")
+	fs.StringVar(&c.token, "token", os.Getenv("DEBEZIUM_TOKEN"), "bearer token for Kafka Connect")
+	fs.StringVar(&c.username, "user", os.Getenv("DEBEZIUM_USERNAME"), "basic auth user for Kafka Connect")
+	fs.StringVar(&c.password, "password", os.Getenv("DEBEZIUM_PASSWORD"), "basic auth password for Kafka Connect")
+}
+
+func (c *connectFlags) client() (*debezium_client.Client, error) {
+	if c.urls == "" {
+		return nil, errors.New("-url is required")
+	}
+
+	var opts []debezium_client.Option
+	switch {
+	case c.token != "":
+		opts = append(opts, debezium_client.WithBearerToken(c.token))
+	case c.username != "":
+		opts = append(opts, debezium_client.WithBasicAuth(c.username, c.password))
+	}
+	return debezium_client.New(strings.Split(c.urls, ","), c.timeout, opts...), nil
+}
+
+// export writes an archive of the connectors with their secrets, offsets
+// and statuses. The file is only readable by its owner.
+func export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var connect connectFlags
+	connect.register(fs)
+	out := fs.String("out", "-", "archive file, - for stdout")
+	formatName := fs.String("format", "", "json, tar or tar.gz; taken from the -out extension by default")
+	cluster := fs.String("cluster", "", "cluster name recorded in the archive")
+	var names listFlags
+	fs.Var(&names, "connector", "connector to export, may be repeated; all by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	format := backup.FormatFromPath(*out)
+	if *formatName != "" {
+		var err error
+		if format, err = backup.ParseFormat(*formatName); err != nil {
+			return err
+		}
+	}
+
+	client, err := connect.client()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	a, err := backup.Export(ctx, client, *cluster, names...)
+	if err != nil {
+		return err
+	}
+
+	if *out == "-" {
+		return backup.Write(os.Stdout, a, format)
+	}
+
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := backup.Write(f, a, format); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("exported %d connectors to %s", len(a.Connectors), *out)
+	return nil
+}
+
+// importArchive restores the connectors of an archive. Override values are
+// rendered as config templates, so secrets can come from the environment.
+func importArchive(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	var connect connectFlags
+	connect.register(fs)
+	in := fs.String("in", "-", "archive file, - for stdin")
+	varsPath := fs.String("vars", "", "JSON file with the variables of the overrides")
+	noOffsets := fs.Bool("no-offsets", false, "restore the configs only")
+	var names listFlags
+	fs.Var(&names, "connector", "connector to import, may be repeated; all by default")
+	rename := varFlags{}
+	fs.Var(rename, "rename", "new name of a connector in the form old=new, may be repeated")
+	overrides := varFlags{}
+	fs.Var(overrides, "override", "config override for every connector in the form key=value, may be repeated")
+	vars := varFlags{}
+	fs.Var(vars, "var", "variable override in the form key=value, may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	a, err := backup.Read(r)
+	if err != nil {
+		return err
+	}
+
+	fileVars := map[string]string{}
+	if *varsPath != "" {
+		if fileVars, err = configtemplate.LoadVars(*varsPath); err != nil {
+			return err
+		}
+	}
+	rendered, problems := configtemplate.NewRenderer(fileVars, configtemplate.Unrestricted()).WithVars(vars).RenderConfig(overrides)
+	if len(problems) > 0 {
+		return &configtemplate.ValidationError{Problems: problems}
+	}
+
+	// Secrets are masked like the server masks them, so an archive from
+	// GET .../backup needs the same overrides here.
+	var redactConfig redact.Config
+	if err := cleanenv.ReadEnv(&redactConfig); err != nil {
+		return err
+	}
+	redactor, err := redact.New(redactConfig)
+	if err != nil {
+		return err
+	}
+
+	client, err := connect.client()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	results, err := backup.Restore(ctx, client, a, backup.RestoreOptions{
+		Connectors:  names,
+		Rename:      rename,
+		Overrides:   rendered,
+		SkipOffsets: *noOffsets,
+		Masker:      redactor,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		switch {
+		case res.Error != "":
+			log.Printf("%s: failed: %s", res.Name, res.Error)
+		case res.Name != res.Source:
+			log.Printf("%s: restored from %s, %s", res.Name, res.Source, res.State)
+		default:
+			log.Printf("%s: restored, %s", res.Name, res.State)
+		}
+	}
+	if backup.Failed(results) {
+		return errors.New("some connectors were not restored")
+	}
+
+	return nil
+}
+
+// render prints the rendered connector definition, ready to be POSTed to Kafka Connect.
+func render(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	templatePath := fs.String("template", "", "connector definition template")
+	varsPath := fs.String("vars", "", "JSON file with the variables of the target environment")
+	fileRoots := fs.String("file-roots", "", "comma separated directories ${file:...} may read from")
+	avroRegistry := fs.String("avro-registry", "", "schema registry URL; switches the converters to Avro")
+	avroUserInfo := fs.String("avro-user-info", "", "basic auth user:password for the schema registry, may be a placeholder")
+	overrides := varFlags{}
+	fs.Var(overrides, "var", "variable override in the form key=value, may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *templatePath == "" {
+		return fmt.Errorf("-template is required")
+	}
+	if *avroUserInfo != "" && *avroRegistry == "" {
+		return fmt.Errorf("-avro-user-info requires -avro-registry")
+	}
+
+	def, err := configtemplate.Load(*templatePath)
+	if err != nil {
+		return err
+	}
+	// Merged before rendering, so the flags may use placeholders too.
+	if *avroRegistry != "" {
+		if def.Config == nil {
+			def.Config = map[string]string{}
+		}
+		for k, v := range avro.ConverterConfig(*avroRegistry, *avroUserInfo) {
+			def.Config[k] = v
+		}
+	}
+
+	vars := map[string]string{}
+	if *varsPath != "" {
+		if vars, err = configtemplate.LoadVars(*varsPath); err != nil {
+			return err
+		}
+	}
+
+	opts := []configtemplate.Option{configtemplate.Unrestricted()}
+	if *fileRoots != "" {
+		opts = append(opts, configtemplate.WithFileRoots(strings.Split(*fileRoots, ",")...))
+	}
+
+	rendered, err := configtemplate.NewRenderer(vars, opts...).WithVars(overrides).Render(def)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rendered)
+}
@@ -2,50 +2,218 @@ package main
 
 import (
 	"context"
+	"debezium_server/internal/alerting"
+	"debezium_server/internal/auth"
+	"debezium_server/internal/cluster"
 	"debezium_server/internal/config"
+	"debezium_server/internal/projection"
+	"debezium_server/internal/repository"
+	"debezium_server/internal/service"
+	"debezium_server/internal/stream"
 	v1 "debezium_server/internal/transport/http/v1"
+	"debezium_server/pkg/cdc/avro"
+	"debezium_server/pkg/cdc/kafka"
+	"debezium_server/pkg/configtemplate"
 	"debezium_server/pkg/logger"
 	"debezium_server/pkg/postgres"
+	"debezium_server/pkg/redact"
+	"debezium_server/pkg/schemaregistry"
+	"debezium_server/pkg/tracing"
 	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
-	"time"
 
-	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 )
 
 func main() {
-	envPath := os.Getenv("ENV_PATH")
-	if envPath == "" {
-		envPath = "./config/.env"
-	}
+	flags := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
 
-	if err := godotenv.Load(envPath); err != nil {
-		panic(fmt.Errorf("error loading .env file: %s", err))
+	if err := config.LoadDotEnv(); err != nil {
+		panic(err)
 	}
 
-	cfg, err := config.ParseConfigFromEnv()
+	cfg, err := config.Load(flags)
 	if err != nil {
 		panic(fmt.Errorf("failed to parse config: %w", err))
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		panic(fmt.Errorf("failed to set up tracing: %w", err))
+	}
+
 	db, err := postgres.New(cfg.Config)
 	if err != nil {
 		panic(fmt.Errorf("failed to connect to database: %w", err))
 	}
 
-	lg := logger.NewLogger(cfg.Environment)
+	redactor, err := redact.New(cfg.Redact)
+	if err != nil {
+		panic(fmt.Errorf("failed to create redactor: %w", err))
+	}
+
+	lg, err := logger.NewLogger(cfg.Environment,
+		logger.WithLevel(cfg.Log.Level),
+		logger.WithRedactor(redactor),
+	)
+	if err != nil {
+		panic(fmt.Errorf("failed to create logger: %w", err))
+	}
+	defer lg.Sync()
 
-	ctx := logger.WithRequestID(context.Background(), "12345678")
+	ctx := context.Background()
 
 	lg.Info(ctx, "starting server")
 
-	server := v1.NewServer(cfg.Port, db.Pool)
+	hub, err := stream.NewHub(cfg.Stream)
+	if err != nil {
+		lg.Error(ctx, "failed to create change stream hub", zap.Error(err))
+		return
+	}
+
+	var templateVars map[string]string
+	if cfg.Template.VarsFile != "" {
+		templateVars, err = configtemplate.LoadVars(cfg.Template.VarsFile)
+		if err != nil {
+			lg.Error(ctx, "failed to load template variables", zap.Error(err))
+			return
+		}
+	}
+
+	var authn auth.Authenticator
+	if cfg.Auth.Enabled {
+		authn, err = auth.New(cfg.Auth)
+		if err != nil {
+			lg.Error(ctx, "failed to configure authentication", zap.Error(err))
+			return
+		}
+	} else {
+		lg.Warn(ctx, "authentication is disabled")
+	}
+
+	clusters, err := cluster.New(cfg.ConnectClusters(), cfg.Timeout)
+	if err != nil {
+		lg.Error(ctx, "failed to configure Kafka Connect clusters", zap.Error(err))
+		return
+	}
+	defer clusters.Close()
+
+	var alerts *alerting.Engine
+	alertCtx, stopAlerts := context.WithCancel(ctx)
+	defer stopAlerts()
+	if cfg.Alerting.Enabled {
+		slots := make(map[string]alerting.SlotLister)
+		for _, c := range clusters.All() {
+			if c.Source == nil {
+				if cfg.Alerting.NeedsSlots() {
+					lg.Warn(ctx, "alerting: the cluster has no signal_dsn, so its replication slots are not inspected",
+						zap.String("cluster", c.Name))
+				}
+				continue
+			}
+			slots[c.Name] = repository.NewSlotRepository(c.Source)
+		}
+		alerts = alerting.New(cfg.Alerting, clusters, slots, lg)
+		go alerts.Run(alertCtx)
+		lg.Info(ctx, fmt.Sprintf("alerting enabled with %d rules", len(cfg.Alerting.Rules)))
+	}
+
+	consumerCtx, stopConsumer := context.WithCancel(ctx)
+	defer stopConsumer()
+	consumerDone := make(chan struct{})
+	consumerFailed := make(chan error, 1)
+	var (
+		deadLetters *service.DeadLetterService
+		schemas     *service.SchemaHistoryService
+		projector   *projection.Projector
+	)
+	if cfg.Kafka.Enabled {
+		opts := []kafka.Option{
+			kafka.WithConcurrency(cfg.Kafka.Concurrency),
+			kafka.WithRetry(cfg.Kafka.RetryAttempts, cfg.Kafka.RetryBackoff),
+		}
+		if cfg.Kafka.Format == kafka.FormatAvro {
+			registry, err := schemaregistry.New(cfg.Kafka.SchemaRegistry)
+			if err != nil {
+				lg.Error(ctx, "failed to create schema registry client", zap.Error(err))
+				return
+			}
+			opts = append(opts, kafka.WithDecoder(avro.NewDecoder(registry, avro.WithJSONFallback())))
+		}
+
+		client, err := kafka.NewGroupClient(cfg.Kafka)
+		if err != nil {
+			lg.Error(ctx, "failed to create Kafka consumer", zap.Error(err))
+			return
+		}
+		client.OnFetchError = func(err error) {
+			lg.Warn(ctx, "Kafka fetch failed, retrying", zap.Error(err))
+		}
+
+		var heartbeats service.HeartbeatObserver
+		if alerts != nil {
+			heartbeats = alerts
+		}
+		deadLetterRepo := repository.NewDeadLetterRepository(db.Pool)
+		switch cfg.Kafka.DeadLetter.Mode {
+		case kafka.DeadLetterPostgres:
+			opts = append(opts, kafka.WithDeadLetterQueue(service.NewDeadLetterQueue(deadLetterRepo)))
+		case kafka.DeadLetterTopic:
+			opts = append(opts, kafka.WithDeadLetterQueue(kafka.NewTopicDeadLetterQueue(client, cfg.Kafka.DeadLetter.Topic)))
+		}
+		schemas = service.NewSchemaHistoryService(repository.NewSchemaHistoryRepository(db.Pool), lg)
+		var changeProjector service.ChangeProjector
+		if len(cfg.Projection.Enabled) > 0 {
+			var projections []projection.Projection
+			for _, name := range cfg.Projection.Enabled {
+				projections = append(projections, projection.Builtin[name]())
+			}
+			projector = projection.NewProjector(db.Pool, projections...)
+			changeProjector = projector
+		}
+		feed := service.NewChangeFeed(hub, heartbeats, schemas, changeProjector, clusters, lg)
+		go feed.Run(consumerCtx)
+		consumer := kafka.NewConsumer(client, feed, opts...)
+		if cfg.Kafka.DeadLetter.Mode == kafka.DeadLetterPostgres {
+			deadLetters = service.NewDeadLetterService(deadLetterRepo, consumer)
+		}
+		go func() {
+			defer close(consumerDone)
+			defer client.Close()
+			if err := consumer.Run(consumerCtx); err != nil {
+				consumerFailed <- err
+			}
+		}()
+		lg.Info(ctx, "Kafka consumer started", zap.Strings("topics", cfg.Kafka.Topics))
+	} else {
+		close(consumerDone)
+		lg.Warn(ctx, "Kafka consumer is disabled, change streams only send heartbeats")
+	}
+
+	templates := configtemplate.NewRenderer(templateVars,
+		configtemplate.WithFileRoots(cfg.Template.FileRoots...),
+		configtemplate.WithEnvAllowlist(cfg.Template.EnvAllow...),
+	)
+	server := v1.NewServer(cfg.Port, v1.Dependencies{
+		DB:          db.Pool,
+		Hub:         hub,
+		Clusters:    clusters,
+		Templates:   templates,
+		Redactor:    redactor,
+		Logger:      lg,
+		Auth:        authn,
+		Alerts:      alerts,
+		DeadLetters: deadLetters,
+		Schemas:     schemas,
+		Projector:   projector,
+	})
 	err = server.RegisterHandlers()
 	if err != nil {
 		lg.Error(ctx, "failed to register handlers", zap.Error(err))
@@ -62,22 +230,92 @@ func main() {
 		}
 	}()
 
+	reloadSh := make(chan os.Signal, 1)
+	signal.Notify(reloadSh, syscall.SIGHUP)
+	go reloadOnSignal(ctx, reloadSh, lg, flags, cfg, clusters)
+
 	graceSh := make(chan os.Signal, 1)
 	signal.Notify(graceSh, os.Interrupt, syscall.SIGTERM)
-	<-graceSh
 
-	lg.Info(ctx, "Shutdown signal received, starting graceful shutdown...")
+	exitCode := 0
+	select {
+	case <-graceSh:
+		lg.Info(ctx, "Shutdown signal received, starting graceful shutdown...")
+	case err := <-consumerFailed:
+		// Without the consumer the change streams, projections and
+		// heartbeats go quiet, so the server stops to be restarted. The
+		// new consumer resumes from the committed offsets.
+		lg.Error(ctx, "Kafka consumer stopped, shutting down", zap.Error(err))
+		exitCode = 1
+	}
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Timeout*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
 	defer cancel()
 
 	if err := server.Stop(shutdownCtx); err != nil {
 		lg.Error(ctx, "server shutdown error", zap.Error(err))
 	}
+	stopAlerts()
+	stopConsumer()
+	<-consumerDone
 
 	db.Close()
 	lg.Info(ctx, "Database connection pool closed")
 
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		lg.Error(ctx, "tracing shutdown error", zap.Error(err))
+	}
+
 	wg.Wait()
+	if exitCode != 0 {
+		lg.Sync()
+		os.Exit(exitCode)
+	}
 	lg.Info(ctx, "Server stopped gracefully")
 }
+
+// reloadOnSignal reloads the config on every signal and applies the keys
+// that can change at runtime. Other changed keys are reported and take
+// effect on the next restart; they are compared with the config in effect,
+// so they stay reported until then. An invalid config is rejected as a
+// whole.
+func reloadOnSignal(
+	ctx context.Context,
+	sig <-chan os.Signal,
+	lg logger.Logger,
+	flags *config.Flags,
+	started *config.Config,
+	clusters *cluster.Registry,
+) {
+	applied := *started
+	for range sig {
+		next, err := config.Load(flags)
+		if err != nil {
+			lg.Error(ctx, "config reload rejected", zap.Error(err))
+			continue
+		}
+
+		runtime, pending := config.Changes(&applied, next)
+		for _, key := range runtime {
+			switch key {
+			case "log.level":
+				applied.Log.Level = next.Log.Level
+			case "debezium_base_url":
+				clusters.Default().Client.SetBaseURLs(next.DebeziumWorkers())
+				applied.DebeziumBaseURL = next.DebeziumBaseURL
+			}
+		}
+
+		// The default level depends on the environment, so recompute it
+		// even if only the environment changed.
+		if level, err := logger.ParseLevel(next.Environment, next.Log.Level); err == nil && level != lg.Level() {
+			lg.SetLevel(level)
+		}
+
+		if len(pending) > 0 {
+			lg.Warn(ctx, "config reloaded, some changes require a restart", zap.Strings("keys", pending))
+		} else {
+			lg.Info(ctx, "config reloaded")
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"debezium_server/internal/config"
+	"debezium_server/migrations"
+	"debezium_server/pkg/migrate"
+	"debezium_server/pkg/postgres"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	command := flag.String("command", "up", "migration command: up, down, version or force")
+	steps := flag.Int("steps", 1, "number of migrations to roll back with down")
+	version := flag.Uint64("version", 0, "schema version to set with force")
+	flags := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	if err := config.LoadDotEnv(); err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := config.LoadPostgres(flags)
+	if err != nil {
+		log.Fatalf("failed to parse config: %s", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg, *command, *steps, *version); err != nil {
+		stop()
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, cfg postgres.Config, command string, steps int, version uint64) error {
+	db, err := postgres.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	m, err := migrate.New(db.Pool, migrations.FS)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	switch command {
+	case "up":
+		err = m.Up(ctx)
+	case "down":
+		err = m.Down(ctx, steps)
+	case "force":
+		err = m.Force(ctx, version)
+	case "version":
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		log.Print("no change")
+	} else if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+
+	current, dirty, err := m.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("version: %w", err)
+	}
+	log.Printf("version %d (dirty: %t)", current, dirty)
+
+	return nil
+}
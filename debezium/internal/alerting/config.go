@@ -0,0 +1,168 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"time"
+)
+
+type RuleType string
+
+const (
+	// RuleNotRunning fires when a connector has not been RUNNING for the
+	// rule's duration.
+	RuleNotRunning RuleType = "not_running"
+	// RuleTaskFailed fires for every FAILED task of a connector.
+	RuleTaskFailed RuleType = "task_failed"
+	// RuleSlotLag fires when the replication slot of a Postgres connector
+	// retains more WAL than the threshold.
+	RuleSlotLag RuleType = "slot_lag"
+	// RuleHeartbeat fires when a running connector sent no heartbeat
+	// within the rule's duration.
+	RuleHeartbeat RuleType = "heartbeat"
+)
+
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+// Config holds the alerting rules and the webhooks they notify. Rules and
+// webhooks can only be set in the config file.
+type Config struct {
+	Enabled bool `yaml:"enabled" env:"ALERTING_ENABLED" env-default:"false"`
+	// Interval is how often connector statuses and slots are inspected.
+	Interval time.Duration `yaml:"interval" env:"ALERTING_INTERVAL" env-default:"30s"`
+	// RepeatInterval is how often a firing alert is sent again. Rules may
+	// override it.
+	RepeatInterval time.Duration `yaml:"repeat_interval" env:"ALERTING_REPEAT_INTERVAL" env-default:"4h"`
+	// WebhookSecret signs the webhooks that have no secret of their own.
+	WebhookSecret string `yaml:"webhook_secret" env:"ALERTING_WEBHOOK_SECRET"`
+
+	Rules    []Rule    `yaml:"rules"`
+	Webhooks []Webhook `yaml:"webhooks"`
+}
+
+// NeedsSlots reports whether a rule reads the replication slots.
+func (c Config) NeedsSlots() bool {
+	for _, r := range c.Rules {
+		if r.Type == RuleSlotLag || r.Type == RuleHeartbeat {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule selects connectors by cluster and name pattern and the condition
+// they alert on. For is how long the condition must hold for not_running,
+// task_failed and slot_lag, and the longest gap between heartbeats for
+// heartbeat.
+type Rule struct {
+	Name       string        `yaml:"name"`
+	Type       RuleType      `yaml:"type"`
+	Cluster    string        `yaml:"cluster"`
+	Connectors []string      `yaml:"connectors"`
+	For        time.Duration `yaml:"for"`
+	// ThresholdBytes is the slot lag slot_lag fires above.
+	ThresholdBytes int64 `yaml:"threshold_bytes"`
+	// Slot overrides the slot.name of the connector config for slot_lag.
+	Slot           string        `yaml:"slot"`
+	Severity       string        `yaml:"severity"`
+	RepeatInterval time.Duration `yaml:"repeat_interval"`
+	// Webhooks names the webhooks to notify, all of them if empty.
+	Webhooks []string `yaml:"webhooks"`
+}
+
+type Webhook struct {
+	Name    string            `yaml:"name"`
+	URL     string            `yaml:"url"`
+	Secret  string            `yaml:"secret"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+// Validate returns a description of the first problem with the rule.
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return errors.New("needs a name")
+	}
+	switch r.Type {
+	case RuleNotRunning, RuleTaskFailed:
+	case RuleSlotLag:
+		if r.ThresholdBytes <= 0 {
+			return fmt.Errorf("%q needs a positive threshold_bytes", r.Name)
+		}
+	case RuleHeartbeat:
+		if r.For <= 0 {
+			return fmt.Errorf("%q needs a positive for", r.Name)
+		}
+	default:
+		return fmt.Errorf("%q has unknown type %q, use %s, %s, %s or %s",
+			r.Name, r.Type, RuleNotRunning, RuleTaskFailed, RuleSlotLag, RuleHeartbeat)
+	}
+	if r.For < 0 || r.RepeatInterval < 0 {
+		return fmt.Errorf("for and repeat_interval of %q must not be negative", r.Name)
+	}
+	switch r.Severity {
+	case "", SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("severity of %q must be %s or %s", r.Name, SeverityWarning, SeverityCritical)
+	}
+	for _, p := range r.Connectors {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("connector pattern %q of %q: %w", p, r.Name, err)
+		}
+	}
+	return nil
+}
+
+// Validate returns a description of the first problem with the webhook.
+func (w Webhook) Validate() error {
+	if w.Name == "" {
+		return errors.New("needs a name")
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url of %q must be an http or https URL", w.Name)
+	}
+	if w.Timeout < 0 {
+		return fmt.Errorf("timeout of %q must not be negative", w.Name)
+	}
+	return nil
+}
+
+// matches reports whether the rule applies to the connector.
+func (r Rule) matches(cluster, connector string) bool {
+	if r.Cluster != "" && r.Cluster != cluster {
+		return false
+	}
+	if len(r.Connectors) == 0 {
+		return true
+	}
+	for _, p := range r.Connectors {
+		if ok, _ := path.Match(p, connector); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingFor is how long the condition must hold before the alert fires.
+// For a heartbeat rule, For is already the allowed gap.
+func (r Rule) pendingFor() time.Duration {
+	if r.Type == RuleHeartbeat {
+		return 0
+	}
+	return r.For
+}
+
+func (r Rule) severity() string {
+	if r.Severity == "" {
+		return SeverityWarning
+	}
+	return r.Severity
+}
@@ -0,0 +1,505 @@
+package alerting
+
+import (
+	"context"
+	"debezium_server/internal/cluster"
+	"debezium_server/internal/models"
+	debezium_client "debezium_server/pkg/debezium-client"
+	"debezium_server/pkg/logger"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Status string
+
+const (
+	StatusFiring   Status = "firing"
+	StatusResolved Status = "resolved"
+)
+
+const (
+	defaultSlotName = "debezium"
+	postgresClass   = "PostgresConnector"
+)
+
+// Alert is one rule firing for one connector, or task for task_failed.
+// The fingerprint stays the same from the first firing to the resolve
+// message, so receivers can deduplicate on it.
+type Alert struct {
+	Fingerprint string     `json:"fingerprint"`
+	Rule        string     `json:"rule"`
+	Type        RuleType   `json:"type"`
+	Severity    string     `json:"severity"`
+	Status      Status     `json:"status"`
+	Cluster     string     `json:"cluster"`
+	Connector   string     `json:"connector"`
+	Task        *int       `json:"task,omitempty"`
+	Summary     string     `json:"summary"`
+	Value       any        `json:"value,omitempty"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+}
+
+type SlotLister interface {
+	List(ctx context.Context) ([]models.ReplicationSlot, error)
+}
+
+// slotKey identifies a replication slot: clusters capturing different
+// databases may use the same slot name.
+type slotKey struct {
+	cluster string
+	name    string
+}
+
+// state tracks one fingerprint from the first time its condition holds
+// until the resolve message was sent.
+type state struct {
+	rule     Rule
+	scope    string
+	alert    Alert
+	since    time.Time
+	firing   bool
+	resolved bool
+	sentAt   time.Time
+	pending  bool
+}
+
+// Engine evaluates the rules at every interval and notifies the webhooks.
+// An alert is sent when it starts firing, again every repeat interval
+// while it fires, and once more when it resolves. Sends that fail are
+// retried at the next evaluation, so delivery is at least once.
+type Engine struct {
+	cfg      Config
+	clusters *cluster.Registry
+	slots    map[string]SlotLister
+	notifier *notifier
+	lg       logger.Logger
+	now      func() time.Time
+
+	mu         sync.Mutex
+	states     map[string]*state
+	heartbeats map[string]time.Time
+	slotsSeen  map[slotKey]models.ReplicationSlot
+	started    time.Time
+}
+
+// New returns an engine for the clusters. slots holds the replication slots
+// of the source database of each cluster, by cluster name; the slots of a
+// cluster without one are not inspected.
+func New(cfg Config, clusters *cluster.Registry, slots map[string]SlotLister, lg logger.Logger) *Engine {
+	e := &Engine{
+		cfg:        cfg,
+		clusters:   clusters,
+		slots:      slots,
+		lg:         lg,
+		now:        time.Now,
+		states:     make(map[string]*state),
+		heartbeats: make(map[string]time.Time),
+		slotsSeen:  make(map[slotKey]models.ReplicationSlot),
+	}
+	e.notifier = newNotifier(cfg, e.now)
+	e.started = e.now()
+	return e
+}
+
+// Run evaluates the rules until ctx is done.
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		e.Evaluate(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ObserveHeartbeat records a heartbeat of the connector. The Kafka consumer
+// calls it for the heartbeat topics; the replication slot of a Postgres
+// connector confirming a new position, or having nothing to confirm,
+// counts as a heartbeat too.
+func (e *Engine) ObserveHeartbeat(clusterName, connector string, at time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := clusterName + "/" + connector
+	if at.After(e.heartbeats[key]) {
+		e.heartbeats[key] = at
+	}
+}
+
+// Active returns the firing alerts, oldest first.
+func (e *Engine) Active() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make([]Alert, 0, len(e.states))
+	for _, s := range e.states {
+		if s.firing && !s.resolved {
+			out = append(out, s.alert)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].StartsAt.Equal(out[j].StartsAt) {
+			return out[i].StartsAt.Before(out[j].StartsAt)
+		}
+		return out[i].Fingerprint < out[j].Fingerprint
+	})
+	return out
+}
+
+// connector is what one evaluation knows about a connector.
+type connector struct {
+	cluster string
+	name    string
+	status  debezium_client.ConnectorStatus
+	slot    string
+}
+
+// Evaluate inspects the connectors once, updates the alert states and
+// sends the notifications that are due.
+func (e *Engine) Evaluate(ctx context.Context) {
+	connectors, evaluated := e.inspect(ctx)
+
+	var (
+		slots     map[slotKey]models.ReplicationSlot
+		slotsRead map[string]bool
+	)
+	if e.cfg.NeedsSlots() {
+		slots, slotsRead = e.listSlots(ctx)
+	}
+
+	now := e.now()
+	e.observeSlotProgress(connectors, slots, now)
+
+	observed := make(map[string]Alert)
+	scopes := make(map[string]bool)
+	for _, rule := range e.cfg.Rules {
+		for clusterName := range evaluated {
+			if rule.Cluster != "" && rule.Cluster != clusterName {
+				continue
+			}
+			if rule.Type == RuleSlotLag && !slotsRead[clusterName] {
+				continue
+			}
+			scopes[scopeKey(rule, clusterName)] = true
+		}
+		for _, c := range connectors {
+			if !rule.matches(c.cluster, c.name) {
+				continue
+			}
+			for _, a := range e.check(rule, c, slots, now) {
+				observed[a.Fingerprint] = a
+			}
+		}
+	}
+
+	due := e.update(observed, scopes, now)
+	e.notify(ctx, due)
+}
+
+// inspect lists the connectors of every cluster with their statuses. The
+// clusters that could not be listed are left out of evaluated, so their
+// alerts are neither raised nor resolved.
+func (e *Engine) inspect(ctx context.Context) ([]connector, map[string]bool) {
+	var (
+		out       []connector
+		evaluated = make(map[string]bool)
+	)
+	needsConfig := e.cfg.NeedsSlots()
+
+	for _, c := range e.clusters.All() {
+		list, err := c.Client.ListConnectors(ctx, true)
+		if err != nil {
+			e.lg.Warn(ctx, "alerting: failed to list connectors", zap.String("cluster", c.Name), zap.Error(err))
+			continue
+		}
+		evaluated[c.Name] = true
+
+		for _, name := range list.Names {
+			conn := connector{cluster: c.Name, name: name, status: list.Statuses[name]}
+			if needsConfig {
+				info, err := c.Client.GetConnector(ctx, name)
+				if err != nil {
+					e.lg.Warn(ctx, "alerting: failed to read connector config",
+						zap.String("cluster", c.Name), zap.String("connector", name), zap.Error(err))
+				} else {
+					conn.slot = slotName(info.Config)
+				}
+			}
+			out = append(out, conn)
+		}
+	}
+
+	return out, evaluated
+}
+
+// listSlots returns the replication slots of every cluster and the
+// clusters whose slots could be read.
+func (e *Engine) listSlots(ctx context.Context) (map[slotKey]models.ReplicationSlot, map[string]bool) {
+	slots := make(map[slotKey]models.ReplicationSlot)
+	read := make(map[string]bool)
+	for clusterName, lister := range e.slots {
+		list, err := lister.List(ctx)
+		if err != nil {
+			e.lg.Warn(ctx, "alerting: failed to inspect replication slots",
+				zap.String("cluster", clusterName), zap.Error(err))
+			continue
+		}
+		read[clusterName] = true
+		for _, s := range list {
+			slots[slotKey{cluster: clusterName, name: s.Name}] = s
+		}
+	}
+	return slots, read
+}
+
+// slotName returns the replication slot of a Postgres connector, or "" for
+// other connectors.
+func slotName(config map[string]any) string {
+	class, _ := config["connector.class"].(string)
+	if !strings.HasSuffix(class, postgresClass) {
+		return ""
+	}
+	if name, ok := config["slot.name"].(string); ok && name != "" {
+		return name
+	}
+	return defaultSlotName
+}
+
+// observeSlotProgress counts a slot confirming a new position as a
+// heartbeat of the connectors that read from it. On an idle database the
+// position cannot move, so an active slot that has caught up or has seen no
+// new WAL since the last evaluation counts as well.
+func (e *Engine) observeSlotProgress(connectors []connector, slots map[slotKey]models.ReplicationSlot, now time.Time) {
+	e.mu.Lock()
+	alive := make(map[slotKey]bool)
+	for key, s := range slots {
+		prev, ok := e.slotsSeen[key]
+		switch {
+		case ok && prev.ConfirmedFlushLSN != s.ConfirmedFlushLSN:
+			alive[key] = true
+		case !s.Active:
+		case s.LagBytes == 0:
+			alive[key] = true
+		case ok && prev.LagBytes == s.LagBytes:
+			alive[key] = true
+		}
+		e.slotsSeen[key] = s
+	}
+	e.mu.Unlock()
+
+	for _, c := range connectors {
+		if c.slot != "" && alive[slotKey{cluster: c.cluster, name: c.slot}] {
+			e.ObserveHeartbeat(c.cluster, c.name, now)
+		}
+	}
+}
+
+// check returns the alerts the rule raises for the connector.
+func (e *Engine) check(rule Rule, c connector, slots map[slotKey]models.ReplicationSlot, now time.Time) []Alert {
+	base := Alert{
+		Rule:      rule.Name,
+		Type:      rule.Type,
+		Severity:  rule.severity(),
+		Status:    StatusFiring,
+		Cluster:   c.cluster,
+		Connector: c.name,
+	}
+	state := c.status.Connector.State
+
+	switch rule.Type {
+	case RuleNotRunning:
+		if state == debezium_client.StateRunning {
+			return nil
+		}
+		if state == "" {
+			state = "UNKNOWN"
+		}
+		base.Summary = fmt.Sprintf("connector %s is %s", c.name, state)
+		base.Value = state
+		return []Alert{withFingerprint(base)}
+
+	case RuleTaskFailed:
+		var out []Alert
+		for _, t := range c.status.Tasks {
+			if t.State != "FAILED" {
+				continue
+			}
+			a := base
+			id := t.Id
+			a.Task = &id
+			a.Summary = fmt.Sprintf("task %d of connector %s failed", id, c.name)
+			if trace, _, _ := strings.Cut(t.Trace, "\n"); trace != "" {
+				a.Value = trace
+			}
+			out = append(out, withFingerprint(a))
+		}
+		return out
+
+	case RuleSlotLag:
+		slot := rule.Slot
+		if slot == "" {
+			slot = c.slot
+		}
+		s, ok := slots[slotKey{cluster: c.cluster, name: slot}]
+		if slot == "" || !ok || s.LagBytes <= rule.ThresholdBytes {
+			return nil
+		}
+		base.Summary = fmt.Sprintf("replication slot %s of connector %s retains %d bytes of WAL, over %d",
+			slot, c.name, s.LagBytes, rule.ThresholdBytes)
+		base.Value = s.LagBytes
+		return []Alert{withFingerprint(base)}
+
+	case RuleHeartbeat:
+		if state != debezium_client.StateRunning {
+			return nil
+		}
+		e.mu.Lock()
+		last, ok := e.heartbeats[c.cluster+"/"+c.name]
+		e.mu.Unlock()
+		if !ok {
+			last = e.started
+		}
+		if now.Sub(last) <= rule.For {
+			return nil
+		}
+		base.Summary = fmt.Sprintf("no heartbeat from connector %s for %s", c.name, now.Sub(last).Round(time.Second))
+		if ok {
+			base.Value = last
+		}
+		return []Alert{withFingerprint(base)}
+	}
+
+	return nil
+}
+
+func withFingerprint(a Alert) Alert {
+	a.Fingerprint = a.Rule + "/" + a.Cluster + "/" + a.Connector
+	if a.Task != nil {
+		a.Fingerprint += fmt.Sprintf("/%d", *a.Task)
+	}
+	return a
+}
+
+func scopeKey(rule Rule, clusterName string) string {
+	return rule.Name + "/" + clusterName
+}
+
+// update applies one evaluation to the states and returns the alerts to
+// send. A condition that is gone resolves its alert only if its rule was
+// evaluated for the cluster.
+func (e *Engine) update(observed map[string]Alert, scopes map[string]bool, now time.Time) []*state {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rules := make(map[string]Rule, len(e.cfg.Rules))
+	for _, r := range e.cfg.Rules {
+		rules[r.Name] = r
+	}
+
+	for fp, a := range observed {
+		s, ok := e.states[fp]
+		if !ok || s.resolved {
+			s = &state{rule: rules[a.Rule], scope: scopeKey(rules[a.Rule], a.Cluster), since: now}
+			e.states[fp] = s
+		}
+		a.StartsAt = s.since
+		s.alert = a
+	}
+
+	var due []*state
+	for fp, s := range e.states {
+		if _, ok := observed[fp]; !ok && !s.resolved {
+			if !scopes[s.scope] {
+				continue
+			}
+			if !s.firing {
+				delete(e.states, fp)
+				continue
+			}
+			s.resolved = true
+			s.pending = true
+			ends := now
+			s.alert.Status = StatusResolved
+			s.alert.EndsAt = &ends
+		}
+
+		if !s.firing && !s.resolved && now.Sub(s.since) >= s.rule.pendingFor() {
+			s.firing = true
+			s.pending = true
+		}
+
+		repeat := s.rule.RepeatInterval
+		if repeat == 0 {
+			repeat = e.cfg.RepeatInterval
+		}
+		if s.firing && !s.resolved && repeat > 0 && !s.sentAt.IsZero() && now.Sub(s.sentAt) >= repeat {
+			s.pending = true
+		}
+
+		if s.pending {
+			due = append(due, s)
+		}
+	}
+
+	return due
+}
+
+// notify sends the due alerts, grouped per webhook. An alert counts as
+// sent once every webhook of its rule accepted it.
+func (e *Engine) notify(ctx context.Context, due []*state) {
+	if len(due) == 0 {
+		return
+	}
+
+	e.mu.Lock()
+	byWebhook := make(map[string][]Alert)
+	var order []string
+	for _, s := range due {
+		for _, name := range e.notifier.targets(s.rule) {
+			if _, ok := byWebhook[name]; !ok {
+				order = append(order, name)
+			}
+			byWebhook[name] = append(byWebhook[name], s.alert)
+		}
+	}
+	e.mu.Unlock()
+
+	failed := make(map[string]bool)
+	for _, name := range order {
+		if err := e.notifier.send(ctx, name, byWebhook[name]); err != nil {
+			e.lg.Warn(ctx, "alerting: failed to send webhook", zap.String("webhook", name), zap.Error(err))
+			failed[name] = true
+		}
+	}
+
+	now := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range due {
+		ok := true
+		for _, name := range e.notifier.targets(s.rule) {
+			if failed[name] {
+				ok = false
+			}
+		}
+		if !ok {
+			continue
+		}
+		s.pending = false
+		s.sentAt = now
+		if s.resolved && e.states[s.alert.Fingerprint] == s {
+			delete(e.states, s.alert.Fingerprint)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package alerting
+
+import (
+	"context"
+	"debezium_server/internal/cluster"
+	"debezium_server/internal/models"
+	"debezium_server/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type slotList []models.ReplicationSlot
+
+func (l slotList) List(context.Context) ([]models.ReplicationSlot, error) {
+	return l, nil
+}
+
+// growingSlot is a slot that falls further behind at every inspection.
+type growingSlot struct {
+	slot models.ReplicationSlot
+}
+
+func (g *growingSlot) List(context.Context) ([]models.ReplicationSlot, error) {
+	g.slot.LagBytes += 5000
+	return []models.ReplicationSlot{g.slot}, nil
+}
+
+// connectServer serves one running Postgres connector named pg reading
+// from debezium_slot.
+func connectServer(t *testing.T) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /connectors", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"pg": {"status": {"name": "pg", "connector": {"state": "RUNNING"}, "tasks": []}}}`))
+	})
+	mux.HandleFunc("GET /connectors/pg", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "pg", "config": {
+			"connector.class": "io.debezium.connector.postgresql.PostgresConnector",
+			"slot.name": "debezium_slot"}}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestEngineMatchesSlotsPerCluster(t *testing.T) {
+	ctx := context.Background()
+	clusters, err := cluster.New([]cluster.Config{
+		{Name: "primary", URL: connectServer(t)},
+		{Name: "dr", URL: connectServer(t)},
+		{Name: "legacy", URL: connectServer(t)},
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("cluster.New: %v", err)
+	}
+	t.Cleanup(clusters.Close)
+
+	lg, err := logger.NewLogger("test", logger.WithLevel("error"))
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+
+	// Both source databases have a debezium_slot; only the primary one
+	// lags. The legacy cluster has no source database.
+	slots := map[string]SlotLister{
+		"primary": &growingSlot{models.ReplicationSlot{Name: "debezium_slot", Active: true, ConfirmedFlushLSN: "0/100"}},
+		"dr":      slotList{{Name: "debezium_slot", Active: true, ConfirmedFlushLSN: "0/200", LagBytes: 0}},
+	}
+	e := New(Config{
+		Interval: time.Minute,
+		Rules: []Rule{
+			{Name: "slot-lag", Type: RuleSlotLag, ThresholdBytes: 1000},
+			{Name: "no-heartbeat", Type: RuleHeartbeat, For: time.Minute},
+		},
+	}, clusters, slots, lg)
+	now := e.started
+	e.now = func() time.Time { return now }
+
+	e.Evaluate(ctx)
+	now = now.Add(2 * time.Minute)
+	e.Evaluate(ctx)
+
+	want := map[string]bool{
+		"slot-lag/primary/pg": true,
+		// The lagging slot has not confirmed a new position and the legacy
+		// cluster has no slots, so neither sent a heartbeat. The caught up
+		// slot of dr did.
+		"no-heartbeat/primary/pg": true,
+		"no-heartbeat/legacy/pg":  true,
+	}
+	active := e.Active()
+	for _, a := range active {
+		if !want[a.Fingerprint] {
+			t.Errorf("unexpected alert %s: %s", a.Fingerprint, a.Summary)
+		}
+		delete(want, a.Fingerprint)
+	}
+	for fp := range want {
+		t.Errorf("alert %s is not firing", fp)
+	}
+}
@@ -0,0 +1,129 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of
+	// "<timestamp>.<body>" keyed with the webhook secret.
+	SignatureHeader = "X-Signature-256"
+	// TimestampHeader carries the Unix time the signature was made, so
+	// receivers can reject replays.
+	TimestampHeader = "X-Signature-Timestamp"
+
+	payloadVersion = "1"
+)
+
+// Payload is the JSON body of every webhook.
+type Payload struct {
+	Version string    `json:"version"`
+	SentAt  time.Time `json:"sent_at"`
+	Alerts  []Alert   `json:"alerts"`
+}
+
+type notifier struct {
+	hc       *http.Client
+	webhooks map[string]Webhook
+	order    []string
+	secret   string
+	now      func() time.Time
+}
+
+func newNotifier(cfg Config, now func() time.Time) *notifier {
+	n := &notifier{
+		hc:       &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		webhooks: make(map[string]Webhook, len(cfg.Webhooks)),
+		secret:   cfg.WebhookSecret,
+		now:      now,
+	}
+	for _, w := range cfg.Webhooks {
+		n.webhooks[w.Name] = w
+		n.order = append(n.order, w.Name)
+	}
+	return n
+}
+
+// targets returns the webhooks a rule notifies.
+func (n *notifier) targets(rule Rule) []string {
+	if len(rule.Webhooks) == 0 {
+		return n.order
+	}
+	return rule.Webhooks
+}
+
+// send posts the alerts to one webhook. Any status other than 2xx is an
+// error, so the alerts are sent again on the next evaluation.
+func (n *notifier) send(ctx context.Context, name string, alerts []Alert) error {
+	w, ok := n.webhooks[name]
+	if !ok {
+		return fmt.Errorf("unknown webhook %q", name)
+	}
+
+	sentAt := n.now().UTC()
+	body, err := json.Marshal(Payload{Version: payloadVersion, SentAt: sentAt, Alerts: alerts})
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", name, err)
+	}
+
+	timeout := w.Timeout
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", name, err)
+	}
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	secret := w.Secret
+	if secret == "" {
+		secret = n.secret
+	}
+	if secret != "" {
+		ts := strconv.FormatInt(sentAt.Unix(), 10)
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, "sha256="+Sign(secret, ts, body))
+	}
+
+	resp, err := n.hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: unexpected status %d", name, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>". Receivers
+// compute it the same way and compare in constant time.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
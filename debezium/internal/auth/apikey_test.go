@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	keys, err := NewAPIKeyAuthenticator([]string{"ops@example.com:s3cret", "ci@example.com:t0ken"})
+	if err != nil {
+		t.Fatalf("NewAPIKeyAuthenticator: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		subject string
+		err     error
+	}{
+		{name: "first key", key: "s3cret", subject: "ops@example.com"},
+		{name: "second key", key: "t0ken", subject: "ci@example.com"},
+		{name: "wrong key", key: "s3cret2", err: ErrInvalidCredentials},
+		{name: "prefix of a key", key: "s3c", err: ErrInvalidCredentials},
+		{name: "no header", err: ErrNoCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/connectors", nil)
+			if tt.key != "" {
+				r.Header.Set(apiKeyHeader, tt.key)
+			}
+
+			p, err := keys.Authenticate(r)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Authenticate returned %v, want %v", err, tt.err)
+			}
+			if p.Subject != tt.subject {
+				t.Errorf("subject is %q, want %q", p.Subject, tt.subject)
+			}
+			if tt.err == nil && p.Method != MethodAPIKey {
+				t.Errorf("method is %q, want %q", p.Method, MethodAPIKey)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthenticatorRejectsMalformedKeys(t *testing.T) {
+	for _, entry := range []string{"s3cret", ":s3cret", "ops@example.com:"} {
+		t.Run(entry, func(t *testing.T) {
+			_, err := NewAPIKeyAuthenticator([]string{entry})
+			if err == nil {
+				t.Fatal("the entry was accepted")
+			}
+			if strings.Contains(err.Error(), "s3cret") {
+				t.Errorf("the error %q leaks the key", err)
+			}
+		})
+	}
+}
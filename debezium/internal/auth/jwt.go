@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	jwtLeeway = 30 * time.Second
+)
+
+// JWTAuthenticator verifies HS256 and RS256 bearer tokens against a JWKS.
+type JWTAuthenticator struct {
+	jwks   *JWKS
+	parser *jwt.Parser
+}
+
+func NewJWTAuthenticator(jwks *JWKS, issuer, audience string) *JWTAuthenticator {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{algHS256, algRS256}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if issuer != "" {
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+
+	return &JWTAuthenticator{
+		jwks:   jwks,
+		parser: jwt.NewParser(opts...),
+	}
+}
+
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	raw := bearerToken(r)
+	if raw == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(raw, claims, a.key); err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return Principal{}, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	return Principal{Subject: subject, Method: MethodJWT, Claims: claims}, nil
+}
+
+// key picks the verification key by the token's kid and algorithm, so an
+// RSA public key can never be used as an HMAC secret.
+func (a *JWTAuthenticator) key(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	switch token.Method.Alg() {
+	case algRS256:
+		return a.jwks.rsaKey(kid)
+	case algHS256:
+		return a.jwks.hmacKey(kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://id.example.com"
+	testAudience = "debezium-server"
+)
+
+// testKey is an RSA signing key published in the JWKS under kid.
+type testKey struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+func newTestKey(t *testing.T, kid string) testKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return testKey{kid: kid, key: key}
+}
+
+func (k testKey) jwk() jsonWebKey {
+	return jsonWebKey{
+		Kty: ktyRSA,
+		Kid: k.kid,
+		Alg: algRS256,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(k.key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.key.E)).Bytes()),
+	}
+}
+
+// sign issues a token with kid in its header; an empty kid is left out.
+func (k testKey) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	raw, err := token.SignedString(k.key)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return raw
+}
+
+// writeJWKS writes a JWKS file with the keys and loads it.
+func writeJWKS(t *testing.T, keys ...jsonWebKey) *JWKS {
+	t.Helper()
+	data, err := json.Marshal(jwkSet{Keys: keys})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	jwks, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatalf("LoadJWKS: %v", err)
+	}
+	return jwks
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub": "ops@example.com",
+		"iss": testIssuer,
+		"aud": testAudience,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+func bearer(token string) *http.Request {
+	r := httptest.NewRequest("GET", "/api/v1/connectors", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	key := newTestKey(t, "2026-10")
+	other := newTestKey(t, "2026-10")
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	jwks := writeJWKS(t, key.jwk(), jsonWebKey{
+		Kty: ktyOct,
+		Kid: "hmac",
+		Alg: algHS256,
+		K:   base64.RawURLEncoding.EncodeToString(secret),
+	})
+	a := NewJWTAuthenticator(jwks, testIssuer, testAudience)
+
+	with := func(change func(c jwt.MapClaims)) jwt.MapClaims {
+		c := validClaims()
+		change(c)
+		return c
+	}
+	hmacToken := func(kid string, alg jwt.SigningMethod, key any) string {
+		token := jwt.NewWithClaims(alg, validClaims())
+		token.Header["kid"] = kid
+		raw, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return raw
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{name: "valid RS256", token: key.sign(t, key.kid, validClaims())},
+		{name: "valid HS256", token: hmacToken("hmac", jwt.SigningMethodHS256, secret)},
+		{
+			name:  "expired within the leeway",
+			token: key.sign(t, key.kid, with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-10 * time.Second).Unix() })),
+		},
+		{
+			name:  "audience list",
+			token: key.sign(t, key.kid, with(func(c jwt.MapClaims) { c["aud"] = []string{"grafana", testAudience} })),
+		},
+		{
+			name:  "wrong audience",
+			token: key.sign(t, key.kid, with(func(c jwt.MapClaims) { c["aud"] = "grafana" })),
+			err:   jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name:  "wrong issuer",
+			token: key.sign(t, key.kid, with(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })),
+			err:   jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name:  "expired",
+			token: key.sign(t, key.kid, with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+			err:   jwt.ErrTokenExpired,
+		},
+		{
+			name:  "no expiry",
+			token: key.sign(t, key.kid, with(func(c jwt.MapClaims) { delete(c, "exp") })),
+			err:   jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			name:  "not yet valid",
+			token: key.sign(t, key.kid, with(func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Hour).Unix() })),
+			err:   jwt.ErrTokenNotValidYet,
+		},
+		{
+			name:  "no subject",
+			token: key.sign(t, key.kid, with(func(c jwt.MapClaims) { delete(c, "sub") })),
+			err:   ErrInvalidCredentials,
+		},
+		{name: "unknown kid", token: key.sign(t, "2026-04", validClaims()), err: ErrKeyNotFound},
+		{name: "signed by another key", token: other.sign(t, other.kid, validClaims()), err: jwt.ErrTokenSignatureInvalid},
+		{
+			// The RSA public key must not be usable as an HMAC secret.
+			name:  "HS256 signed with the RSA public key",
+			token: hmacToken(key.kid, jwt.SigningMethodHS256, []byte(key.jwk().N)),
+			err:   ErrKeyNotFound,
+		},
+		{name: "none algorithm", token: hmacToken(key.kid, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType), err: jwt.ErrTokenSignatureInvalid},
+		{name: "garbage", token: "not-a-token", err: jwt.ErrTokenMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.Authenticate(bearer(tt.token))
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("Authenticate: %v", err)
+				}
+				if p.Subject != "ops@example.com" || p.Method != MethodJWT {
+					t.Errorf("got principal %+v, want ops@example.com authenticated by JWT", p)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidCredentials) || !errors.Is(err, tt.err) {
+				t.Errorf("Authenticate returned %v, want invalid credentials and %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// TestJWKSRotation checks that tokens of the old and the new key are both
+// accepted while the JWKS publishes the two, and that a token without a kid
+// is rejected once the key is ambiguous.
+func TestJWKSRotation(t *testing.T) {
+	old := newTestKey(t, "2026-04")
+	next := newTestKey(t, "2026-10")
+
+	before := NewJWTAuthenticator(writeJWKS(t, old.jwk()), "", "")
+	during := NewJWTAuthenticator(writeJWKS(t, old.jwk(), next.jwk()), "", "")
+	after := NewJWTAuthenticator(writeJWKS(t, next.jwk()), "", "")
+
+	tests := []struct {
+		name  string
+		a     *JWTAuthenticator
+		token string
+		ok    bool
+	}{
+		{name: "old key before", a: before, token: old.sign(t, old.kid, validClaims()), ok: true},
+		{name: "no kid with a single key", a: before, token: old.sign(t, "", validClaims()), ok: true},
+		{name: "new key before", a: before, token: next.sign(t, next.kid, validClaims())},
+		{name: "old key during", a: during, token: old.sign(t, old.kid, validClaims()), ok: true},
+		{name: "new key during", a: during, token: next.sign(t, next.kid, validClaims()), ok: true},
+		{name: "no kid with two keys", a: during, token: next.sign(t, "", validClaims())},
+		{name: "old key after", a: after, token: old.sign(t, old.kid, validClaims())},
+		{name: "new key after", a: after, token: next.sign(t, next.kid, validClaims()), ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.a.Authenticate(bearer(tt.token))
+			if tt.ok && err != nil {
+				t.Errorf("Authenticate: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrKeyNotFound) {
+				t.Errorf("Authenticate returned %v, want %v", err, ErrKeyNotFound)
+			}
+		})
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	key := newTestKey(t, "rsa").jwk()
+	tests := []struct {
+		name string
+		keys []jsonWebKey
+		ok   bool
+	}{
+		{name: "RSA key", keys: []jsonWebKey{key}, ok: true},
+		{name: "encryption keys only", keys: []jsonWebKey{{Kty: ktyRSA, Kid: "enc", Use: "enc", N: key.N, E: key.E}}},
+		{name: "other algorithms only", keys: []jsonWebKey{{Kty: ktyRSA, Kid: "ps", Alg: "PS256", N: key.N, E: key.E}}},
+		{name: "empty symmetric key", keys: []jsonWebKey{{Kty: ktyOct, Kid: "hmac"}}},
+		{name: "invalid modulus", keys: []jsonWebKey{{Kty: ktyRSA, Kid: "rsa", N: "!", E: key.E}}},
+		{name: "no keys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(jwkSet{Keys: tt.keys})
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			_, err = ParseJWKS(data)
+			if tt.ok != (err == nil) {
+				t.Errorf("ParseJWKS returned %v, want ok=%t", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestQueryToken(t *testing.T) {
+	key := newTestKey(t, "2026-10")
+	token := key.sign(t, key.kid, validClaims())
+	a := NewJWTAuthenticator(writeJWKS(t, key.jwk()), testIssuer, testAudience)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		err     error
+	}{
+		{
+			name:    "EventSource",
+			method:  "GET",
+			path:    "/api/v1/changes/inventory.orders/sse",
+			headers: map[string]string{"Accept": "text/event-stream"},
+		},
+		{
+			name:    "WebSocket",
+			method:  "GET",
+			path:    "/api/v1/changes/ws",
+			headers: map[string]string{"Connection": "Upgrade", "Upgrade": "websocket"},
+		},
+		{name: "API call", method: "GET", path: "/api/v1/connectors", err: ErrNoCredentials},
+		{
+			name:    "API call accepting JSON",
+			method:  "GET",
+			path:    "/api/v1/connectors",
+			headers: map[string]string{"Accept": "application/json"},
+			err:     ErrNoCredentials,
+		},
+		{
+			name:    "write claiming a stream",
+			method:  "DELETE",
+			path:    "/api/v1/connectors/inventory",
+			headers: map[string]string{"Accept": "text/event-stream"},
+			err:     ErrNoCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path+"?access_token="+token, nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			_, err := a.Authenticate(r)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Authenticate returned %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Permission is an action on a resource in the form "resource:action".
+type Permission string
+
+const (
+	PermUsersRead  Permission = "users:read"
+	PermUsersWrite Permission = "users:write"
+
+	PermConnectorsRead         Permission = "connectors:read"
+	PermConnectorsWrite        Permission = "connectors:write"
+	PermConnectorsOperate      Permission = "connectors:operate"
+	PermConnectorsDelete       Permission = "connectors:delete"
+	PermConnectorsResetOffsets Permission = "connectors:reset-offsets"
+	// PermConnectorsBackup exports connectors, optionally with their secrets.
+	PermConnectorsBackup Permission = "connectors:backup"
+
+	PermSnapshotsRead  Permission = "snapshots:read"
+	PermSnapshotsWrite Permission = "snapshots:write"
+
+	PermChangesRead Permission = "changes:read"
+
+	// Dead letters are CDC events the consumer could not handle; write
+	// replays or discards them.
+	PermDeadLettersRead  Permission = "dead-letters:read"
+	PermDeadLettersWrite Permission = "dead-letters:write"
+
+	// PermSchemasRead reads the schema history of tables and checks
+	// proposed schemas against it.
+	PermSchemasRead Permission = "schemas:read"
+
+	// PermProjectionsWrite rebuilds projections, emptying their read tables
+	// and starting an incremental snapshot.
+	PermProjectionsRead  Permission = "projections:read"
+	PermProjectionsWrite Permission = "projections:write"
+
+	PermAuditRead Permission = "audit:read"
+
+	PermLoggingManage Permission = "logging:manage"
+)
+
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+var (
+	ErrNoPrincipal = errors.New("request has no authenticated principal")
+)
+
+// Policy maps a role to the permissions it grants.
+type Policy map[string][]Permission
+
+var viewerPermissions = []Permission{
+	PermUsersRead,
+	PermConnectorsRead,
+	PermSnapshotsRead,
+	PermChangesRead,
+	PermDeadLettersRead,
+	PermSchemasRead,
+	PermProjectionsRead,
+}
+
+// operatorPermissions let on-call engineers pause, resume and restart
+// connectors, drive snapshots and deal with dead letters, but not delete
+// connectors or reset offsets.
+var operatorPermissions = append([]Permission{
+	PermConnectorsOperate,
+	PermSnapshotsWrite,
+	PermDeadLettersWrite,
+	PermProjectionsWrite,
+}, viewerPermissions...)
+
+var adminPermissions = append([]Permission{
+	PermUsersWrite,
+	PermConnectorsWrite,
+	PermConnectorsDelete,
+	PermConnectorsResetOffsets,
+	PermConnectorsBackup,
+	PermAuditRead,
+	PermLoggingManage,
+}, operatorPermissions...)
+
+func DefaultPolicy() Policy {
+	return Policy{
+		RoleViewer:   viewerPermissions,
+		RoleOperator: operatorPermissions,
+		RoleAdmin:    adminPermissions,
+	}
+}
+
+// Allows reports whether any of the roles grants perm. Role names are
+// matched case-insensitively and unknown roles grant nothing.
+func (p Policy) Allows(roles []string, perm Permission) bool {
+	for _, role := range roles {
+		for _, granted := range p[strings.ToLower(role)] {
+			if granted == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ForbiddenError names the permission the principal is missing.
+type ForbiddenError struct {
+	Permission Permission
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("missing permission %s", e.Permission)
+}
+
+// Authorize checks the principal in ctx against the policy. It returns
+// ErrNoPrincipal for unauthenticated requests and *ForbiddenError when
+// none of the principal's roles grants perm.
+func (p Policy) Authorize(ctx context.Context, perm Permission) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ErrNoPrincipal
+	}
+
+	if !p.Allows(principal.Roles, perm) {
+		return &ForbiddenError{Permission: perm}
+	}
+
+	return nil
+}
+
+// RoleResolver looks up the roles of an authenticated subject. Subjects
+// are matched against users.email, so API key names and JWT sub claims
+// must be the user's email.
+type RoleResolver interface {
+	RolesByEmail(ctx context.Context, email string) ([]string, error)
+}
+
+type roleAuthenticator struct {
+	next  Authenticator
+	roles RoleResolver
+}
+
+// WithRoles wraps an authenticator so every principal it returns carries
+// the roles stored for its subject.
+func WithRoles(next Authenticator, roles RoleResolver) Authenticator {
+	return &roleAuthenticator{next: next, roles: roles}
+}
+
+func (a *roleAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	p, err := a.next.Authenticate(r)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	roles, err := a.roles.RolesByEmail(r.Context(), p.Subject)
+	if err != nil {
+		return Principal{}, fmt.Errorf("resolve roles: %w", err)
+	}
+	p.Roles = roles
+
+	return p, nil
+}
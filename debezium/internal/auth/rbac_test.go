@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestPolicyAllows(t *testing.T) {
+	policy := DefaultPolicy()
+	tests := []struct {
+		name  string
+		roles []string
+		perm  Permission
+		want  bool
+	}{
+		{name: "viewer reads", roles: []string{RoleViewer}, perm: PermConnectorsRead, want: true},
+		{name: "viewer cannot operate", roles: []string{RoleViewer}, perm: PermConnectorsOperate},
+		{name: "operator operates", roles: []string{RoleOperator}, perm: PermConnectorsOperate, want: true},
+		{name: "operator reads", roles: []string{RoleOperator}, perm: PermChangesRead, want: true},
+		{name: "operator cannot delete", roles: []string{RoleOperator}, perm: PermConnectorsDelete},
+		{name: "operator cannot reset offsets", roles: []string{RoleOperator}, perm: PermConnectorsResetOffsets},
+		{name: "admin resets offsets", roles: []string{RoleAdmin}, perm: PermConnectorsResetOffsets, want: true},
+		{name: "admin reads", roles: []string{RoleAdmin}, perm: PermSchemasRead, want: true},
+		{name: "role case", roles: []string{"Admin"}, perm: PermAuditRead, want: true},
+		{name: "any role", roles: []string{"unknown", RoleViewer}, perm: PermSnapshotsRead, want: true},
+		{name: "unknown role", roles: []string{"superuser"}, perm: PermConnectorsRead},
+		{name: "no roles", perm: PermConnectorsRead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Allows(tt.roles, tt.perm); got != tt.want {
+				t.Errorf("Allows(%v, %s) = %t, want %t", tt.roles, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyAuthorize(t *testing.T) {
+	policy := DefaultPolicy()
+
+	if err := policy.Authorize(context.Background(), PermConnectorsRead); !errors.Is(err, ErrNoPrincipal) {
+		t.Errorf("Authorize without a principal returned %v, want %v", err, ErrNoPrincipal)
+	}
+
+	ctx := WithPrincipal(context.Background(), Principal{Subject: "ops@example.com", Roles: []string{RoleViewer}})
+	if err := policy.Authorize(ctx, PermConnectorsRead); err != nil {
+		t.Errorf("Authorize: %v", err)
+	}
+	var forbidden *ForbiddenError
+	if err := policy.Authorize(ctx, PermConnectorsDelete); !errors.As(err, &forbidden) || forbidden.Permission != PermConnectorsDelete {
+		t.Errorf("Authorize returned %v, want a ForbiddenError for %s", err, PermConnectorsDelete)
+	}
+}
+
+type roleTable map[string][]string
+
+func (t roleTable) RolesByEmail(_ context.Context, email string) ([]string, error) {
+	if email == "broken@example.com" {
+		return nil, errors.New("connection refused")
+	}
+	return t[email], nil
+}
+
+func TestWithRoles(t *testing.T) {
+	keys, err := NewAPIKeyAuthenticator([]string{
+		"ops@example.com:ops-key",
+		"new@example.com:new-key",
+		"broken@example.com:broken-key",
+	})
+	if err != nil {
+		t.Fatalf("NewAPIKeyAuthenticator: %v", err)
+	}
+	a := WithRoles(keys, roleTable{"ops@example.com": {RoleOperator}})
+
+	tests := []struct {
+		name  string
+		key   string
+		roles []string
+		err   error
+	}{
+		{name: "stored roles", key: "ops-key", roles: []string{RoleOperator}},
+		{name: "user without roles", key: "new-key"},
+		{name: "invalid key", key: "wrong", err: ErrInvalidCredentials},
+		{name: "no credentials", err: ErrNoCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/connectors", nil)
+			if tt.key != "" {
+				r.Header.Set(apiKeyHeader, tt.key)
+			}
+
+			p, err := a.Authenticate(r)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Authenticate returned %v, want %v", err, tt.err)
+			}
+			if !slices.Equal(p.Roles, tt.roles) {
+				t.Errorf("roles are %v, want %v", p.Roles, tt.roles)
+			}
+		})
+	}
+
+	// A failed lookup must not let the request through without roles.
+	r := httptest.NewRequest("GET", "/api/v1/connectors", nil)
+	r.Header.Set(apiKeyHeader, "broken-key")
+	if _, err := a.Authenticate(r); err == nil {
+		t.Error("Authenticate succeeded although the roles could not be read")
+	}
+}
@@ -0,0 +1,217 @@
+package cluster
+
+import (
+	"context"
+	debezium_client "debezium_server/pkg/debezium-client"
+	"debezium_server/pkg/signaling"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DefaultName is the cluster built from DEBEZIUM_BASE_URL when no
+	// clusters are configured.
+	DefaultName = "default"
+)
+
+var (
+	ErrUnknownCluster = errors.New("unknown cluster")
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Config is one Kafka Connect cluster. URL and URLs list its workers;
+// requests fail over between them. Timeout defaults to the HTTP timeout.
+// Username and Password enable basic auth; Token enables bearer auth.
+// Headers are sent with every request. A worker that fails is skipped for
+// Cooldown. With HealthCheckInterval set, every worker is also probed at
+// that interval. SignalDSN is the source database its connectors capture,
+// where incremental snapshot signals are written and replication slots are
+// inspected; SignalTable overrides the connector's signal.data.collection.
+type Config struct {
+	Name                string            `yaml:"name"`
+	URL                 string            `yaml:"url"`
+	URLs                []string          `yaml:"urls"`
+	Timeout             time.Duration     `yaml:"timeout"`
+	Username            string            `yaml:"username"`
+	Password            string            `yaml:"password"`
+	Token               string            `yaml:"token"`
+	Headers             map[string]string `yaml:"headers"`
+	TLS                 TLSConfig         `yaml:"tls"`
+	Cooldown            time.Duration     `yaml:"cooldown"`
+	HealthCheckInterval time.Duration     `yaml:"health_check_interval"`
+	SignalDSN           string            `yaml:"signal_dsn"`
+	SignalTable         string            `yaml:"signal_table"`
+}
+
+// Workers returns the worker URLs of the cluster.
+func (c Config) Workers() []string {
+	var out []string
+	if c.URL != "" {
+		out = append(out, c.URL)
+	}
+	return append(out, c.URLs...)
+}
+
+// Validate returns a description of the first problem with the cluster.
+func (c Config) Validate() error {
+	if !namePattern.MatchString(c.Name) {
+		return fmt.Errorf("name %q must match %s", c.Name, namePattern)
+	}
+	workers := c.Workers()
+	if len(workers) == 0 {
+		return fmt.Errorf("%q needs a url or urls", c.Name)
+	}
+	for _, w := range workers {
+		u, err := url.Parse(w)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url %q of %q must be an http or https URL", w, c.Name)
+		}
+	}
+	if c.Cooldown < 0 || c.HealthCheckInterval < 0 {
+		return fmt.Errorf("cooldown and health_check_interval of %q must not be negative", c.Name)
+	}
+	if c.Token != "" && (c.Username != "" || c.Password != "") {
+		return fmt.Errorf("%q sets both a token and basic auth", c.Name)
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return fmt.Errorf("tls of %q: %w", c.Name, err)
+	}
+	if c.SignalDSN != "" {
+		if _, err := pgxpool.ParseConfig(c.SignalDSN); err != nil {
+			return fmt.Errorf("signal_dsn of %q: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+type Cluster struct {
+	Name   string
+	Client *debezium_client.Client
+	// Signals writes snapshot signals to the cluster's source database.
+	Signals *signaling.Channel
+	// Source is the source database, nil without a signal_dsn. Signals
+	// owns it.
+	Source *pgxpool.Pool
+}
+
+// Registry holds a client per Kafka Connect cluster. The first configured
+// cluster is the default one, used by the unscoped /api/v1/connectors routes.
+type Registry struct {
+	clusters map[string]*Cluster
+	order    []string
+}
+
+func New(configs []Config, defaultTimeout time.Duration) (*Registry, error) {
+	if len(configs) == 0 {
+		return nil, errors.New("no Kafka Connect clusters configured")
+	}
+
+	r := &Registry{clusters: make(map[string]*Cluster, len(configs))}
+	for _, c := range configs {
+		if err := c.Validate(); err != nil {
+			r.Close()
+			return nil, err
+		}
+		if _, ok := r.clusters[c.Name]; ok {
+			r.Close()
+			return nil, fmt.Errorf("cluster %q is configured twice", c.Name)
+		}
+
+		timeout := c.Timeout
+		if timeout == 0 {
+			timeout = defaultTimeout
+		}
+
+		opts, err := c.TLS.options()
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("cluster %q: %w", c.Name, err)
+		}
+		if len(c.Headers) > 0 {
+			opts = append(opts, debezium_client.WithHeaderInjector(headers(c.Headers)))
+		}
+		switch {
+		case c.Token != "":
+			opts = append(opts, debezium_client.WithBearerToken(c.Token))
+		case c.Username != "" || c.Password != "":
+			opts = append(opts, debezium_client.WithBasicAuth(c.Username, c.Password))
+		}
+		if c.Cooldown > 0 {
+			opts = append(opts, debezium_client.WithCooldown(c.Cooldown))
+		}
+		if c.HealthCheckInterval > 0 {
+			opts = append(opts, debezium_client.WithHealthCheck(c.HealthCheckInterval))
+		}
+
+		// The pool connects lazily, so an unreachable source database only
+		// fails the snapshot requests.
+		var signalDB *pgxpool.Pool
+		if c.SignalDSN != "" {
+			signalDB, err = pgxpool.New(context.Background(), c.SignalDSN)
+			if err != nil {
+				r.Close()
+				return nil, fmt.Errorf("cluster %q: signal database: %w", c.Name, err)
+			}
+		}
+
+		r.clusters[c.Name] = &Cluster{
+			Name:    c.Name,
+			Client:  debezium_client.New(c.Workers(), timeout, opts...),
+			Signals: signaling.NewChannel(signalDB, c.SignalTable),
+			Source:  signalDB,
+		}
+		r.order = append(r.order, c.Name)
+	}
+
+	return r, nil
+}
+
+func headers(h map[string]string) func(*http.Request) {
+	return func(r *http.Request) {
+		for k, v := range h {
+			r.Header.Set(k, v)
+		}
+	}
+}
+
+// Get returns the named cluster, or the default one for an empty name.
+func (r *Registry) Get(name string) (*Cluster, error) {
+	if name == "" {
+		return r.Default(), nil
+	}
+
+	c, ok := r.clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCluster, name)
+	}
+	return c, nil
+}
+
+func (r *Registry) Default() *Cluster {
+	return r.clusters[r.order[0]]
+}
+
+// All returns the clusters in configuration order.
+func (r *Registry) All() []*Cluster {
+	out := make([]*Cluster, 0, len(r.order))
+	for _, name := range r.order {
+		out = append(out, r.clusters[name])
+	}
+	return out
+}
+
+// Close stops the health checks of every cluster and closes the signal
+// databases.
+func (r *Registry) Close() {
+	for _, c := range r.clusters {
+		c.Client.Close()
+		c.Signals.Close()
+	}
+}
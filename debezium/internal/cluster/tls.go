@@ -0,0 +1,61 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	debezium_client "debezium_server/pkg/debezium-client"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSConfig configures TLS to the workers of a cluster. CAFile replaces the
+// system roots; CertFile and KeyFile enable mutual TLS. The env tags apply
+// to the default cluster only.
+type TLSConfig struct {
+	CAFile             string `yaml:"ca_file"              env:"DEBEZIUM_TLS_CA_FILE"`
+	CertFile           string `yaml:"cert_file"            env:"DEBEZIUM_TLS_CERT_FILE"`
+	KeyFile            string `yaml:"key_file"             env:"DEBEZIUM_TLS_KEY_FILE"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" env:"DEBEZIUM_TLS_INSECURE_SKIP_VERIFY"`
+}
+
+func (t TLSConfig) Validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return errors.New("cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// options loads the files into client options.
+func (t TLSConfig) options() ([]debezium_client.Option, error) {
+	var opts []debezium_client.Option
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file %s: no PEM certificates found", t.CAFile)
+		}
+		opts = append(opts, debezium_client.WithRootCAs(pool))
+	}
+
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		opts = append(opts, debezium_client.WithClientCertificate(cert))
+	}
+
+	if t.InsecureSkipVerify {
+		opts = append(opts, debezium_client.WithTLSConfig(&tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: true, // explicitly requested for test clusters
+		}))
+	}
+
+	return opts, nil
+}
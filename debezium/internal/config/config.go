@@ -1,30 +1,206 @@
 package config
 
 import (
+	"debezium_server/internal/alerting"
+	"debezium_server/internal/auth"
+	"debezium_server/internal/cluster"
+	"debezium_server/internal/projection"
+	"debezium_server/internal/stream"
+	"debezium_server/pkg/cdc/kafka"
+	"debezium_server/pkg/configtemplate"
+	"debezium_server/pkg/logger"
 	"debezium_server/pkg/postgres"
+	"debezium_server/pkg/redact"
+	"debezium_server/pkg/signaling"
+	"debezium_server/pkg/tracing"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/joho/godotenv"
 )
 
+const (
+	defaultDotEnvPath = "./config/.env"
+)
+
+// Config is loaded in layers: defaults from env-default tags, then a YAML or
+// TOML file, then environment variables, then command-line flags. File keys
+// are the yaml tags, e.g. stream.buffer_size.
 type Config struct {
-	Environment string `env:"ENV" env-default:"development"`
+	Environment string `yaml:"environment" env:"ENV" env-default:"development"`
+
+	Port    int           `yaml:"port"    env:"PORT"         env-default:"8080"`
+	Timeout time.Duration `yaml:"timeout" env:"HTTP_TIMEOUT" env-default:"30s"`
+
+	// DebeziumBaseURL is a comma-separated list of Kafka Connect workers.
+	DebeziumBaseURL string `yaml:"debezium_base_url" env:"DEBEZIUM_BASE_URL" env-default:"http://localhost:8080"`
+
+	// Credentials and TLS of the default cluster.
+	DebeziumUsername string            `yaml:"debezium_username" env:"DEBEZIUM_USERNAME"`
+	DebeziumPassword string            `yaml:"debezium_password" env:"DEBEZIUM_PASSWORD"`
+	DebeziumToken    string            `yaml:"debezium_token"    env:"DEBEZIUM_TOKEN"`
+	DebeziumTLS      cluster.TLSConfig `yaml:"debezium_tls"`
+
+	// DebeziumHealthCheckInterval is how often the workers of the default
+	// cluster are probed.
+	DebeziumHealthCheckInterval time.Duration `yaml:"debezium_health_check_interval" env:"DEBEZIUM_HEALTH_CHECK_INTERVAL" env-default:"30s"`
 
-	Port    int           `env:"PORT"         env-default:"8080"`
-	Timeout time.Duration `env:"HTTP_TIMEOUT" env-default:"30s"`
+	// Clusters can only be set in the config file. When empty, a single
+	// cluster named "default" is built from DebeziumBaseURL.
+	Clusters []cluster.Config `yaml:"clusters"`
 
-	DebeziumBaseURL string `env:"DEBEZIUM_BASE_URL" env-default:"http://localhost:8080"`
+	Stream   stream.Config         `yaml:"stream"`
+	Signal   signaling.Config      `yaml:"signal"`
+	Template configtemplate.Config `yaml:"template"`
+	Redact   redact.Config         `yaml:"redact"`
+	Auth     auth.Config           `yaml:"auth"`
+	Tracing  tracing.Config        `yaml:"tracing"`
+	Log      logger.Config         `yaml:"log"`
+	Alerting alerting.Config       `yaml:"alerting"`
+	Kafka    kafka.Config          `yaml:"kafka"`
+	// Projection needs the Kafka consumer.
+	Projection projection.Config `yaml:"projection"`
 
-	postgres.Config
+	postgres.Config `yaml:"postgres"`
 }
 
-func ParseConfigFromEnv() (*Config, error) {
+// Load builds the config from all layers and validates it. flags may be nil.
+// The file is the -config flag or, if unset, the CONFIG_FILE variable.
+func Load(flags *Flags) (*Config, error) {
 	cfg := &Config{}
 
-	if err := cleanenv.ReadEnv(cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config from env: %w", err)
+	raw, err := readLayerFile(flags)
+	if err != nil {
+		return nil, err
+	}
+	if err := load(cfg, raw); err != nil {
+		return nil, err
+	}
+
+	flags.apply(cfg)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
+
+// postgresOnly is the part of Config LoadPostgres reads.
+type postgresOnly struct {
+	postgres.Config `yaml:"postgres"`
+}
+
+// LoadPostgres builds only the postgres section from the file and env
+// layers and validates it, so tools that only need the database are not
+// failed by the settings of the server.
+func LoadPostgres(flags *Flags) (postgres.Config, error) {
+	var cfg postgresOnly
+
+	raw, err := readLayerFile(flags)
+	if err != nil {
+		return postgres.Config{}, err
+	}
+	if section, ok := raw["postgres"]; ok {
+		raw = map[string]any{"postgres": section}
+	} else {
+		raw = nil
+	}
+	if err := load(&cfg, raw); err != nil {
+		return postgres.Config{}, err
+	}
+
+	if problems := validatePostgres(cfg.Config); len(problems) > 0 {
+		return postgres.Config{}, newValidationError(reflect.ValueOf(&cfg).Elem(), problems)
+	}
+	return cfg.Config, nil
+}
+
+// readLayerFile reads the config file of flags, or returns nil without one.
+func readLayerFile(flags *Flags) (map[string]any, error) {
+	path := flags.file()
+	if path == "" {
+		return nil, nil
+	}
+	return readFile(path)
+}
+
+// load fills cfg, a pointer to a config struct, from raw, the config file,
+// and then the environment.
+func load(cfg any, raw map[string]any) error {
+	fileValues, err := decode(cfg, raw)
+	if err != nil {
+		return err
+	}
+
+	if err := cleanenv.ReadEnv(cfg); err != nil {
+		return fmt.Errorf("failed to parse config from env: %w", err)
+	}
+
+	// cleanenv applies env-default to every zero field, which overwrites
+	// zero values set explicitly in the file, e.g. auth.enabled: false.
+	for _, v := range fileValues {
+		if _, ok := os.LookupEnv(v.env); !ok {
+			v.field.Set(v.value)
+		}
+	}
+	return nil
+}
+
+// ConnectClusters returns the configured clusters, or the default cluster.
+func (c *Config) ConnectClusters() []cluster.Config {
+	if len(c.Clusters) > 0 {
+		return c.Clusters
+	}
+	return []cluster.Config{{
+		Name:     cluster.DefaultName,
+		URLs:     c.DebeziumWorkers(),
+		Timeout:  c.Timeout,
+		Username: c.DebeziumUsername,
+		Password: c.DebeziumPassword,
+		Token:    c.DebeziumToken,
+		TLS:      c.DebeziumTLS,
+
+		HealthCheckInterval: c.DebeziumHealthCheckInterval,
+
+		SignalDSN:   c.Signal.DSN,
+		SignalTable: c.Signal.Table,
+	}}
+}
+
+// DebeziumWorkers splits DebeziumBaseURL into worker URLs.
+func (c *Config) DebeziumWorkers() []string {
+	var out []string
+	for _, u := range strings.Split(c.DebeziumBaseURL, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// LoadDotEnv adds the variables of ENV_PATH to the environment. Without
+// ENV_PATH it loads ./config/.env if that file exists, so deployments that
+// mount a config file do not need one.
+func LoadDotEnv() error {
+	path, explicit := os.LookupEnv("ENV_PATH")
+	if !explicit || path == "" {
+		path = defaultDotEnvPath
+	}
+
+	err := godotenv.Load(path)
+	if err != nil && !explicit && errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error loading .env file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// baseFile disables auth, which otherwise needs keys to validate.
+const baseFile = "auth:\n  enabled: false\n"
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func parseFlags(t *testing.T, args ...string) *Flags {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	return flags
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  string
+		flag string
+		want int
+	}{
+		{name: "default", want: 8080},
+		{name: "file", file: "port: 9000\n", want: 9000},
+		{name: "env over file", file: "port: 9000\n", env: "9100", want: 9100},
+		{name: "flag over env", file: "port: 9000\n", env: "9100", flag: "9200", want: 9200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("PORT", tt.env)
+			}
+			args := []string{"-config", writeConfig(t, baseFile+tt.file)}
+			if tt.flag != "" {
+				args = append(args, "-port", tt.flag)
+			}
+
+			cfg, err := Load(parseFlags(t, args...))
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Port != tt.want {
+				t.Errorf("port is %d, want %d", cfg.Port, tt.want)
+			}
+			// The env default of auth.enabled is true; the false of the
+			// file is restored after the env layer.
+			if cfg.Auth.Enabled {
+				t.Error("auth.enabled: false of the file was overwritten by its env default")
+			}
+		})
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		key  string
+	}{
+		{name: "unknown key", file: "stream:\n  bufer_size: 10\n", key: "stream.bufer_size"},
+		{name: "wrong type", file: "stream:\n  buffer_size: many\n", key: "stream.buffer_size"},
+		{name: "invalid value", file: "stream:\n  slow_consumer: block\n", key: "stream.slow_consumer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(parseFlags(t, "-config", writeConfig(t, baseFile+tt.file)))
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Load returned %v, want a ValidationError", err)
+			}
+			if len(verr.Problems) != 1 || verr.Problems[0].Key != tt.key {
+				t.Errorf("got problems %+v, want one for %s", verr.Problems, tt.key)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid, err := Load(parseFlags(t, "-config", writeConfig(t, baseFile)))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		key    string
+		env    string
+	}{
+		{name: "port", change: func(c *Config) { c.Port = 70000 }, key: "port", env: "PORT"},
+		{name: "timeout", change: func(c *Config) { c.Timeout = 0 }, key: "timeout", env: "HTTP_TIMEOUT"},
+		{
+			name:   "worker URL",
+			change: func(c *Config) { c.DebeziumBaseURL = "connect:8083" },
+			key:    "debezium_base_url",
+			env:    "DEBEZIUM_BASE_URL",
+		},
+		{
+			name:   "auth without keys",
+			change: func(c *Config) { c.Auth.Enabled = true },
+			key:    "auth.enabled",
+			env:    "AUTH_ENABLED",
+		},
+		{
+			name:   "projection without kafka",
+			change: func(c *Config) { c.Projection.Enabled = []string{"order_summaries"} },
+			key:    "projection.enabled",
+			env:    "PROJECTIONS",
+		},
+		{
+			name:   "postgres sslmode",
+			change: func(c *Config) { c.Config.SSLMode = "on" },
+			key:    "postgres.sslmode",
+			env:    "POSTGRES_SSLMODE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := *valid
+			tt.change(&c)
+			var verr *ValidationError
+			if !errors.As(c.Validate(), &verr) {
+				t.Fatal("the config is valid")
+			}
+			if len(verr.Problems) != 1 || verr.Problems[0].Key != tt.key || verr.Problems[0].Env != tt.env {
+				t.Errorf("got problems %+v, want one for %s (%s)", verr.Problems, tt.key, tt.env)
+			}
+		})
+	}
+}
+
+func TestChanges(t *testing.T) {
+	applied, err := Load(parseFlags(t, "-config", writeConfig(t, baseFile)))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		runtime []string
+		restart []string
+	}{
+		{name: "unchanged", change: func(*Config) {}},
+		{name: "log level", change: func(c *Config) { c.Log.Level = "warn" }, runtime: []string{"log.level"}},
+		{
+			name:    "default cluster URL",
+			change:  func(c *Config) { c.DebeziumBaseURL = "http://connect:8083" },
+			runtime: []string{"debezium_base_url"},
+		},
+		{
+			name:    "restart keys",
+			change:  func(c *Config) { c.Port = 9000; c.Stream.BufferSize = 10; c.Log.Level = "warn" },
+			runtime: []string{"log.level"},
+			restart: []string{"port", "stream.buffer_size"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := *applied
+			tt.change(&next)
+			runtime, restart := Changes(applied, &next)
+			if !slices.Equal(runtime, tt.runtime) || !slices.Equal(restart, tt.restart) {
+				t.Errorf("got runtime %v and restart %v, want %v and %v", runtime, restart, tt.runtime, tt.restart)
+			}
+		})
+	}
+}
+
+// With clusters configured, the URL backs no cluster and needs a restart
+// like the clusters.
+func TestChangesWithClusters(t *testing.T) {
+	file := baseFile + "clusters:\n  - name: primary\n    url: http://connect-a:8083\n"
+	applied, err := Load(parseFlags(t, "-config", writeConfig(t, file)))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	next := *applied
+	next.DebeziumBaseURL = "http://connect:8083"
+	next.Clusters = slices.Clone(applied.Clusters)
+	next.Clusters[0].Timeout = time.Minute
+
+	runtime, restart := Changes(applied, &next)
+	if len(runtime) != 0 || !slices.Equal(restart, []string{"clusters", "debezium_base_url"}) {
+		t.Errorf("got runtime %v and restart %v, want clusters and debezium_base_url to need a restart", runtime, restart)
+	}
+}
+
+func TestLoadPostgres(t *testing.T) {
+	// The server settings are invalid, but only postgres is read.
+	file := "port: 0\nstream:\n  slow_consumer: block\nunknown: 1\n" +
+		"postgres:\n  host: pg\n  max_conns: 4\n"
+	t.Setenv("POSTGRES_DB", "app")
+
+	cfg, err := LoadPostgres(parseFlags(t, "-config", writeConfig(t, file)))
+	if err != nil {
+		t.Fatalf("LoadPostgres: %v", err)
+	}
+	if cfg.Host != "pg" || cfg.MaxConns != 4 || cfg.DbName != "app" || cfg.Port != "5432" {
+		t.Errorf("got %+v, want host pg, 4 connections, database app and the default port", cfg)
+	}
+
+	_, err = LoadPostgres(parseFlags(t, "-config", writeConfig(t, "postgres:\n  sslmode: on\n")))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 1 || verr.Problems[0].Env != "POSTGRES_SSLMODE" {
+		t.Errorf("LoadPostgres returned %v, want a problem with POSTGRES_SSLMODE", err)
+	}
+}
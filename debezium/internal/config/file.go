@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// fileValue is a leaf set by the config file, kept so it can be restored
+// after env defaults are applied.
+type fileValue struct {
+	env   string
+	field reflect.Value
+	value reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// readFile reads a YAML or TOML file into a generic map, chosen by extension.
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+
+	raw := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("config file: unsupported format %q, use .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return raw, nil
+}
+
+// decode sets the fields of cfg, a pointer to a config struct, named by raw.
+// Each leaf is decoded on its own so that unknown keys and type errors name
+// the offending key.
+func decode(cfg any, raw map[string]any) ([]fileValue, error) {
+	var (
+		values   []fileValue
+		problems []Problem
+	)
+	decodeStruct(reflect.ValueOf(cfg).Elem(), raw, "", &values, &problems)
+
+	if len(problems) > 0 {
+		sort.Slice(problems, func(i, j int) bool { return problems[i].Key < problems[j].Key })
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	return values, nil
+}
+
+func decodeStruct(v reflect.Value, raw map[string]any, prefix string, values *[]fileValue, problems *[]Problem) {
+	fields := fieldsByKey(v)
+
+	for key, val := range raw {
+		path := prefix + key
+		f, ok := fields[key]
+		if !ok {
+			*problems = append(*problems, Problem{Key: path, Reason: "is not a known config key"})
+			continue
+		}
+
+		if isSection(f.value) {
+			section, ok := val.(map[string]any)
+			if !ok {
+				*problems = append(*problems, Problem{Key: path, Reason: "must be a table of keys"})
+				continue
+			}
+			decodeStruct(f.value, section, path+".", values, problems)
+			continue
+		}
+
+		data, err := yaml.Marshal(val)
+		if err == nil {
+			dec := yaml.NewDecoder(bytes.NewReader(data))
+			dec.KnownFields(true)
+			err = dec.Decode(f.value.Addr().Interface())
+		}
+		if err != nil {
+			*problems = append(*problems, Problem{Key: path, Env: f.env, Reason: "has an invalid value: " + yamlReason(err)})
+			continue
+		}
+
+		copied := reflect.New(f.value.Type()).Elem()
+		copied.Set(f.value)
+		*values = append(*values, fileValue{env: f.env, field: f.value, value: copied})
+	}
+}
+
+type field struct {
+	value reflect.Value
+	env   string
+}
+
+func fieldsByKey(v reflect.Value) map[string]field {
+	fields := make(map[string]field, v.NumField())
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if key == "" || key == "-" || !sf.IsExported() {
+			continue
+		}
+		env, _, _ := strings.Cut(sf.Tag.Get("env"), ",")
+		fields[key] = field{value: v.Field(i), env: env}
+	}
+	return fields
+}
+
+// isSection reports whether v is a nested config struct rather than a value.
+func isSection(v reflect.Value) bool {
+	return v.Kind() == reflect.Struct && v.Type() != durationType
+}
+
+// yamlReason strips the line prefix yaml adds, since the line refers to
+// the single value being decoded, not the file.
+func yamlReason(err error) string {
+	msg := err.Error()
+	msg = strings.TrimPrefix(msg, "yaml: unmarshal errors:\n  ")
+	if _, rest, ok := strings.Cut(msg, ": "); ok && strings.HasPrefix(msg, "line ") {
+		return rest
+	}
+	return msg
+}
+
+// envNames maps each leaf key to its environment variable.
+func envNames(v reflect.Value, prefix string, out map[string]string) {
+	for key, f := range fieldsByKey(v) {
+		if isSection(f.value) {
+			envNames(f.value, prefix+key+".", out)
+			continue
+		}
+		out[prefix+key] = f.env
+	}
+}
+
+// Diff returns the keys whose values differ between two configs, sorted.
+func Diff(a, b *Config) []string {
+	var keys []string
+	diffStruct(reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem(), "", &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+// Changes splits the keys that differ between the config in effect and a
+// reloaded one into those that can be applied at runtime and those that
+// need a restart.
+func Changes(applied, next *Config) (runtime, restart []string) {
+	for _, key := range Diff(applied, next) {
+		switch key {
+		case "log.level":
+			runtime = append(runtime, key)
+		case "debezium_base_url":
+			// The URL only backs the default cluster when no clusters are
+			// configured; changes to clusters need a restart.
+			if len(applied.Clusters) > 0 {
+				restart = append(restart, key)
+				continue
+			}
+			runtime = append(runtime, key)
+		default:
+			restart = append(restart, key)
+		}
+	}
+	return runtime, restart
+}
+
+func diffStruct(a, b reflect.Value, prefix string, keys *[]string) {
+	fa, fb := fieldsByKey(a), fieldsByKey(b)
+	for key, f := range fa {
+		if isSection(f.value) {
+			diffStruct(f.value, fb[key].value, prefix+key+".", keys)
+			continue
+		}
+		if !reflect.DeepEqual(f.value.Interface(), fb[key].value.Interface()) {
+			*keys = append(*keys, prefix+key)
+		}
+	}
+}
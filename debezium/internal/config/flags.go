@@ -0,0 +1,50 @@
+package config
+
+import (
+	"flag"
+	"os"
+)
+
+// Flags are the command-line overrides, applied after the file and env.
+// Only flags given on the command line take effect.
+type Flags struct {
+	fs *flag.FlagSet
+
+	File            string
+	Port            int
+	LogLevel        string
+	DebeziumBaseURL string
+}
+
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs}
+	fs.StringVar(&f.File, "config", "", "path to a YAML or TOML config file (default $CONFIG_FILE)")
+	fs.IntVar(&f.Port, "port", 0, "HTTP port")
+	fs.StringVar(&f.LogLevel, "log-level", "", "log level: debug, info, warn or error")
+	fs.StringVar(&f.DebeziumBaseURL, "debezium-url", "", "Kafka Connect REST URLs, comma-separated")
+	return f
+}
+
+func (f *Flags) file() string {
+	if f != nil && f.File != "" {
+		return f.File
+	}
+	return os.Getenv("CONFIG_FILE")
+}
+
+func (f *Flags) apply(cfg *Config) {
+	if f == nil || f.fs == nil {
+		return
+	}
+
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "port":
+			cfg.Port = f.Port
+		case "log-level":
+			cfg.Log.Level = f.LogLevel
+		case "debezium-url":
+			cfg.DebeziumBaseURL = f.DebeziumBaseURL
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"debezium_server/internal/stream"
+	"debezium_server/pkg/logger"
+	"debezium_server/pkg/postgres"
+	"debezium_server/pkg/tracing"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Problem is one invalid config key. Env is the variable that sets it.
+type Problem struct {
+	Key    string
+	Env    string
+	Reason string
+}
+
+// ValidationError lists every invalid key.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		if p.Env != "" {
+			msgs = append(msgs, fmt.Sprintf("%s (%s) %s", p.Key, p.Env, p.Reason))
+			continue
+		}
+		msgs = append(msgs, fmt.Sprintf("%s %s", p.Key, p.Reason))
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks the values that would otherwise fail late, at first use.
+func (c *Config) Validate() error {
+	var problems []Problem
+	check := func(ok bool, key, reason string) {
+		if !ok {
+			problems = append(problems, Problem{Key: key, Reason: reason})
+		}
+	}
+
+	check(c.Port > 0 && c.Port <= 65535, "port", "must be between 1 and 65535")
+	check(c.Timeout > 0, "timeout", "must be positive")
+	workers := c.DebeziumWorkers()
+	check(len(workers) > 0, "debezium_base_url", "must list at least one http or https URL")
+	for _, w := range workers {
+		check(validHTTPURL(w), "debezium_base_url", fmt.Sprintf("%q is not an http or https URL", w))
+	}
+	check(c.DebeziumToken == "" || (c.DebeziumUsername == "" && c.DebeziumPassword == ""),
+		"debezium_token", "must not be set together with debezium_username or debezium_password")
+	check(c.DebeziumHealthCheckInterval >= 0, "debezium_health_check_interval", "must not be negative")
+	if err := c.DebeziumTLS.Validate(); err != nil {
+		check(false, "debezium_tls", err.Error())
+	}
+
+	seen := map[string]bool{}
+	for i, cl := range c.Clusters {
+		key := fmt.Sprintf("clusters[%d]", i)
+		if err := cl.Validate(); err != nil {
+			check(false, key, err.Error())
+			continue
+		}
+		check(!seen[cl.Name], key, fmt.Sprintf("repeats the name %q", cl.Name))
+		seen[cl.Name] = true
+	}
+
+	check(c.Stream.BufferSize > 0, "stream.buffer_size", "must be positive")
+	check(c.Stream.HistorySize >= 0, "stream.history_size", "must not be negative")
+	check(c.Stream.SlowConsumer == stream.PolicyDisconnect || c.Stream.SlowConsumer == stream.PolicyDropOldest,
+		"stream.slow_consumer", fmt.Sprintf("must be %q or %q", stream.PolicyDisconnect, stream.PolicyDropOldest))
+	check(c.Stream.HeartbeatInterval > 0, "stream.heartbeat_interval", "must be positive")
+
+	check(c.Redact.Mask != "", "redact.mask", "must not be empty")
+
+	check(!c.Auth.Enabled || len(c.Auth.APIKeys) > 0 || c.Auth.JWKSFile != "",
+		"auth.enabled", "requires auth.api_keys or auth.jwks_file")
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterFile:
+	default:
+		check(false, "tracing.exporter", fmt.Sprintf("must be %q, %q or %q",
+			tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterFile))
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio", "must be between 0 and 1")
+
+	problems = append(problems, c.validateAlerting()...)
+
+	if c.Kafka.Enabled {
+		if err := c.Kafka.Validate(); err != nil {
+			check(false, "kafka", err.Error())
+		}
+	}
+
+	if len(c.Projection.Enabled) > 0 {
+		check(c.Kafka.Enabled, "projection.enabled", "requires kafka.enabled")
+		if err := c.Projection.Validate(); err != nil {
+			check(false, "projection.enabled", err.Error())
+		}
+	}
+
+	_, err := logger.ParseLevel(c.Environment, c.Log.Level)
+	check(err == nil, "log.level", "must be debug, info, warn or error")
+
+	problems = append(problems, validatePostgres(c.Config)...)
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return newValidationError(reflect.ValueOf(c).Elem(), problems)
+}
+
+// newValidationError names the variable of every problem, taken from the
+// config struct v, and sorts the problems by key.
+func newValidationError(v reflect.Value, problems []Problem) *ValidationError {
+	envs := map[string]string{}
+	envNames(v, "", envs)
+	for i := range problems {
+		problems[i].Env = envs[problems[i].Key]
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].Key < problems[j].Key })
+
+	return &ValidationError{Problems: problems}
+}
+
+func validatePostgres(c postgres.Config) []Problem {
+	var problems []Problem
+	check := func(ok bool, key, reason string) {
+		if !ok {
+			problems = append(problems, Problem{Key: key, Reason: reason})
+		}
+	}
+
+	port, err := strconv.Atoi(c.Port)
+	check(err == nil && port > 0 && port <= 65535, "postgres.port", "must be between 1 and 65535")
+	check(c.Host != "", "postgres.host", "must not be empty")
+	check(slices.Contains(postgres.SSLModes, c.SSLMode), "postgres.sslmode",
+		"must be one of "+strings.Join(postgres.SSLModes, ", "))
+	check((c.SSLCert == "") == (c.SSLKey == ""), "postgres.sslcert", "must be set together with postgres.sslkey")
+	check(c.MaxConns >= 0, "postgres.max_conns", "must not be negative")
+	check(c.MinConns >= 0 && (c.MaxConns == 0 || c.MinConns <= c.MaxConns),
+		"postgres.min_conns", "must be between 0 and postgres.max_conns")
+	check(c.MaxConnLifetime >= 0, "postgres.max_conn_lifetime", "must not be negative")
+	check(c.MaxConnIdleTime >= 0, "postgres.max_conn_idle_time", "must not be negative")
+	check(c.HealthCheckPeriod >= 0, "postgres.health_check_period", "must not be negative")
+
+	return problems
+}
+
+func (c *Config) validateAlerting() []Problem {
+	var problems []Problem
+	check := func(ok bool, key, reason string) {
+		if !ok {
+			problems = append(problems, Problem{Key: key, Reason: reason})
+		}
+	}
+
+	a := c.Alerting
+	check(a.Interval > 0, "alerting.interval", "must be positive")
+	check(a.RepeatInterval >= 0, "alerting.repeat_interval", "must not be negative")
+	check(!a.Enabled || len(a.Webhooks) > 0, "alerting.enabled", "requires alerting.webhooks")
+
+	webhooks := map[string]bool{}
+	for i, w := range a.Webhooks {
+		key := fmt.Sprintf("alerting.webhooks[%d]", i)
+		if err := w.Validate(); err != nil {
+			check(false, key, err.Error())
+			continue
+		}
+		check(!webhooks[w.Name], key, fmt.Sprintf("repeats the name %q", w.Name))
+		webhooks[w.Name] = true
+		check(!a.Enabled || w.Secret != "" || a.WebhookSecret != "", key,
+			"needs a secret, or alerting.webhook_secret must be set, so receivers can verify it")
+	}
+
+	clusters := map[string]bool{}
+	for _, cl := range c.ConnectClusters() {
+		clusters[cl.Name] = true
+	}
+	rules := map[string]bool{}
+	for i, r := range a.Rules {
+		key := fmt.Sprintf("alerting.rules[%d]", i)
+		if err := r.Validate(); err != nil {
+			check(false, key, err.Error())
+			continue
+		}
+		check(!rules[r.Name], key, fmt.Sprintf("repeats the name %q", r.Name))
+		rules[r.Name] = true
+		check(r.Cluster == "" || clusters[r.Cluster], key, fmt.Sprintf("refers to unknown cluster %q", r.Cluster))
+		for _, w := range r.Webhooks {
+			check(webhooks[w], key, fmt.Sprintf("refers to unknown webhook %q", w))
+		}
+	}
+
+	return problems
+}
+
+func validHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditCreate       AuditAction = "create"
+	AuditUpdate       AuditAction = "update"
+	AuditPause        AuditAction = "pause"
+	AuditResume       AuditAction = "resume"
+	AuditRestart      AuditAction = "restart"
+	AuditStop         AuditAction = "stop"
+	AuditDelete       AuditAction = "delete"
+	AuditAlterOffsets AuditAction = "alter_offsets"
+	AuditResetOffsets AuditAction = "reset_offsets"
+	AuditCopy         AuditAction = "copy"
+	AuditMigrate      AuditAction = "migrate"
+	AuditResetTopics  AuditAction = "reset_topics"
+	AuditRollback     AuditAction = "rollback"
+	AuditRestore      AuditAction = "restore"
+)
+
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditChange is the before and after value of one config key. A nil
+// value means the key was absent. Secret values are masked.
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+type AuditEntry struct {
+	ID         int64                  `json:"id"`
+	Principal  string                 `json:"principal"`
+	AuthMethod string                 `json:"auth_method,omitempty"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	Action     AuditAction            `json:"action"`
+	Cluster    string                 `json:"cluster"`
+	Connector  string                 `json:"connector"`
+	TaskID     *int                   `json:"task_id,omitempty"`
+	Diff       map[string]AuditChange `json:"diff"`
+	Result     string                 `json:"result"`
+	Error      string                 `json:"error,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditFilter narrows an audit log query. Zero fields are not applied.
+// Key matches entries whose diff touches that config key.
+type AuditFilter struct {
+	Cluster   string
+	Connector string
+	Principal string
+	Action    AuditAction
+	Key       string
+	From      time.Time
+	To        time.Time
+	Offset    int
+	Limit     int
+}
@@ -1,5 +1,11 @@
 package models
 
+import "errors"
+
+var (
+	ErrUserAlreadyExists = errors.New("user already exists")
+)
+
 type User struct {
 	ID       int64    `json:"id"`
 	Email    string   `json:"email"`
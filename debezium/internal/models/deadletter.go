@@ -0,0 +1,39 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+)
+
+// DeadLetterHeader is a Kafka record header. Value is base64 in JSON.
+type DeadLetterHeader struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// DeadLetter is a CDC message the consumer could not handle, with its
+// position in the source topic.
+type DeadLetter struct {
+	ID          int64              `json:"id"`
+	Topic       string             `json:"topic"`
+	Partition   int32              `json:"partition"`
+	Offset      int64              `json:"offset"`
+	Key         []byte             `json:"-"`
+	Value       []byte             `json:"-"`
+	Headers     []DeadLetterHeader `json:"headers"`
+	MessageTime time.Time          `json:"message_time"`
+	Error       string             `json:"error"`
+	Attempts    int                `json:"attempts"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+type DeadLetterFilter struct {
+	Topic  string
+	Offset int
+	Limit  int
+}
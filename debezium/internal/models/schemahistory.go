@@ -0,0 +1,50 @@
+package models
+
+import (
+	"debezium_server/pkg/cdc"
+	"errors"
+	"time"
+)
+
+const (
+	// SchemaSourceEvent versions are taken from the schema of change
+	// events, SchemaSourceDDL versions from the schema change topic.
+	SchemaSourceEvent = "event"
+	SchemaSourceDDL   = "ddl"
+)
+
+var (
+	ErrTableSchemaNotFound = errors.New("table schema not found")
+)
+
+// TableSchemaVersion is a schema of a table as first seen by the consumer,
+// with the changes from the previous version of the same source.
+type TableSchemaVersion struct {
+	ID          int64              `json:"id"`
+	Table       string             `json:"table"`
+	Source      string             `json:"source"`
+	DDL         string             `json:"ddl,omitempty"`
+	Columns     []cdc.Column       `json:"columns"`
+	Changes     []cdc.ColumnChange `json:"changes"`
+	Breaking    bool               `json:"breaking"`
+	Topic       string             `json:"topic"`
+	Partition   int32              `json:"partition"`
+	Offset      int64              `json:"offset"`
+	MessageTime time.Time          `json:"message_time"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+type SchemaHistoryFilter struct {
+	Table        string
+	BreakingOnly bool
+	Offset       int
+	Limit        int
+}
+
+// SchemaCheck is a proposed schema compared with the latest version of the
+// table.
+type SchemaCheck struct {
+	Compatible bool               `json:"compatible"`
+	VersionID  int64              `json:"version_id"`
+	Changes    []cdc.ColumnChange `json:"changes"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// ReplicationSlot is a logical replication slot of the database. LagBytes
+// is the WAL retained for the slot beyond what its consumer has confirmed.
+type ReplicationSlot struct {
+	Name              string `json:"name"`
+	Plugin            string `json:"plugin"`
+	Database          string `json:"database"`
+	Active            bool   `json:"active"`
+	ConfirmedFlushLSN string `json:"confirmed_flush_lsn"`
+	LagBytes          int64  `json:"lag_bytes"`
+}
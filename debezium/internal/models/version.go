@@ -0,0 +1,32 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrVersionNotFound = errors.New("connector version not found")
+)
+
+// ConnectorVersion is a connector config as applied by the service.
+// Versions count up from 1 per cluster and connector. Secret values are
+// masked.
+type ConnectorVersion struct {
+	ID        int64             `json:"id"`
+	Cluster   string            `json:"cluster"`
+	Connector string            `json:"connector"`
+	Version   int               `json:"version"`
+	Config    map[string]string `json:"config"`
+	Author    string            `json:"author"`
+	Comment   string            `json:"comment,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type ConnectorVersionFilter struct {
+	Cluster   string
+	Connector string
+	Offset    int
+	Limit     int
+}
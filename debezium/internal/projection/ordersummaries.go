@@ -0,0 +1,217 @@
+package projection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OrderSummariesName = "order_summaries"
+
+	ordersTable     = "inventory.orders"
+	orderItemsTable = "inventory.order_items"
+	customersTable  = "inventory.customers"
+)
+
+// OrderSummaries keeps one row per order in order_summaries with its
+// customer and the totals of its items, from the inventory tables of
+// init/init-scripts/init.sql. It expects the connector's
+// decimal.handling.mode=double and time.precision.mode=adaptive.
+type OrderSummaries struct{}
+
+var _ Projection = (*OrderSummaries)(nil)
+
+func NewOrderSummaries() Projection {
+	return &OrderSummaries{}
+}
+
+func (*OrderSummaries) Name() string {
+	return OrderSummariesName
+}
+
+func (*OrderSummaries) Sources() map[string][]string {
+	return map[string][]string{
+		ordersTable:     {"id"},
+		orderItemsTable: {"id"},
+		customersTable:  {"id"},
+	}
+}
+
+func (o *OrderSummaries) Apply(ctx context.Context, tx *Tx, change Change) error {
+	switch change.Table {
+	case ordersTable, orderItemsTable:
+		// An item moved to another order changes both.
+		column := "id"
+		if change.Table == orderItemsTable {
+			column = "order_id"
+		}
+		for _, id := range idsOf(change, column) {
+			if err := o.refresh(ctx, tx, id, change.LSN); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case customersTable:
+		for _, id := range idsOf(change, "id") {
+			orders, err := tx.Rows(ctx, ordersTable, map[string]any{"customer_id": id})
+			if err != nil {
+				return err
+			}
+			for _, order := range orders {
+				if orderID, ok := integer(order["id"]); ok {
+					if err := o.refresh(ctx, tx, orderID, change.LSN); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+func (*OrderSummaries) Reset(ctx context.Context, tx *Tx, before time.Time) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM order_summaries WHERE updated_at < $1", before); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
+
+// refresh rebuilds the summary of an order from the stored rows, or
+// deletes it if the order is gone.
+func (*OrderSummaries) refresh(ctx context.Context, tx *Tx, orderID int64, lsn int64) error {
+	order, ok, err := tx.Row(ctx, ordersTable, orderID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if _, err := tx.Exec(ctx, "DELETE FROM order_summaries WHERE order_id = $1", orderID); err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+		return nil
+	}
+
+	var (
+		customerID                  *int64
+		customerName, customerEmail *string
+	)
+	if id, ok := integer(order["customer_id"]); ok {
+		customerID = &id
+		customer, ok, err := tx.Row(ctx, customersTable, id)
+		if err != nil {
+			return err
+		}
+		if ok {
+			name := strings.TrimSpace(text(customer["first_name"]) + " " + text(customer["last_name"]))
+			email := text(customer["email"])
+			customerName, customerEmail = &name, &email
+		}
+	}
+
+	items, err := tx.Rows(ctx, orderItemsTable, map[string]any{"order_id": orderID})
+	if err != nil {
+		return err
+	}
+	var quantity int64
+	var itemsTotal float64
+	for _, item := range items {
+		q := number(item["quantity"])
+		quantity += int64(q)
+		itemsTotal += q * number(item["price"])
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO order_summaries (
+			order_id, customer_id, customer_name, customer_email, status, order_date,
+			total_amount, item_count, total_quantity, items_total, source_lsn, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, CURRENT_TIMESTAMP)
+		ON CONFLICT (order_id) DO UPDATE SET
+			customer_id = EXCLUDED.customer_id,
+			customer_name = EXCLUDED.customer_name,
+			customer_email = EXCLUDED.customer_email,
+			status = EXCLUDED.status,
+			order_date = EXCLUDED.order_date,
+			total_amount = EXCLUDED.total_amount,
+			item_count = EXCLUDED.item_count,
+			total_quantity = EXCLUDED.total_quantity,
+			items_total = EXCLUDED.items_total,
+			source_lsn = EXCLUDED.source_lsn,
+			updated_at = EXCLUDED.updated_at`,
+		orderID, customerID, customerName, customerEmail, order["status"], timestamp(order["order_date"]),
+		order["total_amount"], len(items), quantity, itemsTotal, lsn,
+	)
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+	return nil
+}
+
+// idsOf returns the distinct values of an integer column before and after
+// the change.
+func idsOf(change Change, column string) []int64 {
+	var ids []int64
+	for _, row := range []map[string]any{change.Before, change.After} {
+		id, ok := integer(row[column])
+		if ok && (len(ids) == 0 || ids[0] != id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func integer(v any) (int64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	return int64(number(v)), true
+}
+
+func text(v any) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// number reads a decimal written as a double or, with
+// decimal.handling.mode=string, as a string.
+func number(v any) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case json.Number:
+		f, _ := x.Float64()
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(x, 64)
+		return f
+	}
+	return 0
+}
+
+// timestamp reads a TIMESTAMP column: microseconds since the epoch with
+// time.precision.mode=adaptive, or an ISO 8601 string.
+func timestamp(v any) *time.Time {
+	var t time.Time
+	switch x := v.(type) {
+	case float64:
+		t = time.UnixMicro(int64(x)).UTC()
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, x)
+		if err != nil {
+			return nil
+		}
+		t = parsed
+	default:
+		return nil
+	}
+	return &t
+}
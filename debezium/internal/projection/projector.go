@@ -0,0 +1,466 @@
+// Package projection maintains read tables in Postgres from change events.
+//
+// The projector keeps the latest version of every source row a projection
+// reads in projection_rows, together with the LSN of the change that wrote
+// it. A change is applied only if its LSN is above the stored one, so
+// redelivered and replayed events are ignored, and deleted rows are kept as
+// markers so an old event cannot bring them back. Projections then rebuild
+// the affected read rows from the stored source rows.
+package projection
+
+import (
+	"context"
+	"debezium_server/pkg/cdc"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	rowsTable   = "projection_rows"
+	statusTable = "projections"
+)
+
+var (
+	ErrUnknownProjection = errors.New("unknown projection")
+	ErrMissingKey        = errors.New("change event lacks a key column")
+)
+
+// Config lists the projections to run, by name.
+type Config struct {
+	Enabled []string `yaml:"enabled" env:"PROJECTIONS" env-separator:","`
+}
+
+// Builtin are the projections that can be enabled by name.
+var Builtin = map[string]func() Projection{
+	OrderSummariesName: NewOrderSummaries,
+}
+
+// Validate returns a description of the first problem with the config.
+func (c Config) Validate() error {
+	for _, name := range c.Enabled {
+		if _, ok := Builtin[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownProjection, name)
+		}
+	}
+	return nil
+}
+
+// Projection maintains read tables from the rows of its source tables.
+type Projection interface {
+	// Name identifies the projection in the state tables and the API.
+	Name() string
+	// Sources maps the source tables, e.g. inventory.orders, to their
+	// primary key columns.
+	Sources() map[string][]string
+	// Apply updates the read tables after a source row changed. The change
+	// is already stored, so Tx.Row returns the new version.
+	Apply(ctx context.Context, tx *Tx, change Change) error
+	// Reset removes the read rows last written before a rebuild started;
+	// those written since come from the rebuild and are kept.
+	Reset(ctx context.Context, tx *Tx, before time.Time) error
+}
+
+// Change is a source row change. Before is the version the projection
+// stored last, nil for a new row; After is nil for a deleted row.
+type Change struct {
+	Table  string
+	Op     cdc.Op
+	Before map[string]any
+	After  map[string]any
+	LSN    int64
+}
+
+// Tx is the transaction a change is applied in, with access to the stored
+// source rows of the projection.
+type Tx struct {
+	pgx.Tx
+	projection string
+	builder    squirrel.StatementBuilderType
+}
+
+// Row returns the stored row of a source table by its key values, in the
+// order of the key columns.
+func (tx *Tx) Row(ctx context.Context, table string, key ...any) (map[string]any, bool, error) {
+	k, err := json.Marshal(key)
+	if err != nil {
+		return nil, false, fmt.Errorf("Row: %w", err)
+	}
+
+	query, args, err := tx.builder.Select("data").
+		From(rowsTable).
+		Where(squirrel.Eq{"projection": tx.projection, "source_table": table, "row_key": string(k), "deleted": false}).
+		ToSql()
+	if err != nil {
+		return nil, false, fmt.Errorf("Row: %w", err)
+	}
+
+	var row map[string]any
+	err = tx.QueryRow(ctx, query, args...).Scan(&row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("Row: %w", err)
+	}
+	return row, true, nil
+}
+
+// Rows returns the stored rows of a source table whose columns contain
+// match, e.g. {"order_id": 5}.
+func (tx *Tx) Rows(ctx context.Context, table string, match map[string]any) ([]map[string]any, error) {
+	m, err := json.Marshal(match)
+	if err != nil {
+		return nil, fmt.Errorf("Rows: %w", err)
+	}
+
+	query, args, err := tx.builder.Select("data").
+		From(rowsTable).
+		Where(squirrel.Eq{"projection": tx.projection, "source_table": table, "deleted": false}).
+		Where("data @> ?::jsonb", string(m)).
+		OrderBy("row_key").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("Rows: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Rows: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]map[string]any, 0)
+	for rows.Next() {
+		var row map[string]any
+		if err := rows.Scan(&row); err != nil {
+			return nil, fmt.Errorf("Rows: %w", err)
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Rows: %w", err)
+	}
+	return result, nil
+}
+
+// Status is the progress of a projection.
+type Status struct {
+	Name    string   `json:"name"`
+	Sources []string `json:"sources"`
+	// LSN is the highest source LSN applied since the last rebuild.
+	LSN                int64      `json:"lsn"`
+	AppliedAt          *time.Time `json:"applied_at,omitempty"`
+	RebuildSignalID    string     `json:"rebuild_signal_id,omitempty"`
+	RebuildRequestedAt *time.Time `json:"rebuild_requested_at,omitempty"`
+}
+
+// Projector applies change events to the projections reading their table.
+type Projector struct {
+	db          *pgxpool.Pool
+	builder     squirrel.StatementBuilderType
+	projections map[string]Projection
+	byTable     map[string][]Projection
+}
+
+func NewProjector(db *pgxpool.Pool, projections ...Projection) *Projector {
+	p := &Projector{
+		db:          db,
+		builder:     squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		projections: make(map[string]Projection),
+		byTable:     make(map[string][]Projection),
+	}
+	for _, proj := range projections {
+		p.projections[proj.Name()] = proj
+		for table := range proj.Sources() {
+			p.byTable[table] = append(p.byTable[table], proj)
+		}
+	}
+	return p
+}
+
+// Project applies a change event to every projection reading its table.
+// Truncates are not projected; rebuild the projection after one.
+func (p *Projector) Project(ctx context.Context, ev cdc.ChangeEvent) error {
+	row, deleted := ev.After, false
+	switch ev.Op {
+	case cdc.OpCreate, cdc.OpUpdate, cdc.OpRead:
+	case cdc.OpDelete:
+		row, deleted = ev.Before, true
+	default:
+		return nil
+	}
+
+	table := ev.Table()
+	for _, proj := range p.byTable[table] {
+		key, err := rowKey(row, proj.Sources()[table])
+		if err != nil {
+			return fmt.Errorf("Project: %s: %s: %w", proj.Name(), table, err)
+		}
+		if err := p.apply(ctx, proj, table, key, row, deleted, ev); err != nil {
+			return fmt.Errorf("Project: %s: %w", proj.Name(), err)
+		}
+	}
+	return nil
+}
+
+// rowKey returns the key column values as a JSON array.
+func rowKey(row map[string]any, columns []string) (string, error) {
+	values := make([]any, 0, len(columns))
+	for _, c := range columns {
+		v, ok := row[c]
+		if !ok || v == nil {
+			return "", fmt.Errorf("%w: %s", ErrMissingKey, c)
+		}
+		values = append(values, v)
+	}
+	k, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(k), nil
+}
+
+// apply stores the row and lets the projection update its read tables, in
+// one transaction that holds the projection's advisory lock: a projection
+// reads rows other changes write, so its changes are applied one at a time.
+func (p *Projector) apply(
+	ctx context.Context,
+	proj Projection,
+	table, key string,
+	row map[string]any,
+	deleted bool,
+	ev cdc.ChangeEvent,
+) error {
+	lsn := ev.Source.LSN
+
+	return p.inTx(ctx, proj.Name(), func(tx *Tx) error {
+		query, args, err := p.builder.Select("data", "lsn", "deleted").
+			From(rowsTable).
+			Where(squirrel.Eq{"projection": proj.Name(), "source_table": table, "row_key": key}).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("select: %w", err)
+		}
+
+		var (
+			before      map[string]any
+			storedLSN   int64
+			wasDeleted  bool
+			alreadySeen = true
+		)
+		err = tx.QueryRow(ctx, query, args...).Scan(&before, &storedLSN, &wasDeleted)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			alreadySeen = false
+		case err != nil:
+			return fmt.Errorf("select: %w", err)
+		}
+		// Events without an LSN are always applied.
+		if alreadySeen && lsn != 0 && storedLSN >= lsn {
+			return nil
+		}
+		if wasDeleted {
+			before = nil
+		}
+
+		data, err := json.Marshal(row)
+		if err != nil {
+			return fmt.Errorf("insert: %w", err)
+		}
+		query, args, err = p.builder.Insert(rowsTable).
+			Columns("projection", "source_table", "row_key", "data", "lsn", "deleted").
+			Values(proj.Name(), table, key, data, lsn, deleted).
+			Suffix("ON CONFLICT (projection, source_table, row_key) DO UPDATE SET " +
+				"data = EXCLUDED.data, lsn = EXCLUDED.lsn, deleted = EXCLUDED.deleted, " +
+				"updated_at = CURRENT_TIMESTAMP").
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("insert: %w", err)
+		}
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("insert: %w", err)
+		}
+
+		change := Change{Table: table, Op: ev.Op, Before: before, LSN: lsn}
+		if !deleted {
+			change.After = row
+		}
+		if err := proj.Apply(ctx, tx, change); err != nil {
+			return err
+		}
+
+		query, args, err = p.builder.Insert(statusTable).
+			Columns("name", "lsn", "applied_at").
+			Values(proj.Name(), lsn, squirrel.Expr("CURRENT_TIMESTAMP")).
+			Suffix("ON CONFLICT (name) DO UPDATE SET " +
+				"lsn = GREATEST(" + statusTable + ".lsn, EXCLUDED.lsn), applied_at = EXCLUDED.applied_at").
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+		return nil
+	})
+}
+
+func (p *Projector) inTx(ctx context.Context, name string, fn func(tx *Tx) error) error {
+	pgTx, err := p.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer pgTx.Rollback(ctx) //nolint:errcheck
+
+	if _, err := pgTx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "projection:"+name); err != nil {
+		return fmt.Errorf("lock: %w", err)
+	}
+
+	if err := fn(&Tx{Tx: pgTx, projection: name, builder: p.builder}); err != nil {
+		return err
+	}
+
+	if err := pgTx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// Sources returns the source tables of a projection, sorted.
+func (p *Projector) Sources(name string) ([]string, error) {
+	proj, ok := p.projections[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProjection, name)
+	}
+	tables := make([]string, 0, len(proj.Sources()))
+	for table := range proj.Sources() {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
+
+// Now returns the database time the stored rows are stamped with.
+func (p *Projector) Now(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	if err := p.db.QueryRow(ctx, "SELECT CURRENT_TIMESTAMP").Scan(&now); err != nil {
+		return time.Time{}, fmt.Errorf("Now: %w", err)
+	}
+	return now, nil
+}
+
+// Reset forgets the source rows of a projection stored before a rebuild
+// started and removes the read rows written before it. Rows written since,
+// e.g. by the incremental snapshot of the source tables that rebuilds the
+// projection and whose signal ID is recorded, are kept, so the snapshot
+// can be started first and the projection is never left empty.
+func (p *Projector) Reset(ctx context.Context, name string, before time.Time) error {
+	proj, ok := p.projections[name]
+	if !ok {
+		return fmt.Errorf("Reset: %w: %s", ErrUnknownProjection, name)
+	}
+
+	err := p.inTx(ctx, name, func(tx *Tx) error {
+		query, args, err := p.builder.Delete(rowsTable).
+			Where(squirrel.Eq{"projection": name}).
+			Where(squirrel.Lt{"updated_at": before}).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+
+		if err := proj.Reset(ctx, tx, before); err != nil {
+			return err
+		}
+
+		query, args, err = p.builder.Insert(statusTable).
+			Columns("name", "lsn", "applied_at", "rebuild_signal_id", "rebuild_requested_at").
+			Values(name, 0, nil, nil, nil).
+			Suffix("ON CONFLICT (name) DO UPDATE SET "+
+				"lsn = CASE WHEN "+statusTable+".applied_at >= ? THEN "+statusTable+".lsn ELSE 0 END, "+
+				"applied_at = CASE WHEN "+statusTable+".applied_at >= ? THEN "+statusTable+".applied_at END, "+
+				"rebuild_signal_id = NULL, rebuild_requested_at = NULL", before, before).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Reset: %w", err)
+	}
+	return nil
+}
+
+// RecordRebuild stores the signal of the snapshot that rebuilds a
+// projection.
+func (p *Projector) RecordRebuild(ctx context.Context, name, signalID string) error {
+	query, args, err := p.builder.Update(statusTable).
+		Set("rebuild_signal_id", signalID).
+		Set("rebuild_requested_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"name": name}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("RecordRebuild: %w", err)
+	}
+	if _, err := p.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("RecordRebuild: %w", err)
+	}
+	return nil
+}
+
+// Statuses returns the status of every projection, sorted by name.
+// Projections that never applied a change have a zero LSN.
+func (p *Projector) Statuses(ctx context.Context) ([]Status, error) {
+	query, args, err := p.builder.Select(
+		"name", "lsn", "applied_at", "COALESCE(rebuild_signal_id, '')", "rebuild_requested_at",
+	).
+		From(statusTable).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("Statuses: %w", err)
+	}
+
+	rows, err := p.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Statuses: %w", err)
+	}
+	defer rows.Close()
+
+	stored := make(map[string]Status)
+	for rows.Next() {
+		var s Status
+		if err := rows.Scan(&s.Name, &s.LSN, &s.AppliedAt, &s.RebuildSignalID, &s.RebuildRequestedAt); err != nil {
+			return nil, fmt.Errorf("Statuses: %w", err)
+		}
+		stored[s.Name] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Statuses: %w", err)
+	}
+
+	statuses := make([]Status, 0, len(p.projections))
+	for name := range p.projections {
+		s, ok := stored[name]
+		if !ok {
+			s = Status{Name: name}
+		}
+		s.Sources, _ = p.Sources(name)
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
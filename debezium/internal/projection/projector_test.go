@@ -0,0 +1,172 @@
+package projection
+
+import (
+	"context"
+	"debezium_server/internal/pgtest"
+	"debezium_server/pkg/cdc"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// event builds a change event of an inventory table with numbers decoded
+// the way cdc.DecodeJSON decodes them.
+func event(table string, op cdc.Op, lsn int64, before, after map[string]any) cdc.ChangeEvent {
+	return cdc.ChangeEvent{
+		Op:     op,
+		Before: before,
+		After:  after,
+		Source: cdc.Source{Schema: "inventory", Table: table, LSN: lsn},
+	}
+}
+
+func order(id float64, status string) map[string]any {
+	return map[string]any{"id": id, "customer_id": nil, "status": status, "total_amount": 10.0}
+}
+
+func item(id, orderID, quantity, price float64) map[string]any {
+	return map[string]any{"id": id, "order_id": orderID, "quantity": quantity, "price": price}
+}
+
+type summary struct {
+	status     string
+	itemCount  int
+	itemsTotal float64
+}
+
+// summaryOf returns the order summary, or false if there is none.
+func summaryOf(t *testing.T, db *pgxpool.Pool, orderID int64) (summary, bool) {
+	t.Helper()
+	var s summary
+	err := db.QueryRow(context.Background(),
+		"SELECT status, item_count, items_total FROM order_summaries WHERE order_id = $1", orderID,
+	).Scan(&s.status, &s.itemCount, &s.itemsTotal)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return summary{}, false
+	}
+	if err != nil {
+		t.Fatalf("select summary: %v", err)
+	}
+	return s, true
+}
+
+func project(t *testing.T, p *Projector, events ...cdc.ChangeEvent) {
+	t.Helper()
+	for _, ev := range events {
+		if err := p.Project(context.Background(), ev); err != nil {
+			t.Fatalf("Project: %v", err)
+		}
+	}
+}
+
+func TestProjectSkipsOlderEvents(t *testing.T) {
+	db := pgtest.Pool(t)
+	p := NewProjector(db, NewOrderSummaries())
+
+	project(t, p,
+		event("orders", cdc.OpCreate, 100, nil, order(1, "pending")),
+		event("orders", cdc.OpUpdate, 200, order(1, "pending"), order(1, "shipped")),
+		// Redelivered after a crash, and replayed from the dead letters.
+		event("orders", cdc.OpUpdate, 200, order(1, "pending"), order(1, "cancelled")),
+		event("orders", cdc.OpUpdate, 150, order(1, "pending"), order(1, "paid")),
+	)
+
+	s, ok := summaryOf(t, db, 1)
+	if !ok || s.status != "shipped" {
+		t.Errorf("got summary %+v (found %t), want the shipped order", s, ok)
+	}
+}
+
+func TestProjectDeleteMarkerBlocksResurrection(t *testing.T) {
+	db := pgtest.Pool(t)
+	p := NewProjector(db, NewOrderSummaries())
+
+	project(t, p,
+		event("orders", cdc.OpCreate, 100, nil, order(1, "pending")),
+		event("orders", cdc.OpDelete, 200, order(1, "pending"), nil),
+	)
+	if _, ok := summaryOf(t, db, 1); ok {
+		t.Fatal("the deleted order has a summary")
+	}
+
+	// The create, delivered again, must not bring the order back.
+	project(t, p, event("orders", cdc.OpCreate, 100, nil, order(1, "pending")))
+	if _, ok := summaryOf(t, db, 1); ok {
+		t.Error("a redelivered create resurrected the deleted order")
+	}
+
+	// A newer create of the same key does.
+	project(t, p, event("orders", cdc.OpCreate, 300, nil, order(1, "new")))
+	if s, ok := summaryOf(t, db, 1); !ok || s.status != "new" {
+		t.Errorf("got summary %+v (found %t), want the recreated order", s, ok)
+	}
+}
+
+func TestProjectItemMovedBetweenOrders(t *testing.T) {
+	db := pgtest.Pool(t)
+	p := NewProjector(db, NewOrderSummaries())
+
+	project(t, p,
+		event("orders", cdc.OpCreate, 100, nil, order(1, "pending")),
+		event("orders", cdc.OpCreate, 110, nil, order(2, "pending")),
+		event("order_items", cdc.OpCreate, 120, nil, item(10, 1, 2, 5)),
+		event("order_items", cdc.OpCreate, 130, nil, item(11, 2, 1, 3)),
+		event("order_items", cdc.OpUpdate, 140, item(10, 1, 2, 5), item(10, 2, 2, 5)),
+	)
+
+	tests := []struct {
+		orderID    int64
+		itemCount  int
+		itemsTotal float64
+	}{
+		{orderID: 1, itemCount: 0, itemsTotal: 0},
+		{orderID: 2, itemCount: 2, itemsTotal: 13},
+	}
+	for _, tt := range tests {
+		s, ok := summaryOf(t, db, tt.orderID)
+		if !ok || s.itemCount != tt.itemCount || s.itemsTotal != tt.itemsTotal {
+			t.Errorf("order %d has summary %+v (found %t), want %d items totalling %v",
+				tt.orderID, s, ok, tt.itemCount, tt.itemsTotal)
+		}
+	}
+}
+
+func TestResetKeepsRowsWrittenSince(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.Pool(t)
+	p := NewProjector(db, NewOrderSummaries())
+
+	project(t, p, event("orders", cdc.OpCreate, 100, nil, order(1, "pending")))
+	started, err := p.Now(ctx)
+	if err != nil {
+		t.Fatalf("Now: %v", err)
+	}
+	// Read by the snapshot before the reset ran.
+	project(t, p, event("orders", cdc.OpRead, 200, nil, order(2, "pending")))
+
+	if err := p.Reset(ctx, OrderSummariesName, started); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if _, ok := summaryOf(t, db, 1); ok {
+		t.Error("the summary written before the rebuild was kept")
+	}
+	if _, ok := summaryOf(t, db, 2); !ok {
+		t.Error("the summary written by the snapshot was removed")
+	}
+
+	// Its stored row was kept too, so a redelivered older event is skipped.
+	project(t, p, event("orders", cdc.OpUpdate, 150, order(2, "pending"), order(2, "paid")))
+	if s, _ := summaryOf(t, db, 2); s.status != "pending" {
+		t.Errorf("got status %q after an older event, want pending", s.status)
+	}
+
+	statuses, err := p.Statuses(ctx)
+	if err != nil {
+		t.Fatalf("Statuses: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].LSN != 200 {
+		t.Errorf("got statuses %+v, want order_summaries at LSN 200", statuses)
+	}
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	auditTable = "audit_log"
+)
+
+type AuditRepository struct {
+	db      *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *AuditRepository) Insert(ctx context.Context, entry models.AuditEntry) error {
+	if entry.Diff == nil {
+		entry.Diff = map[string]models.AuditChange{}
+	}
+
+	diff, err := json.Marshal(entry.Diff)
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	query, args, err := r.builder.Insert(auditTable).
+		Columns(
+			"principal", "auth_method", "request_id", "action", "cluster", "connector", "task_id", "diff", "result", "error",
+		).
+		Values(
+			entry.Principal,
+			entry.AuthMethod,
+			entry.RequestID,
+			entry.Action,
+			entry.Cluster,
+			entry.Connector,
+			entry.TaskID,
+			diff,
+			entry.Result,
+			entry.Error,
+		).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the entries matching the filter, newest first.
+func (r *AuditRepository) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	q := r.builder.Select(
+		"id", "principal", "auth_method", "request_id", "action", "cluster", "connector", "task_id", "diff", "result",
+		"error", "created_at").
+		From(auditTable).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(uint64(filter.Limit)).
+		Offset(uint64(filter.Offset))
+
+	if filter.Cluster != "" {
+		q = q.Where(squirrel.Eq{"cluster": filter.Cluster})
+	}
+	if filter.Connector != "" {
+		q = q.Where(squirrel.Eq{"connector": filter.Connector})
+	}
+	if filter.Principal != "" {
+		q = q.Where(squirrel.Eq{"principal": filter.Principal})
+	}
+	if filter.Action != "" {
+		q = q.Where(squirrel.Eq{"action": filter.Action})
+	}
+	if filter.Key != "" {
+		// ?? is rendered as the jsonb ? operator, which the GIN index serves.
+		q = q.Where(squirrel.Expr("diff ?? ?", filter.Key))
+	}
+	if !filter.From.IsZero() {
+		q = q.Where(squirrel.GtOrEq{"created_at": filter.From})
+	}
+	if !filter.To.IsZero() {
+		q = q.Where(squirrel.Lt{"created_at": filter.To})
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var (
+			entry models.AuditEntry
+			diff  []byte
+		)
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Principal,
+			&entry.AuthMethod,
+			&entry.RequestID,
+			&entry.Action,
+			&entry.Cluster,
+			&entry.Connector,
+			&entry.TaskID,
+			&diff,
+			&entry.Result,
+			&entry.Error,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+		if err := json.Unmarshal(diff, &entry.Diff); err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	return entries, nil
+}
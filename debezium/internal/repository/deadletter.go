@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const deadLetterTable = "cdc_dead_letters"
+
+type DeadLetterRepository struct {
+	db      *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+}
+
+func NewDeadLetterRepository(db *pgxpool.Pool) *DeadLetterRepository {
+	return &DeadLetterRepository{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// Insert stores a dead letter. If the message is already stored, its error
+// is replaced and the attempts are added up.
+func (r *DeadLetterRepository) Insert(ctx context.Context, dl models.DeadLetter) error {
+	headers, err := json.Marshal(dl.Headers)
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+	if dl.Headers == nil {
+		headers = []byte("[]")
+	}
+
+	query, args, err := r.builder.Insert(deadLetterTable).
+		Columns(
+			"topic", "kafka_partition", "kafka_offset", "message_key", "message_value",
+			"headers", "message_time", "error", "attempts",
+		).
+		Values(dl.Topic, dl.Partition, dl.Offset, dl.Key, dl.Value, headers, dl.MessageTime, dl.Error, dl.Attempts).
+		Suffix("ON CONFLICT (topic, kafka_partition, kafka_offset) DO UPDATE SET " +
+			"error = EXCLUDED.error, " +
+			"attempts = " + deadLetterTable + ".attempts + EXCLUDED.attempts, " +
+			"updated_at = CURRENT_TIMESTAMP").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	return nil
+}
+
+// List returns dead letters without their keys and values, oldest first.
+func (r *DeadLetterRepository) List(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetter, error) {
+	q := r.builder.Select(
+		"id", "topic", "kafka_partition", "kafka_offset", "headers", "message_time",
+		"error", "attempts", "created_at", "updated_at",
+	).
+		From(deadLetterTable).
+		OrderBy("id").
+		Limit(uint64(filter.Limit)).
+		Offset(uint64(filter.Offset))
+	if filter.Topic != "" {
+		q = q.Where(squirrel.Eq{"topic": filter.Topic})
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer rows.Close()
+
+	letters := make([]models.DeadLetter, 0)
+	for rows.Next() {
+		var (
+			dl      models.DeadLetter
+			headers []byte
+		)
+		if err := rows.Scan(
+			&dl.ID,
+			&dl.Topic,
+			&dl.Partition,
+			&dl.Offset,
+			&headers,
+			&dl.MessageTime,
+			&dl.Error,
+			&dl.Attempts,
+			&dl.CreatedAt,
+			&dl.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+		if err := json.Unmarshal(headers, &dl.Headers); err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+		letters = append(letters, dl)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	return letters, nil
+}
+
+// Get returns one dead letter with its key and value, or
+// models.ErrDeadLetterNotFound.
+func (r *DeadLetterRepository) Get(ctx context.Context, id int64) (models.DeadLetter, error) {
+	query, args, err := r.builder.Select(
+		"id", "topic", "kafka_partition", "kafka_offset", "message_key", "message_value", "headers",
+		"message_time", "error", "attempts", "created_at", "updated_at",
+	).
+		From(deadLetterTable).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return models.DeadLetter{}, fmt.Errorf("get: %w", err)
+	}
+
+	var (
+		dl      models.DeadLetter
+		headers []byte
+	)
+	err = r.db.QueryRow(ctx, query, args...).Scan(
+		&dl.ID,
+		&dl.Topic,
+		&dl.Partition,
+		&dl.Offset,
+		&dl.Key,
+		&dl.Value,
+		&headers,
+		&dl.MessageTime,
+		&dl.Error,
+		&dl.Attempts,
+		&dl.CreatedAt,
+		&dl.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.DeadLetter{}, fmt.Errorf("get: %w", models.ErrDeadLetterNotFound)
+	}
+	if err != nil {
+		return models.DeadLetter{}, fmt.Errorf("get: %w", err)
+	}
+	if err := json.Unmarshal(headers, &dl.Headers); err != nil {
+		return models.DeadLetter{}, fmt.Errorf("get: %w", err)
+	}
+
+	return dl, nil
+}
+
+// RecordFailure stores the error of a failed replay and counts the attempt.
+func (r *DeadLetterRepository) RecordFailure(ctx context.Context, id int64, errMsg string) error {
+	query, args, err := r.builder.Update(deadLetterTable).
+		Set("error", errMsg).
+		Set("attempts", squirrel.Expr("attempts + 1")).
+		Set("updated_at", squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update: %w", models.ErrDeadLetterNotFound)
+	}
+
+	return nil
+}
+
+// Delete removes a dead letter, or returns models.ErrDeadLetterNotFound.
+func (r *DeadLetterRepository) Delete(ctx context.Context, id int64) error {
+	query, args, err := r.builder.Delete(deadLetterTable).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete: %w", models.ErrDeadLetterNotFound)
+	}
+
+	return nil
+}
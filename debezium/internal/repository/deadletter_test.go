@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"debezium_server/internal/pgtest"
+	"testing"
+	"time"
+)
+
+func TestDeadLetterInsertAddsUpAttempts(t *testing.T) {
+	ctx := context.Background()
+	repo := NewDeadLetterRepository(pgtest.Pool(t))
+
+	dl := models.DeadLetter{
+		Topic:       "orders",
+		Partition:   1,
+		Offset:      42,
+		Key:         []byte(`{"id":1}`),
+		Value:       []byte(`{"op":"u"}`),
+		MessageTime: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		Error:       "first failure",
+		Attempts:    3,
+	}
+	if err := repo.Insert(ctx, dl); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	// The same message, delivered again after a crash, fails again.
+	dl.Error = "second failure"
+	if err := repo.Insert(ctx, dl); err != nil {
+		t.Fatalf("Insert again: %v", err)
+	}
+	// Another offset is another dead letter.
+	dl.Offset = 43
+	if err := repo.Insert(ctx, dl); err != nil {
+		t.Fatalf("Insert other offset: %v", err)
+	}
+
+	letters, err := repo.List(ctx, models.DeadLetterFilter{Topic: "orders", Limit: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("got %d dead letters, want 2", len(letters))
+	}
+	first := letters[0]
+	if first.Offset != 42 || first.Attempts != 6 || first.Error != "second failure" {
+		t.Errorf("got offset %d with %d attempts and error %q, want 42, 6 and the second failure",
+			first.Offset, first.Attempts, first.Error)
+	}
+
+	if err := repo.RecordFailure(ctx, first.ID, "replay failed"); err != nil {
+		t.Fatalf("RecordFailure: %v", err)
+	}
+	got, err := repo.Get(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Attempts != 7 || got.Error != "replay failed" || string(got.Value) != `{"op":"u"}` {
+		t.Errorf("got %d attempts, error %q and value %q after the failed replay", got.Attempts, got.Error, got.Value)
+	}
+}
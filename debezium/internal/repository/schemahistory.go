@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const schemaHistoryTable = "cdc_schema_history"
+
+var schemaHistoryColumns = []string{
+	"id", "table_name", "source", "COALESCE(ddl, '')", "columns", "changes", "breaking",
+	"topic", "kafka_partition", "kafka_offset", "message_time", "created_at",
+}
+
+type SchemaHistoryRepository struct {
+	db      *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+}
+
+func NewSchemaHistoryRepository(db *pgxpool.Pool) *SchemaHistoryRepository {
+	return &SchemaHistoryRepository{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// Insert stores a schema version. A version already stored for the same
+// message is kept.
+func (r *SchemaHistoryRepository) Insert(ctx context.Context, v models.TableSchemaVersion) error {
+	columns, err := json.Marshal(v.Columns)
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+	changes, err := json.Marshal(v.Changes)
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	var ddl *string
+	if v.DDL != "" {
+		ddl = &v.DDL
+	}
+
+	query, args, err := r.builder.Insert(schemaHistoryTable).
+		Columns(
+			"table_name", "source", "ddl", "columns", "changes", "breaking",
+			"topic", "kafka_partition", "kafka_offset", "message_time",
+		).
+		Values(v.Table, v.Source, ddl, columns, changes, v.Breaking, v.Topic, v.Partition, v.Offset, v.MessageTime).
+		Suffix("ON CONFLICT (topic, kafka_partition, kafka_offset, table_name) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the versions of a table, newest first.
+func (r *SchemaHistoryRepository) List(ctx context.Context, filter models.SchemaHistoryFilter) ([]models.TableSchemaVersion, error) {
+	q := r.builder.Select(schemaHistoryColumns...).
+		From(schemaHistoryTable).
+		Where(squirrel.Eq{"table_name": filter.Table}).
+		OrderBy("id DESC").
+		Limit(uint64(filter.Limit)).
+		Offset(uint64(filter.Offset))
+	if filter.BreakingOnly {
+		q = q.Where(squirrel.Eq{"breaking": true})
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make([]models.TableSchemaVersion, 0)
+	for rows.Next() {
+		v, err := scanSchemaVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+		versions = append(versions, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	return versions, nil
+}
+
+// Latest returns the newest version of a table from source, or
+// models.ErrTableSchemaNotFound.
+func (r *SchemaHistoryRepository) Latest(ctx context.Context, table, source string) (models.TableSchemaVersion, error) {
+	query, args, err := r.builder.Select(schemaHistoryColumns...).
+		From(schemaHistoryTable).
+		Where(squirrel.Eq{"table_name": table, "source": source}).
+		OrderBy("id DESC").
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return models.TableSchemaVersion{}, fmt.Errorf("get: %w", err)
+	}
+
+	v, err := scanSchemaVersion(r.db.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.TableSchemaVersion{}, fmt.Errorf("get: %w", models.ErrTableSchemaNotFound)
+	}
+	if err != nil {
+		return models.TableSchemaVersion{}, fmt.Errorf("get: %w", err)
+	}
+
+	return v, nil
+}
+
+func scanSchemaVersion(row pgx.Row) (models.TableSchemaVersion, error) {
+	var (
+		v       models.TableSchemaVersion
+		columns []byte
+		changes []byte
+	)
+	if err := row.Scan(
+		&v.ID,
+		&v.Table,
+		&v.Source,
+		&v.DDL,
+		&columns,
+		&changes,
+		&v.Breaking,
+		&v.Topic,
+		&v.Partition,
+		&v.Offset,
+		&v.MessageTime,
+		&v.CreatedAt,
+	); err != nil {
+		return models.TableSchemaVersion{}, err
+	}
+	if err := json.Unmarshal(columns, &v.Columns); err != nil {
+		return models.TableSchemaVersion{}, err
+	}
+	if err := json.Unmarshal(changes, &v.Changes); err != nil {
+		return models.TableSchemaVersion{}, err
+	}
+	return v, nil
+}
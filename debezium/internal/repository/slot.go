@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SlotRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSlotRepository(db *pgxpool.Pool) *SlotRepository {
+	return &SlotRepository{db: db}
+}
+
+// List returns the logical replication slots of the database. A slot that
+// has never confirmed a position reports no lag.
+func (r *SlotRepository) List(ctx context.Context) ([]models.ReplicationSlot, error) {
+	const query = `
+		SELECT slot_name,
+		       COALESCE(plugin, ''),
+		       COALESCE(database, ''),
+		       active,
+		       COALESCE(confirmed_flush_lsn::text, ''),
+		       COALESCE(pg_wal_lsn_diff(pg_current_wal_lsn(), confirmed_flush_lsn), 0)::bigint
+		FROM pg_replication_slots
+		WHERE slot_type = 'logical'
+		ORDER BY slot_name`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer rows.Close()
+
+	slots := make([]models.ReplicationSlot, 0)
+	for rows.Next() {
+		var s models.ReplicationSlot
+		if err := rows.Scan(&s.Name, &s.Plugin, &s.Database, &s.Active, &s.ConfirmedFlushLSN, &s.LagBytes); err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+		slots = append(slots, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	return slots, nil
+}
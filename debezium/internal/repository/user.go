@@ -3,21 +3,35 @@ package repository
 import (
 	"context"
 	"debezium_server/internal/models"
+	"debezium_server/pkg/outbox"
+	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const (
+	userAggregateType = "user"
+	userCreatedEvent  = "UserCreated"
+
+	uniqueViolationCode = "23505"
+)
+
 type UserRepository struct {
 	db      *pgxpool.Pool
 	builder squirrel.StatementBuilderType
+	outbox  *outbox.Store
 }
 
 func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 	return &UserRepository{
 		db:      db,
 		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		outbox:  outbox.NewStore(outbox.DefaultTable),
 	}
 }
 
@@ -55,3 +69,63 @@ func (r *UserRepository) Select(ctx context.Context, offset, limit int) ([]model
 
 	return users, nil
 }
+
+// Create inserts the user and records a UserCreated outbox event in the same transaction.
+func (r *UserRepository) Create(ctx context.Context, user models.User) (models.User, error) {
+	if user.Role == nil {
+		user.Role = []string{}
+	}
+
+	query, args, err := r.builder.Insert("users").
+		Columns("email", "name", "last_name", "role").
+		Values(user.Email, user.Name, user.LastName, user.Role).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return models.User{}, fmt.Errorf("create: %w", err)
+	}
+
+	err = r.outbox.InTx(ctx, r.db, func(ctx context.Context, tx pgx.Tx) ([]outbox.Event, error) {
+		if err := tx.QueryRow(ctx, query, args...).Scan(&user.ID); err != nil {
+			return nil, err
+		}
+
+		event, err := outbox.NewEvent(userAggregateType, strconv.FormatInt(user.ID, 10), userCreatedEvent, user)
+		if err != nil {
+			return nil, err
+		}
+
+		return []outbox.Event{event}, nil
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return models.User{}, fmt.Errorf("create: %w", models.ErrUserAlreadyExists)
+		}
+		return models.User{}, fmt.Errorf("create: %w", err)
+	}
+
+	return user, nil
+}
+
+// RolesByEmail returns the roles of the user with the given email, or no
+// roles if there is no such user.
+func (r *UserRepository) RolesByEmail(ctx context.Context, email string) ([]string, error) {
+	query, args, err := r.builder.Select("role").
+		From("users").
+		Where(squirrel.Eq{"email": email}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("roles: %w", err)
+	}
+
+	var roles []string
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&roles); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("roles: %w", err)
+	}
+
+	return roles, nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	versionTable = "connector_versions"
+
+	// versionInsertAttempts bounds the retries when concurrent inserts
+	// pick the same version number.
+	versionInsertAttempts = 3
+)
+
+type VersionRepository struct {
+	db      *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+}
+
+func NewVersionRepository(db *pgxpool.Pool) *VersionRepository {
+	return &VersionRepository{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// Insert stores v as the next version of its connector and returns it with
+// the version number, ID and creation time set.
+func (r *VersionRepository) Insert(ctx context.Context, v models.ConnectorVersion) (models.ConnectorVersion, error) {
+	config, err := json.Marshal(v.Config)
+	if err != nil {
+		return models.ConnectorVersion{}, fmt.Errorf("insert: %w", err)
+	}
+
+	next := squirrel.Expr(
+		"(SELECT COALESCE(MAX(version), 0) + 1 FROM "+versionTable+" WHERE cluster = ? AND connector = ?)",
+		v.Cluster, v.Connector,
+	)
+	query, args, err := r.builder.Insert(versionTable).
+		Columns("cluster", "connector", "version", "config", "author", "comment", "request_id").
+		Values(v.Cluster, v.Connector, next, config, v.Author, v.Comment, v.RequestID).
+		Suffix("RETURNING id, version, created_at").
+		ToSql()
+	if err != nil {
+		return models.ConnectorVersion{}, fmt.Errorf("insert: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = r.db.QueryRow(ctx, query, args...).Scan(&v.ID, &v.Version, &v.CreatedAt)
+		var pgErr *pgconn.PgError
+		if attempt < versionInsertAttempts && errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			continue
+		}
+		break
+	}
+	if err != nil {
+		return models.ConnectorVersion{}, fmt.Errorf("insert: %w", err)
+	}
+
+	return v, nil
+}
+
+// List returns the versions of a connector, newest first.
+func (r *VersionRepository) List(
+	ctx context.Context,
+	filter models.ConnectorVersionFilter,
+) ([]models.ConnectorVersion, error) {
+	query, args, err := r.selectVersions().
+		Where(squirrel.Eq{"cluster": filter.Cluster, "connector": filter.Connector}).
+		OrderBy("version DESC").
+		Limit(uint64(filter.Limit)).
+		Offset(uint64(filter.Offset)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make([]models.ConnectorVersion, 0)
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+		versions = append(versions, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	return versions, nil
+}
+
+// Get returns one version, or models.ErrVersionNotFound.
+func (r *VersionRepository) Get(
+	ctx context.Context,
+	cluster, connector string,
+	version int,
+) (models.ConnectorVersion, error) {
+	query, args, err := r.selectVersions().
+		Where(squirrel.Eq{"cluster": cluster, "connector": connector, "version": version}).
+		ToSql()
+	if err != nil {
+		return models.ConnectorVersion{}, fmt.Errorf("get: %w", err)
+	}
+
+	v, err := scanVersion(r.db.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ConnectorVersion{}, fmt.Errorf("get: %w", models.ErrVersionNotFound)
+	}
+	if err != nil {
+		return models.ConnectorVersion{}, fmt.Errorf("get: %w", err)
+	}
+
+	return v, nil
+}
+
+func (r *VersionRepository) selectVersions() squirrel.SelectBuilder {
+	return r.builder.Select(
+		"id", "cluster", "connector", "version", "config", "author", "comment", "request_id", "created_at").
+		From(versionTable)
+}
+
+func scanVersion(row pgx.Row) (models.ConnectorVersion, error) {
+	var (
+		v      models.ConnectorVersion
+		config []byte
+	)
+	if err := row.Scan(
+		&v.ID,
+		&v.Cluster,
+		&v.Connector,
+		&v.Version,
+		&config,
+		&v.Author,
+		&v.Comment,
+		&v.RequestID,
+		&v.CreatedAt,
+	); err != nil {
+		return models.ConnectorVersion{}, err
+	}
+	if err := json.Unmarshal(config, &v.Config); err != nil {
+		return models.ConnectorVersion{}, err
+	}
+	return v, nil
+}
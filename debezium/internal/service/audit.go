@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"debezium_server/internal/auth"
+	"debezium_server/internal/models"
+	"debezium_server/pkg/logger"
+	"debezium_server/pkg/redact"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+
+	anonymousPrincipal = "anonymous"
+)
+
+type AuditStore interface {
+	Insert(ctx context.Context, entry models.AuditEntry) error
+	List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+type AuditService struct {
+	store AuditStore
+}
+
+func NewAuditService(store AuditStore) *AuditService {
+	return &AuditService{store: store}
+}
+
+func (s *AuditService) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	return s.store.List(ctx, filter)
+}
+
+// auditor records connector mutations. The principal and request ID are
+// taken from the request context.
+type auditor struct {
+	cluster  string
+	store    AuditStore
+	redactor *redact.Redactor
+}
+
+// record writes one entry for a mutation that has already been attempted.
+// opErr is the mutation's result. The returned error joins opErr with any
+// failure to write the entry, so a mutation never goes unaudited silently.
+func (a auditor) record(
+	ctx context.Context,
+	action models.AuditAction,
+	connector string,
+	diff map[string]models.AuditChange,
+	opErr error,
+) error {
+	return a.write(ctx, models.AuditEntry{Action: action, Connector: connector, Diff: diff}, opErr)
+}
+
+// recordTask writes one entry for a mutation of a single connector task.
+func (a auditor) recordTask(
+	ctx context.Context,
+	action models.AuditAction,
+	connector string,
+	taskID int,
+	opErr error,
+) error {
+	return a.write(ctx, models.AuditEntry{Action: action, Connector: connector, TaskID: &taskID}, opErr)
+}
+
+func (a auditor) write(ctx context.Context, entry models.AuditEntry, opErr error) error {
+	entry.Principal, entry.AuthMethod = principalFromContext(ctx)
+	entry.RequestID = logger.RequestIDFromContext(ctx)
+	entry.Cluster = a.cluster
+	entry.Result = models.AuditSuccess
+	if opErr != nil {
+		entry.Result = models.AuditFailure
+		entry.Error = opErr.Error()
+	}
+
+	// The request may have been cancelled after the mutation was sent;
+	// the entry must still be written.
+	if err := a.store.Insert(context.WithoutCancel(ctx), entry); err != nil {
+		return errors.Join(opErr, fmt.Errorf("audit: %w", err))
+	}
+
+	return opErr
+}
+
+// principalFromContext returns the subject and auth method of the caller,
+// or anonymousPrincipal when authentication is disabled.
+func principalFromContext(ctx context.Context) (string, string) {
+	if p, ok := auth.PrincipalFromContext(ctx); ok {
+		return p.Subject, p.Method
+	}
+	return anonymousPrincipal, ""
+}
+
+// configDiff returns the keys whose values differ between before and after,
+// with secret values masked.
+func (a auditor) configDiff(before, after map[string]any) map[string]models.AuditChange {
+	diff := make(map[string]models.AuditChange)
+
+	for k, b := range before {
+		v, ok := after[k]
+		if ok && reflect.DeepEqual(b, v) {
+			continue
+		}
+		change := models.AuditChange{Before: a.mask(k, b)}
+		if ok {
+			change.After = a.mask(k, v)
+		}
+		diff[k] = change
+	}
+	for k, v := range after {
+		if _, ok := before[k]; !ok {
+			diff[k] = models.AuditChange{After: a.mask(k, v)}
+		}
+	}
+
+	return diff
+}
+
+func (a auditor) mask(key string, value any) any {
+	if value != nil && value != "" && a.redactor.IsSecret(key) {
+		return a.redactor.Mask()
+	}
+	return value
+}
+
+func stringsToAny(config map[string]string) map[string]any {
+	if config == nil {
+		return nil
+	}
+
+	out := make(map[string]any, len(config))
+	for k, v := range config {
+		out[k] = v
+	}
+	return out
+}
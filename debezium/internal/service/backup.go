@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"debezium_server/internal/cluster"
+	"debezium_server/internal/models"
+	"debezium_server/pkg/backup"
+	"debezium_server/pkg/configtemplate"
+	"debezium_server/pkg/redact"
+	"fmt"
+	"time"
+)
+
+// BackupService exports the connectors of a cluster to an archive and
+// restores archives into any cluster.
+type BackupService struct {
+	clusters  *cluster.Registry
+	templates *configtemplate.Renderer
+	redactor  *redact.Redactor
+	audit     AuditStore
+	versions  VersionStore
+}
+
+func NewBackupService(
+	clusters *cluster.Registry,
+	templates *configtemplate.Renderer,
+	redactor *redact.Redactor,
+	audit AuditStore,
+	versions VersionStore,
+) *BackupService {
+	return &BackupService{
+		clusters:  clusters,
+		templates: templates,
+		redactor:  redactor,
+		audit:     audit,
+		versions:  versions,
+	}
+}
+
+// Export snapshots the named connectors of the cluster, or all of them.
+// Secret values are masked unless includeSecrets is set.
+func (s *BackupService) Export(
+	ctx context.Context,
+	clusterName string,
+	names []string,
+	includeSecrets bool,
+) (*backup.Archive, error) {
+	c, err := s.clusters.Get(clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := backup.Export(ctx, c.Client, c.Name, names...)
+	if err != nil {
+		return nil, err
+	}
+	if !includeSecrets {
+		for i := range a.Connectors {
+			a.Connectors[i].Config = s.redactor.StringMap(a.Connectors[i].Config)
+		}
+	}
+
+	return a, nil
+}
+
+// Restore recreates the connectors of the archive in the cluster. The
+// overrides are rendered as a config template with vars, so secrets masked
+// in the archive can be supplied from the environment. Each connector is
+// audited and recorded in the version history; one that fails does not
+// stop the others.
+func (s *BackupService) Restore(
+	ctx context.Context,
+	clusterName string,
+	a *backup.Archive,
+	opts backup.RestoreOptions,
+	vars map[string]string,
+) ([]backup.Result, error) {
+	c, err := s.clusters.Get(clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, problems := s.templates.WithVars(vars).RenderConfig(opts.Overrides)
+	if len(problems) > 0 {
+		return nil, &configtemplate.ValidationError{Problems: problems}
+	}
+	opts.Overrides = overrides
+
+	planned, err := a.Plan(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := auditor{cluster: c.Name, store: s.audit, redactor: s.redactor}
+	versions := versioner{cluster: c.Name, store: s.versions, redactor: s.redactor}
+	note := fmt.Sprintf("restore from backup of %s taken at %s", a.Cluster, a.CreatedAt.Format(time.RFC3339))
+
+	results := make([]backup.Result, 0, len(planned))
+	for _, p := range planned {
+		result := backup.Result{Source: p.Source, Name: p.Name}
+
+		err := backup.CheckMasked(p.Config, s.redactor)
+		if err == nil {
+			err = backup.RestoreConnector(ctx, c.Client, p.Connector)
+		}
+		diff := audit.configDiff(nil, stringsToAny(p.Config))
+		for k, v := range offsetsDiff(nil, p.Offsets) {
+			diff[k] = v
+		}
+		if err := audit.record(ctx, models.AuditRestore, p.Name, diff, err); err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		if err := versions.record(ctx, p.Name, stringsToAny(p.Config), note); err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.State = p.State()
+		results = append(results, result)
+	}
+
+	return results, nil
+}
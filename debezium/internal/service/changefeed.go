@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"debezium_server/internal/cluster"
+	"debezium_server/pkg/cdc"
+	"debezium_server/pkg/cdc/kafka"
+	"debezium_server/pkg/logger"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// HeartbeatTopicPrefix is the default heartbeat.topics.prefix; the
+	// connector appends "." and its topic.prefix.
+	HeartbeatTopicPrefix = "__debezium-heartbeat."
+
+	// prefixRefreshInterval is how often the feed reloads the connector
+	// configs to map heartbeat topics to connectors. An unknown topic
+	// prefix reloads them sooner, but not more often than
+	// prefixRetryInterval.
+	prefixRefreshInterval = time.Minute
+	prefixRetryInterval   = 10 * time.Second
+)
+
+// ChangePublisher is the change stream hub.
+type ChangePublisher interface {
+	Publish(event cdc.ChangeEvent) error
+}
+
+// HeartbeatObserver is the alerting engine.
+type HeartbeatObserver interface {
+	ObserveHeartbeat(cluster, connector string, at time.Time)
+}
+
+// SchemaObserver is the schema history.
+type SchemaObserver interface {
+	ObserveSchema(ctx context.Context, ev kafka.Event) error
+}
+
+// ChangeProjector is the projector of the read models.
+type ChangeProjector interface {
+	Project(ctx context.Context, ev cdc.ChangeEvent) error
+}
+
+type connectorRef struct {
+	cluster string
+	name    string
+}
+
+// ChangeFeed handles the messages of the Debezium topics: change events go
+// to the projector and the change stream, heartbeats to the alerting engine
+// and the schemas of change events and schema change events to the schema
+// history. heartbeats, schemas and projector may be nil.
+type ChangeFeed struct {
+	hub        ChangePublisher
+	heartbeats HeartbeatObserver
+	schemas    SchemaObserver
+	projector  ChangeProjector
+	clusters   *cluster.Registry
+	lg         logger.Logger
+
+	mu        sync.Mutex
+	prefixes  map[string][]connectorRef
+	refreshed time.Time
+	// unknown wakes Run when a heartbeat has an unknown topic prefix.
+	unknown chan struct{}
+}
+
+func NewChangeFeed(
+	hub ChangePublisher,
+	heartbeats HeartbeatObserver,
+	schemas SchemaObserver,
+	projector ChangeProjector,
+	clusters *cluster.Registry,
+	lg logger.Logger,
+) *ChangeFeed {
+	return &ChangeFeed{
+		hub:        hub,
+		heartbeats: heartbeats,
+		schemas:    schemas,
+		projector:  projector,
+		clusters:   clusters,
+		lg:         lg,
+		prefixes:   make(map[string][]connectorRef),
+		unknown:    make(chan struct{}, 1),
+	}
+}
+
+// Run keeps the topic prefixes of the connectors up to date until ctx is
+// done. Heartbeats are dropped until the first reload.
+func (f *ChangeFeed) Run(ctx context.Context) {
+	if f.heartbeats == nil {
+		return
+	}
+
+	ticker := time.NewTicker(prefixRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		f.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-f.unknown:
+			f.mu.Lock()
+			wait := prefixRetryInterval - time.Since(f.refreshed)
+			f.mu.Unlock()
+			if wait > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+		}
+	}
+}
+
+var _ kafka.Handler = (*ChangeFeed)(nil)
+
+func (f *ChangeFeed) Handle(ctx context.Context, ev kafka.Event) error {
+	if prefix, ok := strings.CutPrefix(ev.Topic, HeartbeatTopicPrefix); ok {
+		f.heartbeat(prefix, ev)
+		return nil
+	}
+	if ev.Change == nil {
+		return nil
+	}
+
+	// Schema changes are recorded first, so a breaking change is flagged
+	// before its first event reaches the stream.
+	if f.schemas != nil {
+		if err := f.schemas.ObserveSchema(ctx, ev); err != nil {
+			return fmt.Errorf("Handle.ObserveSchema: %w", err)
+		}
+	}
+	if ev.Change.IsSchemaChange() {
+		return nil
+	}
+
+	if f.projector != nil {
+		if err := f.projector.Project(ctx, *ev.Change); err != nil {
+			return fmt.Errorf("Handle.Project: %w", err)
+		}
+	}
+
+	if err := f.hub.Publish(*ev.Change); err != nil {
+		return fmt.Errorf("Handle.Publish: %w", err)
+	}
+	return nil
+}
+
+// heartbeat records a heartbeat for every connector writing to the topic
+// prefix. Heartbeats of unknown connectors are dropped.
+func (f *ChangeFeed) heartbeat(prefix string, ev kafka.Event) {
+	if f.heartbeats == nil {
+		return
+	}
+
+	at := ev.Timestamp
+	if ev.Change != nil && ev.Change.TsMs > 0 {
+		at = time.UnixMilli(ev.Change.TsMs)
+	}
+	for _, ref := range f.connectors(prefix) {
+		f.heartbeats.ObserveHeartbeat(ref.cluster, ref.name, at)
+	}
+}
+
+// connectors returns the connectors with the topic prefix and asks Run to
+// reload the configs when the prefix is unknown.
+func (f *ChangeFeed) connectors(prefix string) []connectorRef {
+	f.mu.Lock()
+	refs, ok := f.prefixes[prefix]
+	f.mu.Unlock()
+
+	if !ok {
+		select {
+		case f.unknown <- struct{}{}:
+		default:
+		}
+	}
+	return refs
+}
+
+// refresh reloads the topic prefixes of the connectors of every cluster.
+// A cluster whose connectors cannot be listed keeps its known prefixes.
+func (f *ChangeFeed) refresh(ctx context.Context) {
+	f.mu.Lock()
+	known := f.prefixes
+	f.mu.Unlock()
+
+	prefixes := make(map[string][]connectorRef)
+	for _, c := range f.clusters.All() {
+		list, err := c.Client.ListConnectors(ctx, false)
+		if err != nil {
+			if ctx.Err() == nil {
+				f.lg.Warn(ctx, "cannot list connectors for heartbeats", zap.String("cluster", c.Name), zap.Error(err))
+			}
+			for p, refs := range known {
+				for _, ref := range refs {
+					if ref.cluster == c.Name {
+						prefixes[p] = append(prefixes[p], ref)
+					}
+				}
+			}
+			continue
+		}
+		for _, name := range list.Names {
+			conn, err := c.Client.GetConnector(ctx, name)
+			if err != nil {
+				f.lg.Warn(ctx, "cannot read connector for heartbeats",
+					zap.String("cluster", c.Name), zap.String("connector", name), zap.Error(err))
+				continue
+			}
+			if p := topicPrefix(conn.Config); p != "" {
+				prefixes[p] = append(prefixes[p], connectorRef{cluster: c.Name, name: name})
+			}
+		}
+	}
+
+	f.mu.Lock()
+	f.prefixes = prefixes
+	f.refreshed = time.Now()
+	f.mu.Unlock()
+}
+
+// topicPrefix returns topic.prefix, or database.server.name of Debezium
+// 1.x connectors.
+func topicPrefix(config map[string]interface{}) string {
+	for _, key := range []string{"topic.prefix", "database.server.name"} {
+		if p, ok := config[key].(string); ok && p != "" {
+			return p
+		}
+	}
+	return ""
+}
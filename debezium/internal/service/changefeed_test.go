@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"debezium_server/internal/cluster"
+	"debezium_server/pkg/cdc/kafka"
+	"debezium_server/pkg/logger"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConnect serves connectors by name with their topic.prefix.
+type fakeConnect struct {
+	mu       sync.Mutex
+	prefixes map[string]string
+	down     bool
+}
+
+func (c *fakeConnect) set(name, prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prefixes[name] = prefix
+}
+
+func (c *fakeConnect) setDown(down bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.down = down
+}
+
+func (c *fakeConnect) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error_code": 503, "message": "rebalancing"}`))
+		return
+	}
+	if r.URL.Path == "/connectors" {
+		names := make([]string, 0, len(c.prefixes))
+		for name := range c.prefixes {
+			names = append(names, name)
+		}
+		json.NewEncoder(w).Encode(names)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/connectors/")
+	json.NewEncoder(w).Encode(map[string]any{
+		"name":   name,
+		"config": map[string]string{"topic.prefix": c.prefixes[name]},
+	})
+}
+
+type heartbeatRecorder struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (r *heartbeatRecorder) ObserveHeartbeat(clusterName, connector string, _ time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen = append(r.seen, clusterName+"/"+connector)
+}
+
+// take returns the recorded heartbeats, sorted, and forgets them.
+func (r *heartbeatRecorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := r.seen
+	r.seen = nil
+	slices.Sort(seen)
+	return seen
+}
+
+func TestChangeFeedHeartbeats(t *testing.T) {
+	ctx := context.Background()
+	connect := &fakeConnect{prefixes: map[string]string{"inventory": "postgres"}}
+	srv := httptest.NewServer(connect)
+	t.Cleanup(srv.Close)
+
+	clusters, err := cluster.New([]cluster.Config{{Name: "primary", URL: srv.URL}}, time.Second)
+	if err != nil {
+		t.Fatalf("cluster.New: %v", err)
+	}
+	t.Cleanup(clusters.Close)
+	lg, err := logger.NewLogger("test", logger.WithLevel("error"))
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+
+	heartbeats := &heartbeatRecorder{}
+	feed := NewChangeFeed(nil, heartbeats, nil, nil, clusters, lg)
+	beat := func(prefix string) []string {
+		t.Helper()
+		ev := kafka.Event{Message: kafka.Message{Topic: HeartbeatTopicPrefix + prefix, Timestamp: time.Now()}}
+		if err := feed.Handle(ctx, ev); err != nil {
+			t.Fatalf("Handle: %v", err)
+		}
+		return heartbeats.take()
+	}
+
+	// Heartbeats are dropped until the configs are loaded, and an unknown
+	// prefix asks for a reload.
+	if got := beat("postgres"); len(got) != 0 {
+		t.Errorf("got heartbeats %v before the configs were loaded", got)
+	}
+	select {
+	case <-feed.unknown:
+	default:
+		t.Error("an unknown prefix did not ask for a reload")
+	}
+
+	feed.refresh(ctx)
+	if got := beat("postgres"); !slices.Equal(got, []string{"primary/inventory"}) {
+		t.Errorf("got heartbeats %v, want primary/inventory", got)
+	}
+
+	// A connector added later with a known prefix is credited after the
+	// next reload.
+	connect.set("inventory-replica", "postgres")
+	feed.refresh(ctx)
+	if got := beat("postgres"); !slices.Equal(got, []string{"primary/inventory", "primary/inventory-replica"}) {
+		t.Errorf("got heartbeats %v, want both connectors", got)
+	}
+
+	// A cluster that cannot be listed keeps its known prefixes.
+	connect.setDown(true)
+	feed.refresh(ctx)
+	if got := beat("postgres"); len(got) != 2 {
+		t.Errorf("got heartbeats %v while Kafka Connect was down, want both connectors", got)
+	}
+}
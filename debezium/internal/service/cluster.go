@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"debezium_server/internal/cluster"
+	"debezium_server/internal/models"
+	debezium_client "debezium_server/pkg/debezium-client"
+	"debezium_server/pkg/redact"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrSameCluster         = errors.New("source and target cluster are the same")
+	ErrInvalidLoggerScope  = errors.New("logger scope must be worker or cluster")
+	ErrCopyNeedsOverrides  = errors.New("a copy running next to its source needs overrides")
+	ErrMigrationIncomplete = errors.New("the target is running but the stopped source was not deleted")
+)
+
+// exclusiveKeys name resources a connector owns: its replication slot, its
+// publication and its topics. A copy that runs next to its source must not
+// share them.
+var exclusiveKeys = []string{"slot.name", "publication.name", "topic.prefix"}
+
+type ClusterInfo struct {
+	Name    string   `json:"name"`
+	URLs    []string `json:"urls"`
+	Default bool     `json:"default"`
+}
+
+// ClusterConnectors is one cluster's part of the aggregated view. Error is
+// set instead of the connectors when the cluster could not be reached.
+type ClusterConnectors struct {
+	Cluster  string                                     `json:"cluster"`
+	Names    []string                                   `json:"names"`
+	Statuses map[string]debezium_client.ConnectorStatus `json:"statuses,omitempty"`
+	Error    string                                     `json:"error,omitempty"`
+}
+
+type CopyRequest struct {
+	SourceCluster string
+	Name          string
+	TargetCluster string
+	// TargetName defaults to Name.
+	TargetName string
+	// Offsets carries the source offsets so the copy resumes where the
+	// source is instead of taking a new snapshot.
+	Offsets bool
+	// Migrate stops the source before reading its offsets and deletes it
+	// once the copy runs. It implies Offsets.
+	Migrate bool
+	// Overrides replace keys of the source config. Without Migrate they
+	// must give the copy its own exclusiveKeys.
+	Overrides map[string]string
+}
+
+// ClusterService works across the configured Kafka Connect clusters.
+type ClusterService struct {
+	clusters *cluster.Registry
+	redactor *redact.Redactor
+	audit    AuditStore
+	versions VersionStore
+}
+
+func NewClusterService(
+	clusters *cluster.Registry,
+	redactor *redact.Redactor,
+	audit AuditStore,
+	versions VersionStore,
+) *ClusterService {
+	return &ClusterService{
+		clusters: clusters,
+		redactor: redactor,
+		audit:    audit,
+		versions: versions,
+	}
+}
+
+func (s *ClusterService) List() []ClusterInfo {
+	def := s.clusters.Default().Name
+
+	var out []ClusterInfo
+	for _, c := range s.clusters.All() {
+		out = append(out, ClusterInfo{
+			Name:    c.Name,
+			URLs:    stripUserinfo(c.Client.BaseURLs()),
+			Default: c.Name == def,
+		})
+	}
+	return out
+}
+
+// Info returns the worker version and Kafka cluster ID of a cluster.
+func (s *ClusterService) Info(ctx context.Context, name string) (debezium_client.ServerInfo, error) {
+	c, err := s.clusters.Get(name)
+	if err != nil {
+		return debezium_client.ServerInfo{}, err
+	}
+	return c.Client.GetServerInfo(ctx)
+}
+
+func (s *ClusterService) Loggers(ctx context.Context, name string) (map[string]debezium_client.LoggerLevel, error) {
+	c, err := s.clusters.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.ListLoggers(ctx)
+}
+
+func (s *ClusterService) Logger(ctx context.Context, name, logger string) (debezium_client.LoggerLevel, error) {
+	c, err := s.clusters.Get(name)
+	if err != nil {
+		return debezium_client.LoggerLevel{}, err
+	}
+	return c.Client.GetLogger(ctx, logger)
+}
+
+// SetLoggerLevel changes the level of a Kafka Connect logger, on every
+// worker unless scope is debezium_client.LoggerScopeWorker.
+func (s *ClusterService) SetLoggerLevel(ctx context.Context, name, logger, level, scope string) ([]string, error) {
+	c, err := s.clusters.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	switch scope {
+	case "":
+		scope = debezium_client.LoggerScopeCluster
+	case debezium_client.LoggerScopeWorker, debezium_client.LoggerScopeCluster:
+	default:
+		return nil, ErrInvalidLoggerScope
+	}
+	return c.Client.SetLoggerLevel(ctx, logger, strings.ToUpper(level), scope)
+}
+
+// Connectors lists the connectors of every cluster concurrently. A cluster
+// that fails is reported in its entry and does not fail the whole view.
+func (s *ClusterService) Connectors(ctx context.Context, expandStatus bool) []ClusterConnectors {
+	all := s.clusters.All()
+	out := make([]ClusterConnectors, len(all))
+
+	var wg sync.WaitGroup
+	for i, c := range all {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			out[i].Cluster = c.Name
+			resp, err := c.Client.ListConnectors(ctx, expandStatus)
+			if err != nil {
+				out[i].Error = err.Error()
+				return
+			}
+			sort.Strings(resp.Names)
+			out[i].Names = resp.Names
+			out[i].Statuses = resp.Statuses
+		}()
+	}
+	wg.Wait()
+
+	return out
+}
+
+// Copy creates a connector on the target cluster with the source's config
+// and, if requested, its offsets. With offsets the target is created
+// stopped, its offsets are written and it is then resumed. Migrate also
+// stops and finally deletes the source. If a step fails, the source is
+// resumed and a partially created target is deleted. If only the final
+// delete fails, the target is returned with ErrMigrationIncomplete.
+func (s *ClusterService) Copy(ctx context.Context, req CopyRequest) (debezium_client.GetConnectorResponse, error) {
+	src, err := s.clusters.Get(req.SourceCluster)
+	if err != nil {
+		return debezium_client.GetConnectorResponse{}, err
+	}
+	dst, err := s.clusters.Get(req.TargetCluster)
+	if err != nil {
+		return debezium_client.GetConnectorResponse{}, err
+	}
+	if src == dst {
+		return debezium_client.GetConnectorResponse{}, ErrSameCluster
+	}
+
+	targetName := req.TargetName
+	if targetName == "" {
+		targetName = req.Name
+	}
+	action := models.AuditCopy
+	if req.Migrate {
+		action = models.AuditMigrate
+	}
+	srcAudit := auditor{cluster: src.Name, store: s.audit, redactor: s.redactor}
+	dstAudit := auditor{cluster: dst.Name, store: s.audit, redactor: s.redactor}
+
+	current, err := src.Client.GetConnector(ctx, req.Name)
+	if err != nil {
+		return debezium_client.GetConnectorResponse{}, fmt.Errorf("read source: %w", err)
+	}
+
+	config := make(map[string]string, len(current.Config))
+	for k, v := range current.Config {
+		config[k] = fmt.Sprint(v)
+	}
+	if !req.Migrate {
+		var shared []string
+		for _, k := range exclusiveKeys {
+			if v, ok := config[k]; ok && (req.Overrides[k] == "" || req.Overrides[k] == v) {
+				shared = append(shared, k)
+			}
+		}
+		if len(shared) > 0 {
+			return debezium_client.GetConnectorResponse{}, fmt.Errorf("%w: %s",
+				ErrCopyNeedsOverrides, strings.Join(shared, ", "))
+		}
+	}
+	for k, v := range req.Overrides {
+		config[k] = v
+	}
+	config["name"] = targetName
+
+	var rollback []func(context.Context)
+	fail := func(err error) (debezium_client.GetConnectorResponse, error) {
+		// Undo in reverse order even if the request was cancelled.
+		undoCtx := context.WithoutCancel(ctx)
+		for i := len(rollback) - 1; i >= 0; i-- {
+			rollback[i](undoCtx)
+		}
+		diff := dstAudit.configDiff(nil, stringsToAny(config))
+		return debezium_client.GetConnectorResponse{}, dstAudit.record(ctx, action, targetName, diff, err)
+	}
+
+	if req.Migrate {
+		if err := src.Client.StopConnector(ctx, req.Name); err != nil {
+			return fail(fmt.Errorf("stop source: %w", err))
+		}
+		rollback = append(rollback, func(ctx context.Context) {
+			_ = src.Client.ResumeConnector(ctx, req.Name)
+		})
+	}
+
+	var offsets debezium_client.ConnectorOffsets
+	if req.Offsets || req.Migrate {
+		offsets, err = src.Client.GetConnectorOffsets(ctx, req.Name)
+		if err != nil {
+			return fail(fmt.Errorf("read source offsets: %w", err))
+		}
+	}
+
+	create := debezium_client.CreateConnectorRequest{
+		Name:   targetName,
+		Config: debezium_client.NewCreateConnectorConfig(config),
+	}
+	if len(offsets.Offsets) > 0 {
+		create.InitialState = debezium_client.StateStopped
+	}
+	if _, err := dst.Client.CreateConnector(ctx, create); err != nil {
+		return fail(fmt.Errorf("create target: %w", err))
+	}
+	rollback = append(rollback, func(ctx context.Context) {
+		_ = dst.Client.DeleteConnector(ctx, targetName)
+	})
+
+	if len(offsets.Offsets) > 0 {
+		if err := dst.Client.AlterConnectorOffsets(ctx, targetName, offsets); err != nil {
+			return fail(fmt.Errorf("write target offsets: %w", err))
+		}
+		if err := dst.Client.ResumeConnector(ctx, targetName); err != nil {
+			return fail(fmt.Errorf("resume target: %w", err))
+		}
+	}
+
+	diff := dstAudit.configDiff(nil, stringsToAny(config))
+	for k, v := range offsetsDiff(nil, offsets.Offsets) {
+		diff[k] = v
+	}
+	if err := dstAudit.record(ctx, action, targetName, diff, nil); err != nil {
+		return debezium_client.GetConnectorResponse{}, err
+	}
+	dstVersions := versioner{cluster: dst.Name, store: s.versions, redactor: s.redactor}
+	note := fmt.Sprintf("%s from %s/%s", action, src.Name, req.Name)
+	if err := dstVersions.record(ctx, targetName, stringsToAny(config), note); err != nil {
+		return debezium_client.GetConnectorResponse{}, err
+	}
+
+	// The target already runs, so a failed delete is not rolled back: the
+	// source stays stopped and the caller has to delete it.
+	var incomplete error
+	if req.Migrate {
+		err := src.Client.DeleteConnector(ctx, req.Name)
+		diff := srcAudit.configDiff(current.Config, nil)
+		if err := srcAudit.record(ctx, models.AuditDelete, req.Name, diff, err); err != nil {
+			incomplete = fmt.Errorf("%w: %w", ErrMigrationIncomplete, err)
+		}
+	}
+
+	created, err := dst.Client.GetConnector(ctx, targetName)
+	if err != nil {
+		return debezium_client.GetConnectorResponse{}, errors.Join(incomplete, err)
+	}
+	created.Config = s.redactor.Map(created.Config)
+	for i := range created.Tasks {
+		created.Tasks[i].Config = s.redactor.Map(created.Tasks[i].Config)
+	}
+
+	return created, incomplete
+}
+
+func stripUserinfo(urls []string) []string {
+	out := make([]string, len(urls))
+	for i, raw := range urls {
+		out[i] = raw
+		if u, err := url.Parse(raw); err == nil {
+			u.User = nil
+			out[i] = u.String()
+		}
+	}
+	return out
+}
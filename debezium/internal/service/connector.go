@@ -0,0 +1,476 @@
+package service
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"debezium_server/pkg/configtemplate"
+	debezium_client "debezium_server/pkg/debezium-client"
+	"debezium_server/pkg/logger"
+	"debezium_server/pkg/redact"
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+type ConnectClient interface {
+	CreateConnector(
+		ctx context.Context,
+		data debezium_client.CreateConnectorRequest,
+	) (*debezium_client.CreateConnectorResponse, error)
+	GetConnector(ctx context.Context, name string) (debezium_client.GetConnectorResponse, error)
+	GetConnectorStatus(ctx context.Context, name string) (debezium_client.ConnectorStatus, error)
+	GetConnectorTasks(ctx context.Context, name string) ([]debezium_client.TaskInfo, error)
+	ListConnectors(ctx context.Context, expandStatus bool) (debezium_client.ListConnectorsResponse, error)
+	UpdateConnectorConfig(
+		ctx context.Context,
+		name string,
+		config debezium_client.ConnectorConfig,
+	) (debezium_client.GetConnectorResponse, error)
+	UpsertConnector(
+		ctx context.Context,
+		name string,
+		config debezium_client.ConnectorConfig,
+	) (debezium_client.GetConnectorResponse, bool, error)
+	PauseConnector(ctx context.Context, name string) error
+	ResumeConnector(ctx context.Context, name string) error
+	RestartConnector(ctx context.Context, name string) error
+	RestartConnectorTask(ctx context.Context, name string, taskId int) error
+	DeleteConnector(ctx context.Context, name string) error
+	StopConnector(ctx context.Context, name string) error
+	GetConnectorOffsets(ctx context.Context, name string) (debezium_client.ConnectorOffsets, error)
+	AlterConnectorOffsets(ctx context.Context, name string, offsets debezium_client.ConnectorOffsets) error
+	ResetConnectorOffsets(ctx context.Context, name string) error
+	GetConnectorTopics(ctx context.Context, name string) ([]string, error)
+	ResetConnectorTopics(ctx context.Context, name string) error
+}
+
+// ConnectorService renders connector templates for the current environment
+// before they reach Kafka Connect and masks secrets in everything it returns.
+// Every mutation is written to the audit log and every applied config to
+// the version history.
+type ConnectorService struct {
+	connect   ConnectClient
+	templates *configtemplate.Renderer
+	redactor  *redact.Redactor
+	audit     auditor
+	versions  versioner
+	lg        logger.Logger
+}
+
+func NewConnectorService(
+	cluster string,
+	connect ConnectClient,
+	templates *configtemplate.Renderer,
+	redactor *redact.Redactor,
+	audit AuditStore,
+	versions VersionStore,
+	lg logger.Logger,
+) *ConnectorService {
+	return &ConnectorService{
+		connect:   connect,
+		templates: templates,
+		redactor:  redactor,
+		audit:     auditor{cluster: cluster, store: audit, redactor: redactor},
+		versions:  versioner{cluster: cluster, store: versions, redactor: redactor},
+		lg:        lg,
+	}
+}
+
+// Render resolves the placeholders of a definition. vars override the
+// environment variables file for this call only.
+func (s *ConnectorService) Render(
+	def configtemplate.Definition,
+	vars map[string]string,
+) (configtemplate.Definition, error) {
+	return s.templates.WithVars(vars).Render(def)
+}
+
+func (s *ConnectorService) Create(
+	ctx context.Context,
+	def configtemplate.Definition,
+	vars map[string]string,
+	comment string,
+) (*debezium_client.CreateConnectorResponse, error) {
+	rendered, err := s.Render(def, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.connect.CreateConnector(ctx, debezium_client.CreateConnectorRequest{
+		Name:   rendered.Name,
+		Config: debezium_client.NewCreateConnectorConfig(rendered.Config),
+	})
+	diff := s.audit.configDiff(nil, stringsToAny(rendered.Config))
+	if err := s.audit.record(ctx, models.AuditCreate, rendered.Name, diff, err); err != nil {
+		return nil, err
+	}
+	if err := s.versions.record(ctx, rendered.Name, stringsToAny(resp.Config.ToMap()), comment); err != nil {
+		return nil, err
+	}
+
+	redacted := *resp
+	redacted.Config = debezium_client.NewCreateConnectorConfig(s.redactor.StringMap(resp.Config.ToMap()))
+
+	return &redacted, nil
+}
+
+func (s *ConnectorService) List(ctx context.Context, expandStatus bool) (debezium_client.ListConnectorsResponse, error) {
+	resp, err := s.connect.ListConnectors(ctx, expandStatus)
+	if err != nil {
+		return debezium_client.ListConnectorsResponse{}, err
+	}
+
+	sort.Strings(resp.Names)
+
+	return resp, nil
+}
+
+func (s *ConnectorService) Get(ctx context.Context, name string) (debezium_client.GetConnectorResponse, error) {
+	resp, err := s.connect.GetConnector(ctx, name)
+	if err != nil {
+		return debezium_client.GetConnectorResponse{}, err
+	}
+
+	return s.redactConnector(resp), nil
+}
+
+func (s *ConnectorService) Status(ctx context.Context, name string) (debezium_client.ConnectorStatus, error) {
+	return s.connect.GetConnectorStatus(ctx, name)
+}
+
+func (s *ConnectorService) Tasks(ctx context.Context, name string) ([]debezium_client.TaskInfo, error) {
+	tasks, err := s.connect.GetConnectorTasks(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.redactTasks(tasks), nil
+}
+
+func (s *ConnectorService) Pause(ctx context.Context, name string) error {
+	err := s.connect.PauseConnector(ctx, name)
+	return s.audit.record(ctx, models.AuditPause, name, nil, err)
+}
+
+func (s *ConnectorService) Resume(ctx context.Context, name string) error {
+	err := s.connect.ResumeConnector(ctx, name)
+	return s.audit.record(ctx, models.AuditResume, name, nil, err)
+}
+
+func (s *ConnectorService) Restart(ctx context.Context, name string) error {
+	err := s.connect.RestartConnector(ctx, name)
+	return s.audit.record(ctx, models.AuditRestart, name, nil, err)
+}
+
+func (s *ConnectorService) RestartTask(ctx context.Context, name string, taskID int) error {
+	err := s.connect.RestartConnectorTask(ctx, name, taskID)
+	return s.audit.recordTask(ctx, models.AuditRestart, name, taskID, err)
+}
+
+func (s *ConnectorService) Stop(ctx context.Context, name string) error {
+	err := s.connect.StopConnector(ctx, name)
+	return s.audit.record(ctx, models.AuditStop, name, nil, err)
+}
+
+// Delete removes the connector. Its last config is kept in the audit log.
+func (s *ConnectorService) Delete(ctx context.Context, name string) error {
+	current, getErr := s.connect.GetConnector(ctx, name)
+	s.warnLookup(ctx, name, "config", getErr)
+
+	err := s.connect.DeleteConnector(ctx, name)
+	return s.audit.record(ctx, models.AuditDelete, name, s.audit.configDiff(current.Config, nil), err)
+}
+
+func (s *ConnectorService) Offsets(ctx context.Context, name string) (debezium_client.ConnectorOffsets, error) {
+	return s.connect.GetConnectorOffsets(ctx, name)
+}
+
+// AlterOffsets overwrites the offsets of the given partitions of a stopped
+// connector. The previous offsets are kept in the audit log.
+func (s *ConnectorService) AlterOffsets(
+	ctx context.Context,
+	name string,
+	offsets debezium_client.ConnectorOffsets,
+) error {
+	current, getErr := s.connect.GetConnectorOffsets(ctx, name)
+	s.warnLookup(ctx, name, "offsets", getErr)
+
+	err := s.connect.AlterConnectorOffsets(ctx, name, offsets)
+	diff := offsetsDiff(current.Offsets, offsets.Offsets)
+	return s.audit.record(ctx, models.AuditAlterOffsets, name, diff, err)
+}
+
+// ResetOffsets deletes the offsets of a stopped connector. The previous
+// offsets are kept in the audit log.
+func (s *ConnectorService) ResetOffsets(ctx context.Context, name string) error {
+	current, getErr := s.connect.GetConnectorOffsets(ctx, name)
+	s.warnLookup(ctx, name, "offsets", getErr)
+
+	err := s.connect.ResetConnectorOffsets(ctx, name)
+	diff := offsetsDiff(current.Offsets, nil)
+	return s.audit.record(ctx, models.AuditResetOffsets, name, diff, err)
+}
+
+// Topics returns the topics the connector has used, sorted.
+func (s *ConnectorService) Topics(ctx context.Context, name string) ([]string, error) {
+	topics, err := s.connect.GetConnectorTopics(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(topics)
+
+	return topics, nil
+}
+
+// ResetTopics clears the recorded topics. The previous set is kept in the
+// audit log.
+func (s *ConnectorService) ResetTopics(ctx context.Context, name string) error {
+	current, getErr := s.connect.GetConnectorTopics(ctx, name)
+	s.warnLookup(ctx, name, "topics", getErr)
+
+	err := s.connect.ResetConnectorTopics(ctx, name)
+	diff := map[string]models.AuditChange{"topics": {Before: current}}
+	return s.audit.record(ctx, models.AuditResetTopics, name, diff, err)
+}
+
+// UpdateConfig renders the config and replaces masked secrets with the
+// values stored in Kafka Connect before applying it.
+func (s *ConnectorService) UpdateConfig(
+	ctx context.Context,
+	name string,
+	config map[string]string,
+	vars map[string]string,
+	comment string,
+) (debezium_client.GetConnectorResponse, error) {
+	rendered, problems := s.templates.WithVars(vars).RenderConfig(config)
+	if len(problems) > 0 {
+		return debezium_client.GetConnectorResponse{}, &configtemplate.ValidationError{Problems: problems}
+	}
+
+	current, getErr := s.connect.GetConnector(ctx, name)
+	s.warnLookup(ctx, name, "config", getErr)
+
+	rendered, err := s.restoreSecrets(rendered, current.Config, getErr)
+	if err != nil {
+		return debezium_client.GetConnectorResponse{}, err
+	}
+
+	resp, err := s.connect.UpdateConnectorConfig(ctx, name, debezium_client.ConfigMap(rendered))
+	after := stringsToAny(rendered)
+	if err == nil {
+		after = resp.Config
+	} else {
+		err = fmt.Errorf("update config: %w", err)
+	}
+	diff := s.audit.configDiff(current.Config, after)
+	if err := s.audit.record(ctx, models.AuditUpdate, name, diff, err); err != nil {
+		return debezium_client.GetConnectorResponse{}, err
+	}
+	if err := s.versions.record(ctx, name, resp.Config, comment); err != nil {
+		return debezium_client.GetConnectorResponse{}, err
+	}
+
+	return s.redactConnector(resp), nil
+}
+
+// Upsert renders the config and creates the connector, or replaces its
+// config if it exists so that it keeps its offsets. Masked secrets are
+// restored from the stored config. created reports which one happened.
+func (s *ConnectorService) Upsert(
+	ctx context.Context,
+	name string,
+	config map[string]string,
+	vars map[string]string,
+	comment string,
+) (resp debezium_client.GetConnectorResponse, created bool, err error) {
+	rendered, problems := s.templates.WithVars(vars).RenderConfig(config)
+	if len(problems) > 0 {
+		return debezium_client.GetConnectorResponse{}, false, &configtemplate.ValidationError{Problems: problems}
+	}
+
+	current, getErr := s.connect.GetConnector(ctx, name)
+
+	rendered, err = s.restoreSecrets(rendered, current.Config, getErr)
+	if err != nil {
+		return debezium_client.GetConnectorResponse{}, false, err
+	}
+
+	resp, created, err = s.connect.UpsertConnector(ctx, name, debezium_client.ConfigMap(rendered))
+	after := stringsToAny(rendered)
+	if err == nil {
+		after = resp.Config
+	} else {
+		err = fmt.Errorf("upsert: %w", err)
+	}
+
+	action := models.AuditUpdate
+	if created || (err != nil && getErr != nil) {
+		action = models.AuditCreate
+	}
+	if err == nil && !created {
+		// The connector existed, so the lookup should have found it.
+		s.warnLookup(ctx, name, "config", getErr)
+	}
+	diff := s.audit.configDiff(current.Config, after)
+	if err := s.audit.record(ctx, action, name, diff, err); err != nil {
+		return debezium_client.GetConnectorResponse{}, false, err
+	}
+	if err := s.versions.record(ctx, name, resp.Config, comment); err != nil {
+		return debezium_client.GetConnectorResponse{}, false, err
+	}
+
+	return s.redactConnector(resp), created, nil
+}
+
+// Versions returns the config history of the connector, newest first.
+func (s *ConnectorService) Versions(
+	ctx context.Context,
+	name string,
+	limit, offset int,
+) ([]models.ConnectorVersion, error) {
+	return s.versions.list(ctx, name, limit, offset)
+}
+
+func (s *ConnectorService) Version(ctx context.Context, name string, version int) (models.ConnectorVersion, error) {
+	return s.versions.get(ctx, name, version)
+}
+
+// DiffVersions returns the keys that differ between two versions.
+func (s *ConnectorService) DiffVersions(
+	ctx context.Context,
+	name string,
+	from, to int,
+) (map[string]models.AuditChange, error) {
+	before, err := s.versions.get(ctx, name, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.versions.get(ctx, name, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.audit.configDiff(stringsToAny(before.Config), stringsToAny(after.Config)), nil
+}
+
+// Rollback applies the config of an earlier version. Secrets are stored
+// masked in the history, so the connector keeps its current secret values.
+// The rollback itself is recorded as a new version.
+func (s *ConnectorService) Rollback(
+	ctx context.Context,
+	name string,
+	version int,
+	comment string,
+) (debezium_client.GetConnectorResponse, error) {
+	target, err := s.versions.get(ctx, name, version)
+	if err != nil {
+		return debezium_client.GetConnectorResponse{}, err
+	}
+
+	current, getErr := s.connect.GetConnector(ctx, name)
+	s.warnLookup(ctx, name, "config", getErr)
+
+	config, err := s.restoreSecrets(target.Config, current.Config, getErr)
+	if err != nil {
+		return debezium_client.GetConnectorResponse{}, err
+	}
+
+	resp, err := s.connect.UpdateConnectorConfig(ctx, name, debezium_client.ConfigMap(config))
+	after := stringsToAny(config)
+	if err == nil {
+		after = resp.Config
+	} else {
+		err = fmt.Errorf("rollback: %w", err)
+	}
+	diff := s.audit.configDiff(current.Config, after)
+	if err := s.audit.record(ctx, models.AuditRollback, name, diff, err); err != nil {
+		return debezium_client.GetConnectorResponse{}, err
+	}
+
+	note := fmt.Sprintf("rollback to version %d", version)
+	if comment != "" {
+		note += ": " + comment
+	}
+	if err := s.versions.record(ctx, name, resp.Config, note); err != nil {
+		return debezium_client.GetConnectorResponse{}, err
+	}
+
+	return s.redactConnector(resp), nil
+}
+
+// restoreSecrets replaces masked secrets with the stored values. getErr is
+// the error from reading the stored config, reported only if it is needed.
+func (s *ConnectorService) restoreSecrets(
+	config map[string]string,
+	stored map[string]any,
+	getErr error,
+) (map[string]string, error) {
+	masked := false
+	for k, v := range config {
+		if v == s.redactor.Mask() && s.redactor.IsSecret(k) {
+			masked = true
+			break
+		}
+	}
+	if !masked {
+		return config, nil
+	}
+
+	if getErr != nil {
+		return nil, fmt.Errorf("restore secrets: %w", getErr)
+	}
+
+	restored, missing := s.redactor.Restore(config, stored)
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		problems := make([]configtemplate.Problem, 0, len(missing))
+		for _, k := range missing {
+			problems = append(problems, configtemplate.Problem{
+				Key:         "config." + k,
+				Placeholder: s.redactor.Mask(),
+				Reason:      "is masked but the connector has no stored value",
+			})
+		}
+		return nil, &configtemplate.ValidationError{Problems: problems}
+	}
+
+	return restored, nil
+}
+
+// warnLookup logs a failed read of the state a mutation is about to change.
+// The mutation still runs, but its audit entry lacks the previous values.
+func (s *ConnectorService) warnLookup(ctx context.Context, name, what string, err error) {
+	if err == nil {
+		return
+	}
+	s.lg.Warn(ctx, "cannot read connector "+what+" for the audit log",
+		zap.String("connector", name), zap.Error(err))
+}
+
+func (s *ConnectorService) redactConnector(resp debezium_client.GetConnectorResponse) debezium_client.GetConnectorResponse {
+	resp.Config = s.redactor.Map(resp.Config)
+	resp.Tasks = s.redactTasks(resp.Tasks)
+	return resp
+}
+
+func (s *ConnectorService) redactTasks(tasks []debezium_client.TaskInfo) []debezium_client.TaskInfo {
+	out := make([]debezium_client.TaskInfo, len(tasks))
+	for i, t := range tasks {
+		t.Config = s.redactor.Map(t.Config)
+		out[i] = t
+	}
+	return out
+}
+
+func offsetsDiff(before, after []debezium_client.ConnectorOffset) map[string]models.AuditChange {
+	change := models.AuditChange{}
+	if before != nil {
+		change.Before = before
+	}
+	if after != nil {
+		change.After = after
+	}
+	return map[string]models.AuditChange{"offsets": change}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"debezium_server/pkg/cdc/kafka"
+	"errors"
+	"fmt"
+)
+
+const (
+	defaultDeadLetterLimit = 100
+	maxDeadLetterLimit     = 1000
+)
+
+var (
+	ErrReplayFailed = errors.New("replayed event failed again")
+)
+
+type DeadLetterStore interface {
+	Insert(ctx context.Context, dl models.DeadLetter) error
+	List(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetter, error)
+	Get(ctx context.Context, id int64) (models.DeadLetter, error)
+	RecordFailure(ctx context.Context, id int64, errMsg string) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// Replayer handles a message once more; *kafka.Consumer implements it.
+type Replayer interface {
+	Replay(ctx context.Context, m kafka.Message) error
+}
+
+// DeadLetterQueue stores the messages the consumer gave up on in
+// cdc_dead_letters.
+type DeadLetterQueue struct {
+	store DeadLetterStore
+}
+
+func NewDeadLetterQueue(store DeadLetterStore) *DeadLetterQueue {
+	return &DeadLetterQueue{store: store}
+}
+
+var _ kafka.DeadLetterQueue = (*DeadLetterQueue)(nil)
+
+func (q *DeadLetterQueue) Put(ctx context.Context, dl kafka.DeadLetter) error {
+	m := models.DeadLetter{
+		Topic:       dl.Topic,
+		Partition:   dl.Partition,
+		Offset:      dl.Offset,
+		Key:         dl.Key,
+		Value:       dl.Value,
+		MessageTime: dl.Timestamp,
+		Error:       dl.Error,
+		Attempts:    dl.Attempts,
+	}
+	for _, h := range dl.Headers {
+		m.Headers = append(m.Headers, models.DeadLetterHeader{Key: h.Key, Value: h.Value})
+	}
+	return q.store.Insert(ctx, m)
+}
+
+// DeadLetterService lists, replays and discards dead letters.
+type DeadLetterService struct {
+	store    DeadLetterStore
+	replayer Replayer
+}
+
+func NewDeadLetterService(store DeadLetterStore, replayer Replayer) *DeadLetterService {
+	return &DeadLetterService{store: store, replayer: replayer}
+}
+
+func (s *DeadLetterService) List(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetter, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultDeadLetterLimit
+	}
+	if filter.Limit > maxDeadLetterLimit {
+		filter.Limit = maxDeadLetterLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	return s.store.List(ctx, filter)
+}
+
+func (s *DeadLetterService) Get(ctx context.Context, id int64) (models.DeadLetter, error) {
+	return s.store.Get(ctx, id)
+}
+
+// Replay hands the message to the consumer's handler again and removes it
+// on success. A failure is recorded on the dead letter and returned as
+// ErrReplayFailed. Later messages with the same key were already handled,
+// so replaying an old change can move a projection backwards.
+func (s *DeadLetterService) Replay(ctx context.Context, id int64) error {
+	dl, err := s.store.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("Replay.Get: %w", err)
+	}
+
+	m := kafka.Message{
+		Topic:     dl.Topic,
+		Partition: dl.Partition,
+		Offset:    dl.Offset,
+		Key:       dl.Key,
+		Value:     dl.Value,
+		Timestamp: dl.MessageTime,
+	}
+	for _, h := range dl.Headers {
+		m.Headers = append(m.Headers, kafka.Header{Key: h.Key, Value: h.Value})
+	}
+
+	if replayErr := s.replayer.Replay(ctx, m); replayErr != nil {
+		if err := s.store.RecordFailure(ctx, id, replayErr.Error()); err != nil {
+			return fmt.Errorf("Replay.RecordFailure: %w", errors.Join(replayErr, err))
+		}
+		return fmt.Errorf("%w: %w", ErrReplayFailed, replayErr)
+	}
+
+	if err := s.store.Delete(ctx, id); err != nil {
+		return fmt.Errorf("Replay.Delete: %w", err)
+	}
+	return nil
+}
+
+// Discard removes a dead letter without handling it.
+func (s *DeadLetterService) Discard(ctx context.Context, id int64) error {
+	if err := s.store.Delete(ctx, id); err != nil {
+		return fmt.Errorf("Discard.Delete: %w", err)
+	}
+	return nil
+}
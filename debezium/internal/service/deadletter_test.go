@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"debezium_server/pkg/cdc/kafka"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// memoryDeadLetters is a DeadLetterStore that merges repeated inserts of a
+// message like the ON CONFLICT clause of the repository.
+type memoryDeadLetters struct {
+	letters map[int64]models.DeadLetter
+	nextID  int64
+}
+
+func newMemoryDeadLetters() *memoryDeadLetters {
+	return &memoryDeadLetters{letters: make(map[int64]models.DeadLetter)}
+}
+
+func (s *memoryDeadLetters) Insert(_ context.Context, dl models.DeadLetter) error {
+	for id, stored := range s.letters {
+		if stored.Topic == dl.Topic && stored.Partition == dl.Partition && stored.Offset == dl.Offset {
+			stored.Error = dl.Error
+			stored.Attempts += dl.Attempts
+			s.letters[id] = stored
+			return nil
+		}
+	}
+	s.nextID++
+	dl.ID = s.nextID
+	s.letters[dl.ID] = dl
+	return nil
+}
+
+func (s *memoryDeadLetters) List(context.Context, models.DeadLetterFilter) ([]models.DeadLetter, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *memoryDeadLetters) Get(_ context.Context, id int64) (models.DeadLetter, error) {
+	dl, ok := s.letters[id]
+	if !ok {
+		return models.DeadLetter{}, fmt.Errorf("get: %w", models.ErrDeadLetterNotFound)
+	}
+	return dl, nil
+}
+
+func (s *memoryDeadLetters) RecordFailure(_ context.Context, id int64, errMsg string) error {
+	dl, ok := s.letters[id]
+	if !ok {
+		return fmt.Errorf("update: %w", models.ErrDeadLetterNotFound)
+	}
+	dl.Error = errMsg
+	dl.Attempts++
+	s.letters[id] = dl
+	return nil
+}
+
+func (s *memoryDeadLetters) Delete(_ context.Context, id int64) error {
+	if _, ok := s.letters[id]; !ok {
+		return fmt.Errorf("delete: %w", models.ErrDeadLetterNotFound)
+	}
+	delete(s.letters, id)
+	return nil
+}
+
+type replayFunc func(ctx context.Context, m kafka.Message) error
+
+func (f replayFunc) Replay(ctx context.Context, m kafka.Message) error {
+	return f(ctx, m)
+}
+
+func TestDeadLetterReplay(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryDeadLetters()
+	queue := NewDeadLetterQueue(store)
+	failedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	put := func(offset int64, attempts int) {
+		t.Helper()
+		err := queue.Put(ctx, kafka.DeadLetter{
+			Message: kafka.Message{
+				Topic: "orders", Partition: 2, Offset: offset,
+				Key: []byte(`{"id":1}`), Value: []byte(`{"op":"u"}`),
+				Headers:   []kafka.Header{{Key: "trace", Value: []byte("abc")}},
+				Timestamp: failedAt,
+			},
+			Error:    "handler failed",
+			Attempts: attempts,
+			FailedAt: failedAt,
+		})
+		if err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	put(10, 3)
+	put(11, 3)
+
+	var replayed []kafka.Message
+	errAgain := errors.New("still failing")
+	svc := NewDeadLetterService(store, replayFunc(func(_ context.Context, m kafka.Message) error {
+		replayed = append(replayed, m)
+		if m.Offset == 11 {
+			return errAgain
+		}
+		return nil
+	}))
+
+	if err := svc.Replay(ctx, 1); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(replayed) != 1 {
+		t.Fatalf("replayed %d messages, want 1", len(replayed))
+	}
+	m := replayed[0]
+	if m.Topic != "orders" || m.Partition != 2 || m.Offset != 10 || string(m.Value) != `{"op":"u"}` ||
+		!m.Timestamp.Equal(failedAt) || len(m.Headers) != 1 || string(m.Headers[0].Value) != "abc" {
+		t.Errorf("replayed message %+v is not the stored one", m)
+	}
+	if _, err := store.Get(ctx, 1); !errors.Is(err, models.ErrDeadLetterNotFound) {
+		t.Errorf("a replayed dead letter is kept: %v", err)
+	}
+
+	// A failed replay keeps the dead letter with the new error and counts
+	// the attempt.
+	if err := svc.Replay(ctx, 2); !errors.Is(err, ErrReplayFailed) || !errors.Is(err, errAgain) {
+		t.Fatalf("Replay returned %v, want ErrReplayFailed", err)
+	}
+	dl, err := store.Get(ctx, 2)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if dl.Error != errAgain.Error() || dl.Attempts != 4 {
+		t.Errorf("dead letter has error %q and %d attempts, want %q and 4", dl.Error, dl.Attempts, errAgain)
+	}
+
+	// The message failing again in the consumer adds its attempts.
+	put(11, 3)
+	if dl, _ = store.Get(ctx, 2); dl.Attempts != 7 || len(store.letters) != 1 {
+		t.Errorf("got %d dead letters, the last with %d attempts; want 1 with 7", len(store.letters), dl.Attempts)
+	}
+
+	if err := svc.Replay(ctx, 1); !errors.Is(err, models.ErrDeadLetterNotFound) {
+		t.Errorf("Replay of an unknown dead letter returned %v", err)
+	}
+}
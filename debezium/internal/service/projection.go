@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"debezium_server/internal/projection"
+	"debezium_server/pkg/signaling"
+	"fmt"
+	"time"
+)
+
+type Projector interface {
+	Statuses(ctx context.Context) ([]projection.Status, error)
+	Sources(name string) ([]string, error)
+	Now(ctx context.Context) (time.Time, error)
+	Reset(ctx context.Context, name string, before time.Time) error
+	RecordRebuild(ctx context.Context, name, signalID string) error
+}
+
+type SnapshotExecutor interface {
+	Execute(ctx context.Context, connector string, data signaling.SnapshotData) (SnapshotProgress, error)
+}
+
+// ProjectionService reports on the projections and rebuilds them from
+// incremental snapshots.
+type ProjectionService struct {
+	projector Projector
+	snapshots SnapshotExecutor
+}
+
+func NewProjectionService(projector Projector, snapshots SnapshotExecutor) *ProjectionService {
+	return &ProjectionService{projector: projector, snapshots: snapshots}
+}
+
+func (s *ProjectionService) List(ctx context.Context) ([]projection.Status, error) {
+	return s.projector.Statuses(ctx)
+}
+
+// Rebuild starts an incremental snapshot of the source tables of a
+// projection on connector, then removes the rows the projection wrote
+// before the snapshot started. The read events of the snapshot fill it
+// again, and changes streamed meanwhile are applied as usual. If the
+// snapshot cannot be started, the projection is left as it was.
+func (s *ProjectionService) Rebuild(ctx context.Context, name, connector string) (SnapshotProgress, error) {
+	tables, err := s.projector.Sources(name)
+	if err != nil {
+		return SnapshotProgress{}, fmt.Errorf("Rebuild.Sources: %w", err)
+	}
+	// Rows written from now on may come from the snapshot.
+	started, err := s.projector.Now(ctx)
+	if err != nil {
+		return SnapshotProgress{}, fmt.Errorf("Rebuild.Now: %w", err)
+	}
+
+	progress, err := s.snapshots.Execute(ctx, connector, signaling.SnapshotData{
+		DataCollections: tables,
+		Type:            signaling.SnapshotIncremental,
+	})
+	if err != nil {
+		return SnapshotProgress{}, fmt.Errorf("Rebuild.Execute: %w", err)
+	}
+
+	if err := s.projector.Reset(ctx, name, started); err != nil {
+		return SnapshotProgress{}, fmt.Errorf("Rebuild.Reset: %w", err)
+	}
+	if err := s.projector.RecordRebuild(ctx, name, progress.SignalID); err != nil {
+		return SnapshotProgress{}, fmt.Errorf("Rebuild.RecordRebuild: %w", err)
+	}
+	return progress, nil
+}
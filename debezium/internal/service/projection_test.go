@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"debezium_server/internal/projection"
+	"debezium_server/pkg/signaling"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// recordingProjector records the calls a rebuild makes.
+type recordingProjector struct {
+	calls []string
+	now   time.Time
+	reset time.Time
+}
+
+func (p *recordingProjector) Statuses(context.Context) ([]projection.Status, error) {
+	return nil, nil
+}
+
+func (p *recordingProjector) Sources(string) ([]string, error) {
+	return []string{"inventory.orders"}, nil
+}
+
+func (p *recordingProjector) Now(context.Context) (time.Time, error) {
+	p.calls = append(p.calls, "now")
+	return p.now, nil
+}
+
+func (p *recordingProjector) Reset(_ context.Context, _ string, before time.Time) error {
+	p.calls = append(p.calls, "reset")
+	p.reset = before
+	return nil
+}
+
+func (p *recordingProjector) RecordRebuild(_ context.Context, _, signalID string) error {
+	p.calls = append(p.calls, "record "+signalID)
+	return nil
+}
+
+type executeFunc func(ctx context.Context, connector string, data signaling.SnapshotData) (SnapshotProgress, error)
+
+func (f executeFunc) Execute(ctx context.Context, connector string, data signaling.SnapshotData) (SnapshotProgress, error) {
+	return f(ctx, connector, data)
+}
+
+func TestProjectionRebuild(t *testing.T) {
+	ctx := context.Background()
+	started := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	projector := &recordingProjector{now: started}
+	svc := NewProjectionService(projector, executeFunc(
+		func(_ context.Context, connector string, data signaling.SnapshotData) (SnapshotProgress, error) {
+			projector.calls = append(projector.calls, "execute "+connector)
+			if !slices.Equal(data.DataCollections, []string{"inventory.orders"}) || data.Type != signaling.SnapshotIncremental {
+				t.Errorf("snapshot of %v (%s), want an incremental snapshot of inventory.orders", data.DataCollections, data.Type)
+			}
+			return SnapshotProgress{SignalID: "signal-1"}, nil
+		}))
+
+	progress, err := svc.Rebuild(ctx, projection.OrderSummariesName, "postgres-connector")
+	if err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if progress.SignalID != "signal-1" {
+		t.Errorf("got signal %q, want signal-1", progress.SignalID)
+	}
+	// The snapshot starts before the reset, which keeps what it wrote.
+	want := []string{"now", "execute postgres-connector", "reset", "record signal-1"}
+	if !slices.Equal(projector.calls, want) {
+		t.Errorf("got calls %v, want %v", projector.calls, want)
+	}
+	if !projector.reset.Equal(started) {
+		t.Errorf("reset rows before %v, want %v", projector.reset, started)
+	}
+
+	// A snapshot that cannot start leaves the projection as it was.
+	errSignal := errors.New("signal table unavailable")
+	projector = &recordingProjector{now: started}
+	svc = NewProjectionService(projector, executeFunc(
+		func(context.Context, string, signaling.SnapshotData) (SnapshotProgress, error) {
+			return SnapshotProgress{}, errSignal
+		}))
+	if _, err := svc.Rebuild(ctx, projection.OrderSummariesName, "postgres-connector"); !errors.Is(err, errSignal) {
+		t.Fatalf("Rebuild returned %v, want the signal error", err)
+	}
+	if slices.Contains(projector.calls, "reset") {
+		t.Errorf("the projection was reset although the snapshot did not start: %v", projector.calls)
+	}
+}
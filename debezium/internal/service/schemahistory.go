@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"debezium_server/pkg/cdc"
+	"debezium_server/pkg/cdc/kafka"
+	"debezium_server/pkg/logger"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSchemaHistoryLimit = 100
+	maxSchemaHistoryLimit     = 1000
+)
+
+type SchemaHistoryStore interface {
+	Insert(ctx context.Context, v models.TableSchemaVersion) error
+	List(ctx context.Context, filter models.SchemaHistoryFilter) ([]models.TableSchemaVersion, error)
+	Latest(ctx context.Context, table, source string) (models.TableSchemaVersion, error)
+}
+
+type schemaKey struct {
+	table  string
+	source string
+}
+
+// trackedSchema is the latest version of a table and the message it was
+// first seen in.
+type trackedSchema struct {
+	schema    cdc.TableSchema
+	topic     string
+	partition int32
+	offset    int64
+	at        time.Time
+}
+
+// supersedes reports whether the version was seen in a later message than
+// ev, or in ev itself. Events of different keys are handled concurrently,
+// so an event with the old schema may come after the first with the new.
+func (s trackedSchema) supersedes(ev kafka.Event, at time.Time) bool {
+	if s.topic == ev.Topic && s.partition == ev.Partition {
+		return s.offset >= ev.Offset
+	}
+	return s.at.After(at)
+}
+
+// SchemaHistoryService records a version of a table whenever its schema
+// changes, flags breaking changes and checks proposed schemas against the
+// latest version.
+type SchemaHistoryService struct {
+	store SchemaHistoryStore
+	lg    logger.Logger
+
+	// mu serializes observations, so a new schema is recorded once.
+	mu     sync.Mutex
+	latest map[schemaKey]trackedSchema
+
+	schemaless sync.Once
+}
+
+func NewSchemaHistoryService(store SchemaHistoryStore, lg logger.Logger) *SchemaHistoryService {
+	return &SchemaHistoryService{
+		store:  store,
+		lg:     lg,
+		latest: make(map[schemaKey]trackedSchema),
+	}
+}
+
+var _ SchemaObserver = (*SchemaHistoryService)(nil)
+
+// ObserveSchema records the row schema of a change event, or the tables of
+// a schema change event, if it differs from the latest version.
+func (s *SchemaHistoryService) ObserveSchema(ctx context.Context, ev kafka.Event) error {
+	change := ev.Change
+	if change.IsSchemaChange() {
+		for _, tc := range change.TableChanges {
+			if err := s.observe(ctx, tc.TableName(), models.SchemaSourceDDL, tc.Schema(), change.DDL, ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if change.Schema == nil {
+		s.schemaless.Do(func() {
+			s.lg.Warn(ctx, "change events carry no schema, so their schema history is not recorded; "+
+				"set value.converter.schemas.enable=true or use the Avro converter",
+				zap.String("topic", ev.Topic), zap.String("table", change.Table()))
+		})
+		return nil
+	}
+	return s.observe(ctx, change.Table(), models.SchemaSourceEvent, *change.Schema, "", ev)
+}
+
+// observe records schema unless it equals the latest version. Every DDL
+// statement is recorded, even one that leaves the columns unchanged.
+func (s *SchemaHistoryService) observe(
+	ctx context.Context,
+	table, source string,
+	schema cdc.TableSchema,
+	ddl string,
+	ev kafka.Event,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := schemaKey{table: table, source: source}
+	prev, ok := s.latest[key]
+	if ok && ddl == "" && prev.schema.Equal(schema) {
+		return nil
+	}
+
+	// Another instance may have recorded versions while it owned the
+	// partition, so the cache is only trusted for unchanged schemas.
+	stored, err := s.store.Latest(ctx, table, source)
+	switch {
+	case errors.Is(err, models.ErrTableSchemaNotFound):
+		ok = false
+	case err != nil:
+		return fmt.Errorf("ObserveSchema.Latest: %w", err)
+	default:
+		prev, ok = trackedFromVersion(stored), true
+		s.latest[key] = prev
+	}
+
+	at := eventTime(ev)
+	if ok && (prev.supersedes(ev, at) || (ddl == "" && prev.schema.Equal(schema))) {
+		return nil
+	}
+
+	v := models.TableSchemaVersion{
+		Table:       table,
+		Source:      source,
+		DDL:         ddl,
+		Columns:     schema.Columns,
+		Changes:     make([]cdc.ColumnChange, 0),
+		Topic:       ev.Topic,
+		Partition:   ev.Partition,
+		Offset:      ev.Offset,
+		MessageTime: at,
+	}
+	if ok {
+		v.Changes = cdc.CompareSchemas(prev.schema, schema)
+		v.Breaking = cdc.Breaking(v.Changes)
+	}
+	if err := s.store.Insert(ctx, v); err != nil {
+		return fmt.Errorf("ObserveSchema.Insert: %w", err)
+	}
+	s.latest[key] = trackedFromVersion(v)
+
+	fields := []zap.Field{
+		zap.String("table", table),
+		zap.String("source", source),
+		zap.Any("changes", v.Changes),
+	}
+	switch {
+	case v.Breaking:
+		s.lg.Warn(ctx, "breaking schema change", fields...)
+	case ok:
+		s.lg.Info(ctx, "schema change", fields...)
+	}
+
+	return nil
+}
+
+func trackedFromVersion(v models.TableSchemaVersion) trackedSchema {
+	return trackedSchema{
+		schema:    cdc.TableSchema{Columns: v.Columns},
+		topic:     v.Topic,
+		partition: v.Partition,
+		offset:    v.Offset,
+		at:        v.MessageTime,
+	}
+}
+
+// eventTime is when the database made the change, or when the message was
+// produced if the event does not say.
+func eventTime(ev kafka.Event) time.Time {
+	switch {
+	case ev.Change.Source.TsMs > 0:
+		return time.UnixMilli(ev.Change.Source.TsMs)
+	case ev.Change.TsMs > 0:
+		return time.UnixMilli(ev.Change.TsMs)
+	}
+	return ev.Timestamp
+}
+
+// History returns the versions of a table, newest first.
+func (s *SchemaHistoryService) History(ctx context.Context, filter models.SchemaHistoryFilter) ([]models.TableSchemaVersion, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSchemaHistoryLimit
+	}
+	if filter.Limit > maxSchemaHistoryLimit {
+		filter.Limit = maxSchemaHistoryLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	return s.store.List(ctx, filter)
+}
+
+// Check compares a proposed schema of a table, e.g. after a planned ALTER
+// TABLE, with the latest version from source. Types must be named the way
+// the versions of that source name them.
+func (s *SchemaHistoryService) Check(ctx context.Context, table, source string, proposed cdc.TableSchema) (models.SchemaCheck, error) {
+	latest, err := s.store.Latest(ctx, table, source)
+	if err != nil {
+		return models.SchemaCheck{}, fmt.Errorf("Check.Latest: %w", err)
+	}
+
+	changes := cdc.CompareSchemas(cdc.TableSchema{Columns: latest.Columns}, proposed)
+	return models.SchemaCheck{
+		Compatible: !cdc.Breaking(changes),
+		VersionID:  latest.ID,
+		Changes:    changes,
+	}, nil
+}
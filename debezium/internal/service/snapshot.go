@@ -0,0 +1,442 @@
+package service
+
+import (
+	"context"
+	debezium_client "debezium_server/pkg/debezium-client"
+	"debezium_server/pkg/signaling"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SnapshotState string
+
+const (
+	SnapshotPending   SnapshotState = "pending"
+	SnapshotRunning   SnapshotState = "running"
+	SnapshotPaused    SnapshotState = "paused"
+	SnapshotCompleted SnapshotState = "completed"
+	SnapshotStopped   SnapshotState = "stopped"
+	SnapshotFailed    SnapshotState = "failed"
+
+	connectorStateFailed = "FAILED"
+
+	// incrementalSnapshotOffset is the offset key in which Debezium keeps
+	// the collections of a running incremental snapshot.
+	incrementalSnapshotOffset = "incremental_snapshot_collections"
+
+	// Snapshots are tracked for finishedRetention after they completed or
+	// were stopped, and for no longer than trackedRetention in any case.
+	finishedRetention = time.Hour
+	trackedRetention  = 24 * time.Hour
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+)
+
+type Signaler interface {
+	DataCollection() string
+	EnsureTable(ctx context.Context) error
+	ExecuteSnapshot(ctx context.Context, data signaling.SnapshotData) (signaling.Signal, error)
+	StopSnapshot(ctx context.Context, data signaling.SnapshotData) (signaling.Signal, error)
+	PauseSnapshot(ctx context.Context) (signaling.Signal, error)
+	ResumeSnapshot(ctx context.Context) (signaling.Signal, error)
+	Get(ctx context.Context, id string) (signaling.Signal, error)
+	CountWindows(ctx context.Context) (int, int, error)
+}
+
+// SignalChannel returns the Signaler of a connector from its config.
+type SignalChannel interface {
+	For(config map[string]any) (*signaling.Signaler, error)
+}
+
+type ConnectorStatusGetter interface {
+	GetConnectorStatus(ctx context.Context, name string) (debezium_client.ConnectorStatus, error)
+}
+
+type SnapshotConnectors interface {
+	ConnectorStatusGetter
+	GetConnector(ctx context.Context, name string) (debezium_client.GetConnectorResponse, error)
+	GetConnectorOffsets(ctx context.Context, name string) (debezium_client.ConnectorOffsets, error)
+}
+
+// SnapshotProgress combines the signal, the chunk watermarks the connector
+// wrote since the signal and the connector status.
+type SnapshotProgress struct {
+	SignalID        string                      `json:"signal_id"`
+	Connector       string                      `json:"connector"`
+	DataCollections []string                    `json:"data_collections"`
+	Type            signaling.SnapshotType      `json:"type"`
+	State           SnapshotState               `json:"state"`
+	RequestedAt     time.Time                   `json:"requested_at,omitzero"`
+	ChunksOpened    int                         `json:"chunks_opened"`
+	ChunksClosed    int                         `json:"chunks_closed"`
+	ConnectorState  string                      `json:"connector_state"`
+	Tasks           []debezium_client.TaskState `json:"tasks"`
+}
+
+// trackedSnapshot is keyed by its signal ID. Pause, resume and stop signals
+// reach every connector reading the same signal table, so they update the
+// snapshots sent through that table rather than those of one connector.
+type trackedSnapshot struct {
+	connector   string
+	signals     Signaler
+	signalID    string
+	data        signaling.SnapshotData
+	state       SnapshotState
+	requestedAt time.Time
+	finishedAt  time.Time
+	baseOpened  int
+	baseClosed  int
+}
+
+type SnapshotService struct {
+	channel SignalChannel
+	connect SnapshotConnectors
+
+	mu      sync.Mutex
+	ready   map[string]bool
+	tracked map[string]*trackedSnapshot
+}
+
+func NewSnapshotService(channel SignalChannel, connect SnapshotConnectors) *SnapshotService {
+	return &SnapshotService{
+		channel: channel,
+		connect: connect,
+		ready:   make(map[string]bool),
+		tracked: make(map[string]*trackedSnapshot),
+	}
+}
+
+// Execute sends an execute-snapshot signal for the connector and starts tracking it.
+func (s *SnapshotService) Execute(
+	ctx context.Context,
+	connector string,
+	data signaling.SnapshotData,
+) (SnapshotProgress, error) {
+	signals, err := s.signalsFor(ctx, connector)
+	if err != nil {
+		return SnapshotProgress{}, err
+	}
+
+	opened, closed, err := signals.CountWindows(ctx)
+	if err != nil {
+		return SnapshotProgress{}, err
+	}
+
+	signal, err := signals.ExecuteSnapshot(ctx, data)
+	if err != nil {
+		return SnapshotProgress{}, err
+	}
+
+	if data.Type == "" {
+		data.Type = signaling.SnapshotIncremental
+	}
+
+	s.mu.Lock()
+	s.prune(time.Now())
+	s.tracked[signal.ID] = &trackedSnapshot{
+		connector:   connector,
+		signals:     signals,
+		signalID:    signal.ID,
+		data:        data,
+		state:       SnapshotPending,
+		requestedAt: time.Now().UTC(),
+		baseOpened:  opened,
+		baseClosed:  closed,
+	}
+	s.mu.Unlock()
+
+	return s.Progress(ctx, connector, signal.ID)
+}
+
+// Stop sends a stop-snapshot signal. An empty collection list stops every
+// running snapshot signalled through the connector's signal table.
+func (s *SnapshotService) Stop(ctx context.Context, connector string, data signaling.SnapshotData) error {
+	signals, err := s.signalsFor(ctx, connector)
+	if err != nil {
+		return err
+	}
+	if _, err := signals.StopSnapshot(ctx, data); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tracked {
+		if t.signals.DataCollection() != signals.DataCollection() {
+			continue
+		}
+		if t.state == SnapshotCompleted {
+			continue
+		}
+		if len(data.DataCollections) == 0 || overlaps(t.data.DataCollections, data.DataCollections) {
+			t.state = SnapshotStopped
+			t.finishedAt = time.Now()
+		}
+	}
+
+	return nil
+}
+
+func (s *SnapshotService) Pause(ctx context.Context, connector string) error {
+	signals, err := s.signalsFor(ctx, connector)
+	if err != nil {
+		return err
+	}
+	if _, err := signals.PauseSnapshot(ctx); err != nil {
+		return err
+	}
+
+	s.setState(signals, SnapshotPaused, SnapshotPending, SnapshotRunning)
+	return nil
+}
+
+func (s *SnapshotService) Resume(ctx context.Context, connector string) error {
+	signals, err := s.signalsFor(ctx, connector)
+	if err != nil {
+		return err
+	}
+	if _, err := signals.ResumeSnapshot(ctx); err != nil {
+		return err
+	}
+
+	s.setState(signals, SnapshotRunning, SnapshotPaused)
+	return nil
+}
+
+// Progress reports the state of a snapshot. Snapshots requested before a
+// restart are rebuilt from the signal row and count every chunk in the table.
+// A running incremental snapshot has completed once its chunks are closed
+// and the connector offsets no longer list its collections.
+func (s *SnapshotService) Progress(ctx context.Context, connector, signalID string) (SnapshotProgress, error) {
+	s.mu.Lock()
+	t, ok := s.tracked[signalID]
+	var snapshot trackedSnapshot
+	if ok {
+		snapshot = *t
+	}
+	s.mu.Unlock()
+
+	if !ok || snapshot.connector != connector {
+		restored, err := s.restore(ctx, connector, signalID)
+		if err != nil {
+			return SnapshotProgress{}, err
+		}
+		snapshot = restored
+	}
+
+	opened, closed, err := snapshot.signals.CountWindows(ctx)
+	if err != nil {
+		return SnapshotProgress{}, err
+	}
+
+	status, err := s.connect.GetConnectorStatus(ctx, connector)
+	if err != nil {
+		return SnapshotProgress{}, err
+	}
+
+	progress := SnapshotProgress{
+		SignalID:        snapshot.signalID,
+		Connector:       connector,
+		DataCollections: snapshot.data.DataCollections,
+		Type:            snapshot.data.Type,
+		State:           snapshot.state,
+		RequestedAt:     snapshot.requestedAt,
+		ChunksOpened:    max(opened-snapshot.baseOpened, 0),
+		ChunksClosed:    max(closed-snapshot.baseClosed, 0),
+		ConnectorState:  status.Connector.State,
+		Tasks:           status.Tasks,
+	}
+
+	switch {
+	case progress.State == SnapshotCompleted:
+		return progress, nil
+	case failed(status):
+		progress.State = SnapshotFailed
+		return progress, nil
+	case progress.State == SnapshotPending && progress.ChunksOpened > 0:
+		progress.State = SnapshotRunning
+		s.setSignalState(signalID, SnapshotRunning, SnapshotPending)
+	}
+
+	if progress.State == SnapshotRunning && progress.Type == signaling.SnapshotIncremental &&
+		progress.ChunksOpened == progress.ChunksClosed {
+		if s.completed(ctx, connector, progress.DataCollections) {
+			progress.State = SnapshotCompleted
+			s.setSignalState(signalID, SnapshotCompleted, SnapshotRunning)
+		}
+	}
+
+	return progress, nil
+}
+
+// completed reports whether the connector offsets show no incremental
+// snapshot of the collections in progress. Without the offsets, e.g. from
+// Kafka Connect before 3.5, the snapshot is taken to be still running.
+func (s *SnapshotService) completed(ctx context.Context, connector string, collections []string) bool {
+	offsets, err := s.connect.GetConnectorOffsets(ctx, connector)
+	if err != nil {
+		return false
+	}
+	for _, o := range offsets.Offsets {
+		running, _ := o.Offset[incrementalSnapshotOffset].(string)
+		for _, c := range collections {
+			if strings.Contains(running, `"`+c+`"`) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// List returns the snapshots tracked for the connector, newest first.
+func (s *SnapshotService) List(ctx context.Context, connector string) ([]SnapshotProgress, error) {
+	s.mu.Lock()
+	s.prune(time.Now())
+	ids := make([]string, 0, len(s.tracked))
+	requested := make(map[string]time.Time, len(s.tracked))
+	for id, t := range s.tracked {
+		if t.connector == connector {
+			ids = append(ids, id)
+			requested[id] = t.requestedAt
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(ids, func(i, j int) bool {
+		return requested[ids[i]].After(requested[ids[j]])
+	})
+
+	result := make([]SnapshotProgress, 0, len(ids))
+	for _, id := range ids {
+		progress, err := s.Progress(ctx, connector, id)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, progress)
+	}
+
+	return result, nil
+}
+
+func (s *SnapshotService) restore(ctx context.Context, connector, signalID string) (trackedSnapshot, error) {
+	signals, err := s.signalsFor(ctx, connector)
+	if err != nil {
+		return trackedSnapshot{}, err
+	}
+
+	signal, err := signals.Get(ctx, signalID)
+	if errors.Is(err, signaling.ErrSignalNotFound) {
+		return trackedSnapshot{}, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return trackedSnapshot{}, err
+	}
+	if signal.Type != signaling.TypeExecuteSnapshot {
+		return trackedSnapshot{}, ErrSnapshotNotFound
+	}
+
+	var data signaling.SnapshotData
+	if err := json.Unmarshal([]byte(signal.Data), &data); err != nil {
+		return trackedSnapshot{}, fmt.Errorf("restore.Unmarshal: %w", err)
+	}
+
+	return trackedSnapshot{
+		connector: connector,
+		signals:   signals,
+		signalID:  signal.ID,
+		data:      data,
+		state:     SnapshotPending,
+	}, nil
+}
+
+// signalsFor returns the Signaler of the signal table the connector reads,
+// creating the table on first use.
+func (s *SnapshotService) signalsFor(ctx context.Context, connector string) (Signaler, error) {
+	info, err := s.connect.GetConnector(ctx, connector)
+	if err != nil {
+		return nil, err
+	}
+	signals, err := s.channel.For(info.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	ready := s.ready[signals.DataCollection()]
+	s.mu.Unlock()
+	if ready {
+		return signals, nil
+	}
+
+	if err := signals.EnsureTable(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.ready[signals.DataCollection()] = true
+	s.mu.Unlock()
+
+	return signals, nil
+}
+
+// prune forgets the snapshots that finished more than finishedRetention
+// ago or were requested more than trackedRetention ago. s.mu must be held.
+func (s *SnapshotService) prune(now time.Time) {
+	for id, t := range s.tracked {
+		finished := !t.finishedAt.IsZero() && now.Sub(t.finishedAt) > finishedRetention
+		if finished || now.Sub(t.requestedAt) > trackedRetention {
+			delete(s.tracked, id)
+		}
+	}
+}
+
+func (s *SnapshotService) setState(signals Signaler, to SnapshotState, from ...SnapshotState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tracked {
+		if t.signals.DataCollection() == signals.DataCollection() && slices.Contains(from, t.state) {
+			t.state = to
+		}
+	}
+}
+
+func (s *SnapshotService) setSignalState(signalID string, to, from SnapshotState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.tracked[signalID]; ok && t.state == from {
+		t.state = to
+		if to == SnapshotCompleted {
+			t.finishedAt = time.Now()
+		}
+	}
+}
+
+func failed(status debezium_client.ConnectorStatus) bool {
+	if status.Connector.State == connectorStateFailed {
+		return true
+	}
+	for _, task := range status.Tasks {
+		if task.State == connectorStateFailed {
+			return true
+		}
+	}
+	return false
+}
+
+func overlaps(a, b []string) bool {
+	for _, v := range a {
+		if slices.Contains(b, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	debezium_client "debezium_server/pkg/debezium-client"
+	"errors"
+	"testing"
+	"time"
+)
+
+// offsetConnectors serves the offsets of every connector.
+type offsetConnectors struct {
+	offsets debezium_client.ConnectorOffsets
+	err     error
+}
+
+func (c offsetConnectors) GetConnectorStatus(context.Context, string) (debezium_client.ConnectorStatus, error) {
+	return debezium_client.ConnectorStatus{}, nil
+}
+
+func (c offsetConnectors) GetConnector(context.Context, string) (debezium_client.GetConnectorResponse, error) {
+	return debezium_client.GetConnectorResponse{}, nil
+}
+
+func (c offsetConnectors) GetConnectorOffsets(context.Context, string) (debezium_client.ConnectorOffsets, error) {
+	return c.offsets, c.err
+}
+
+func TestSnapshotCompleted(t *testing.T) {
+	offset := func(values map[string]any) debezium_client.ConnectorOffsets {
+		return debezium_client.ConnectorOffsets{Offsets: []debezium_client.ConnectorOffset{
+			{Partition: map[string]any{"server": "postgres"}, Offset: values},
+		}}
+	}
+	tests := []struct {
+		name    string
+		connect offsetConnectors
+		want    bool
+	}{
+		{
+			name: "collection still snapshotted",
+			connect: offsetConnectors{offsets: offset(map[string]any{
+				"lsn": 1234,
+				incrementalSnapshotOffset: `[{"incremental_snapshot_collections_id":"inventory.orders",` +
+					`"incremental_snapshot_collections_additional_condition":null}]`,
+			})},
+			want: false,
+		},
+		{
+			name: "another collection snapshotted",
+			connect: offsetConnectors{offsets: offset(map[string]any{
+				incrementalSnapshotOffset: `[{"incremental_snapshot_collections_id":"inventory.orders_archive"}]`,
+			})},
+			want: true,
+		},
+		{
+			name:    "no snapshot in the offsets",
+			connect: offsetConnectors{offsets: offset(map[string]any{"lsn": 1234})},
+			want:    true,
+		},
+		{
+			name:    "offsets unavailable",
+			connect: offsetConnectors{err: errors.New("404 page not found")},
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSnapshotService(nil, tt.connect)
+			if got := s.completed(context.Background(), "inventory", []string{"inventory.orders"}); got != tt.want {
+				t.Errorf("completed = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotPrune(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	s := NewSnapshotService(nil, offsetConnectors{})
+	s.tracked = map[string]*trackedSnapshot{
+		"running":          {state: SnapshotRunning, requestedAt: now.Add(-time.Hour)},
+		"just-completed":   {state: SnapshotCompleted, requestedAt: now.Add(-2 * time.Hour), finishedAt: now.Add(-time.Minute)},
+		"long-completed":   {state: SnapshotCompleted, requestedAt: now.Add(-3 * time.Hour), finishedAt: now.Add(-2 * time.Hour)},
+		"long-stopped":     {state: SnapshotStopped, requestedAt: now.Add(-3 * time.Hour), finishedAt: now.Add(-2 * time.Hour)},
+		"running-too-long": {state: SnapshotRunning, requestedAt: now.Add(-25 * time.Hour)},
+	}
+
+	s.prune(now)
+
+	for _, id := range []string{"running", "just-completed"} {
+		if _, ok := s.tracked[id]; !ok {
+			t.Errorf("snapshot %s was pruned", id)
+		}
+	}
+	if len(s.tracked) != 2 {
+		t.Errorf("%d snapshots are tracked, want 2", len(s.tracked))
+	}
+}
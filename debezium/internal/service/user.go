@@ -7,6 +7,7 @@ import (
 
 type UserRepository interface {
 	Select(ctx context.Context, offset, limit int) ([]models.User, error)
+	Create(ctx context.Context, user models.User) (models.User, error)
 }
 
 type UserService struct {
@@ -22,3 +23,7 @@ func NewUserService(repo UserRepository) *UserService {
 func (s *UserService) GetUsers(ctx context.Context, offset, limit int) ([]models.User, error) {
 	return s.Repository.Select(ctx, offset, limit)
 }
+
+func (s *UserService) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	return s.Repository.Create(ctx, user)
+}
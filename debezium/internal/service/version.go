@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"debezium_server/pkg/logger"
+	"debezium_server/pkg/redact"
+	"fmt"
+)
+
+const (
+	defaultVersionLimit = 50
+	maxVersionLimit     = 500
+)
+
+type VersionStore interface {
+	Insert(ctx context.Context, v models.ConnectorVersion) (models.ConnectorVersion, error)
+	List(ctx context.Context, filter models.ConnectorVersionFilter) ([]models.ConnectorVersion, error)
+	Get(ctx context.Context, cluster, connector string, version int) (models.ConnectorVersion, error)
+}
+
+// versioner records every connector config the service applies. The author
+// and request ID are taken from the request context.
+type versioner struct {
+	cluster  string
+	store    VersionStore
+	redactor *redact.Redactor
+}
+
+// record stores config, with secrets masked, as the next version of the
+// connector.
+func (v versioner) record(ctx context.Context, connector string, config map[string]any, comment string) error {
+	masked := v.redactor.Map(config)
+	stored := make(map[string]string, len(masked))
+	for k, val := range masked {
+		stored[k] = fmt.Sprint(val)
+	}
+
+	author, _ := principalFromContext(ctx)
+	entry := models.ConnectorVersion{
+		Cluster:   v.cluster,
+		Connector: connector,
+		Config:    stored,
+		Author:    author,
+		Comment:   comment,
+		RequestID: logger.RequestIDFromContext(ctx),
+	}
+
+	// Like the audit entry, the version must be written even if the request
+	// was cancelled after the config was applied.
+	if _, err := v.store.Insert(context.WithoutCancel(ctx), entry); err != nil {
+		return fmt.Errorf("version history: %w", err)
+	}
+
+	return nil
+}
+
+func (v versioner) list(ctx context.Context, connector string, limit, offset int) ([]models.ConnectorVersion, error) {
+	if limit <= 0 {
+		limit = defaultVersionLimit
+	}
+	if limit > maxVersionLimit {
+		limit = maxVersionLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return v.store.List(ctx, models.ConnectorVersionFilter{
+		Cluster:   v.cluster,
+		Connector: connector,
+		Limit:     limit,
+		Offset:    offset,
+	})
+}
+
+func (v versioner) get(ctx context.Context, connector string, version int) (models.ConnectorVersion, error) {
+	return v.store.Get(ctx, v.cluster, connector, version)
+}
@@ -0,0 +1,272 @@
+package stream
+
+import (
+	"cmp"
+	"debezium_server/pkg/cdc"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+type SlowConsumerPolicy string
+
+const (
+	// PolicyDisconnect closes a subscriber whose buffer is full.
+	PolicyDisconnect SlowConsumerPolicy = "disconnect"
+	// PolicyDropOldest discards the oldest buffered event to make room for the new one.
+	PolicyDropOldest SlowConsumerPolicy = "drop-oldest"
+)
+
+var (
+	ErrHubClosed         = errors.New("stream hub is closed")
+	ErrSubscriberClosed  = errors.New("subscriber is closed")
+	ErrSlowConsumer      = errors.New("subscriber buffer overflow")
+	ErrUnknownPolicy     = errors.New("unknown slow consumer policy")
+	ErrInvalidBufferSize = errors.New("buffer size must be positive")
+	ErrInvalidHeartbeat  = errors.New("heartbeat interval must be positive")
+)
+
+type Config struct {
+	BufferSize        int                `yaml:"buffer_size"        env:"STREAM_BUFFER_SIZE"          env-default:"256"`
+	HistorySize       int                `yaml:"history_size"       env:"STREAM_HISTORY_SIZE"         env-default:"1024"`
+	SlowConsumer      SlowConsumerPolicy `yaml:"slow_consumer"      env:"STREAM_SLOW_CONSUMER_POLICY" env-default:"disconnect"`
+	HeartbeatInterval time.Duration      `yaml:"heartbeat_interval" env:"STREAM_HEARTBEAT_INTERVAL"   env-default:"15s"`
+	AllowedOrigins    []string           `yaml:"allowed_origins"    env:"STREAM_ALLOWED_ORIGINS"      env-separator:","`
+}
+
+// Event is a change event stamped with the hub sequence number used for resuming.
+type Event struct {
+	ID    uint64          `json:"id"`
+	Table string          `json:"table"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Hub fans change events out to stream subscribers. Events are published by
+// the Kafka consumer's change feed, so without kafka.enabled the streams
+// only carry heartbeats.
+type Hub struct {
+	mu      sync.Mutex
+	cfg     Config
+	seq     uint64
+	closed  bool
+	history map[string][]Event
+	subs    map[string]map[*Subscriber]struct{}
+}
+
+type Subscriber struct {
+	ch     chan Event
+	done   chan struct{}
+	once   sync.Once
+	err    error
+	tables map[string]struct{}
+}
+
+func NewHub(cfg Config) (*Hub, error) {
+	if cfg.BufferSize <= 0 {
+		return nil, ErrInvalidBufferSize
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		return nil, ErrInvalidHeartbeat
+	}
+	switch cfg.SlowConsumer {
+	case PolicyDisconnect, PolicyDropOldest:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPolicy, cfg.SlowConsumer)
+	}
+
+	return &Hub{
+		cfg:     cfg,
+		history: make(map[string][]Event),
+		subs:    make(map[string]map[*Subscriber]struct{}),
+	}, nil
+}
+
+func (h *Hub) HeartbeatInterval() time.Duration {
+	return h.cfg.HeartbeatInterval
+}
+
+func (h *Hub) AllowedOrigins() []string {
+	return h.cfg.AllowedOrigins
+}
+
+// Publish stamps the event with the next sequence number, keeps it for replay
+// and fans it out to every subscriber of its table.
+func (h *Hub) Publish(event cdc.ChangeEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("Publish.Marshal: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return ErrHubClosed
+	}
+
+	h.seq++
+	table := event.Table()
+	ev := Event{ID: h.seq, Table: table, Data: data}
+
+	if h.cfg.HistorySize > 0 {
+		hist := append(h.history[table], ev)
+		if len(hist) > h.cfg.HistorySize {
+			hist = hist[len(hist)-h.cfg.HistorySize:]
+		}
+		h.history[table] = hist
+	}
+
+	for sub := range h.subs[table] {
+		h.deliver(sub, ev)
+	}
+
+	return nil
+}
+
+func (h *Hub) NewSubscriber() *Subscriber {
+	return &Subscriber{
+		ch:     make(chan Event, h.cfg.BufferSize),
+		done:   make(chan struct{}),
+		tables: make(map[string]struct{}),
+	}
+}
+
+// Subscribe attaches the subscriber to a table and returns the history
+// newer than lastEventID, which the caller sends before any event from
+// Events. The replay bypasses the subscriber's buffer, so resuming after a
+// long gap does not count as a slow consumer. A zero lastEventID skips the
+// replay.
+func (h *Hub) Subscribe(sub *Subscriber, table string, lastEventID uint64) ([]Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+	if sub.isClosed() {
+		return nil, ErrSubscriberClosed
+	}
+
+	var replay []Event
+	if lastEventID > 0 {
+		hist := h.history[table]
+		i, _ := slices.BinarySearchFunc(hist, lastEventID+1, func(ev Event, id uint64) int {
+			return cmp.Compare(ev.ID, id)
+		})
+		replay = slices.Clone(hist[i:])
+	}
+
+	if h.subs[table] == nil {
+		h.subs[table] = make(map[*Subscriber]struct{})
+	}
+	h.subs[table][sub] = struct{}{}
+	sub.tables[table] = struct{}{}
+
+	return replay, nil
+}
+
+func (h *Hub) Unsubscribe(sub *Subscriber, table string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.detach(sub, table)
+}
+
+// Remove detaches the subscriber from every table and closes it.
+func (h *Hub) Remove(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for table := range sub.tables {
+		h.detach(sub, table)
+	}
+	sub.close(nil)
+}
+
+// Close disconnects every subscriber so that long-lived streams return
+// before the HTTP server shuts down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for table, subs := range h.subs {
+		for sub := range subs {
+			sub.close(ErrHubClosed)
+		}
+		delete(h.subs, table)
+	}
+}
+
+func (h *Hub) deliver(sub *Subscriber, ev Event) {
+	select {
+	case sub.ch <- ev:
+		return
+	default:
+	}
+
+	if h.cfg.SlowConsumer == PolicyDropOldest {
+		select {
+		case <-sub.ch:
+		default:
+		}
+		select {
+		case sub.ch <- ev:
+			return
+		default:
+		}
+	}
+
+	for table := range sub.tables {
+		h.detach(sub, table)
+	}
+	sub.close(ErrSlowConsumer)
+}
+
+func (h *Hub) detach(sub *Subscriber, table string) {
+	delete(sub.tables, table)
+	if subs, ok := h.subs[table]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.subs, table)
+		}
+	}
+}
+
+func (s *Subscriber) Events() <-chan Event {
+	return s.ch
+}
+
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err reports why the subscriber was closed. It is nil while the subscriber
+// is open or after a regular Remove.
+func (s *Subscriber) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+func (s *Subscriber) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
+
+func (s *Subscriber) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
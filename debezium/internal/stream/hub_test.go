@@ -0,0 +1,271 @@
+package stream
+
+import (
+	"debezium_server/pkg/cdc"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestHub(t *testing.T, cfg Config) *Hub {
+	t.Helper()
+	if cfg.BufferSize == 0 {
+		cfg.BufferSize = 16
+	}
+	if cfg.SlowConsumer == "" {
+		cfg.SlowConsumer = PolicyDisconnect
+	}
+	cfg.HeartbeatInterval = time.Second
+
+	h, err := NewHub(cfg)
+	if err != nil {
+		t.Fatalf("NewHub: %v", err)
+	}
+	return h
+}
+
+func publish(t *testing.T, h *Hub, tables ...string) {
+	t.Helper()
+	for _, table := range tables {
+		schema, name, _ := strings.Cut(table, ".")
+		if err := h.Publish(cdc.ChangeEvent{Op: "c", Source: cdc.Source{Schema: schema, Table: name}}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+}
+
+func ids(events []Event) []uint64 {
+	out := make([]uint64, 0, len(events))
+	for _, ev := range events {
+		out = append(out, ev.ID)
+	}
+	return out
+}
+
+// buffered drains the events waiting in the subscriber's buffer.
+func buffered(sub *Subscriber) []uint64 {
+	var out []uint64
+	for {
+		select {
+		case ev := <-sub.Events():
+			out = append(out, ev.ID)
+		default:
+			return out
+		}
+	}
+}
+
+func TestNewHub(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		err  error
+	}{
+		{name: "valid", cfg: Config{BufferSize: 1, HeartbeatInterval: time.Second, SlowConsumer: PolicyDropOldest}},
+		{name: "no buffer", cfg: Config{HeartbeatInterval: time.Second, SlowConsumer: PolicyDisconnect}, err: ErrInvalidBufferSize},
+		{name: "no heartbeat", cfg: Config{BufferSize: 1, SlowConsumer: PolicyDisconnect}, err: ErrInvalidHeartbeat},
+		{name: "unknown policy", cfg: Config{BufferSize: 1, HeartbeatInterval: time.Second, SlowConsumer: "block"}, err: ErrUnknownPolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHub(tt.cfg); !errors.Is(err, tt.err) {
+				t.Errorf("NewHub returned %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	h := newTestHub(t, Config{HistorySize: 3})
+	// IDs 1, 3, 4, 5 and 6 are orders, 2 is customers. Only the last three
+	// orders are kept.
+	publish(t, h, "inventory.orders", "inventory.customers",
+		"inventory.orders", "inventory.orders", "inventory.orders", "inventory.orders")
+
+	tests := []struct {
+		name   string
+		table  string
+		lastID uint64
+		want   []uint64
+	}{
+		{name: "no last ID", table: "inventory.orders", want: []uint64{}},
+		{name: "within the history", table: "inventory.orders", lastID: 4, want: []uint64{5, 6}},
+		{name: "before the history", table: "inventory.orders", lastID: 1, want: []uint64{4, 5, 6}},
+		{name: "ID of another table", table: "inventory.orders", lastID: 2, want: []uint64{4, 5, 6}},
+		{name: "up to date", table: "inventory.orders", lastID: 6, want: []uint64{}},
+		{name: "ID from the future", table: "inventory.orders", lastID: 100, want: []uint64{}},
+		{name: "other table", table: "inventory.customers", lastID: 1, want: []uint64{2}},
+		{name: "table without events", table: "inventory.items", lastID: 1, want: []uint64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := h.NewSubscriber()
+			defer h.Remove(sub)
+
+			replay, err := h.Subscribe(sub, tt.table, tt.lastID)
+			if err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			if got := ids(replay); !slices.Equal(got, tt.want) {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReplayThenLive checks that events published after Subscribe come
+// from Events and not from the replay, and that a replay larger than the
+// buffer does not count as a slow consumer.
+func TestReplayThenLive(t *testing.T) {
+	h := newTestHub(t, Config{BufferSize: 1, HistorySize: 10})
+	publish(t, h, "inventory.orders", "inventory.orders", "inventory.orders")
+
+	sub := h.NewSubscriber()
+	defer h.Remove(sub)
+	replay, err := h.Subscribe(sub, "inventory.orders", 1)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	publish(t, h, "inventory.orders")
+
+	if got := ids(replay); !slices.Equal(got, []uint64{2, 3}) {
+		t.Errorf("replayed %v, want [2 3]", got)
+	}
+	if got := buffered(sub); !slices.Equal(got, []uint64{4}) {
+		t.Errorf("got live events %v, want [4]", got)
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("the subscriber was closed: %v", err)
+	}
+}
+
+func TestNoHistory(t *testing.T) {
+	h := newTestHub(t, Config{})
+	publish(t, h, "inventory.orders")
+
+	sub := h.NewSubscriber()
+	defer h.Remove(sub)
+	replay, err := h.Subscribe(sub, "inventory.orders", 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if len(replay) != 0 {
+		t.Errorf("replayed %v without a history", ids(replay))
+	}
+}
+
+func TestSlowConsumer(t *testing.T) {
+	tests := []struct {
+		policy SlowConsumerPolicy
+		events []uint64
+		err    error
+	}{
+		// The buffer keeps the newest events.
+		{policy: PolicyDropOldest, events: []uint64{4, 5}},
+		// The subscriber is closed at the third event and gets no more.
+		{policy: PolicyDisconnect, events: []uint64{1, 2}, err: ErrSlowConsumer},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			h := newTestHub(t, Config{BufferSize: 2, SlowConsumer: tt.policy})
+
+			slow := h.NewSubscriber()
+			defer h.Remove(slow)
+			fast := h.NewSubscriber()
+			defer h.Remove(fast)
+			for _, table := range []string{"inventory.orders", "inventory.customers"} {
+				if _, err := h.Subscribe(slow, table, 0); err != nil {
+					t.Fatalf("Subscribe: %v", err)
+				}
+			}
+			if _, err := h.Subscribe(fast, "inventory.orders", 0); err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+
+			var fastGot []uint64
+			for range 5 {
+				publish(t, h, "inventory.orders")
+				fastGot = append(fastGot, buffered(fast)...)
+			}
+
+			if got := buffered(slow); !slices.Equal(got, tt.events) {
+				t.Errorf("the slow subscriber got %v, want %v", got, tt.events)
+			}
+			if err := slow.Err(); !errors.Is(err, tt.err) {
+				t.Errorf("the slow subscriber has error %v, want %v", err, tt.err)
+			}
+			if !slices.Equal(fastGot, []uint64{1, 2, 3, 4, 5}) {
+				t.Errorf("the other subscriber got %v, want every event", fastGot)
+			}
+
+			// A disconnected subscriber is detached from all its tables.
+			publish(t, h, "inventory.customers")
+			got := buffered(slow)
+			if tt.err != nil && len(got) != 0 {
+				t.Errorf("the disconnected subscriber got %v", got)
+			}
+			if tt.err == nil && !slices.Equal(got, []uint64{6}) {
+				t.Errorf("the slow subscriber got %v, want [6]", got)
+			}
+
+			if _, err := h.Subscribe(slow, "inventory.items", 0); tt.err != nil && !errors.Is(err, ErrSubscriberClosed) {
+				t.Errorf("Subscribe after the disconnect returned %v, want %v", err, ErrSubscriberClosed)
+			}
+		})
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	h := newTestHub(t, Config{})
+	sub := h.NewSubscriber()
+	defer h.Remove(sub)
+	for _, table := range []string{"inventory.orders", "inventory.customers"} {
+		if _, err := h.Subscribe(sub, table, 0); err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+	}
+
+	h.Unsubscribe(sub, "inventory.orders")
+	publish(t, h, "inventory.orders", "inventory.customers")
+
+	if got := buffered(sub); !slices.Equal(got, []uint64{2}) {
+		t.Errorf("got %v, want only the customers event 2", got)
+	}
+}
+
+func TestClose(t *testing.T) {
+	h := newTestHub(t, Config{})
+	removed := h.NewSubscriber()
+	open := h.NewSubscriber()
+	for _, sub := range []*Subscriber{removed, open} {
+		if _, err := h.Subscribe(sub, "inventory.orders", 0); err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+	}
+
+	h.Remove(removed)
+	select {
+	case <-removed.Done():
+	default:
+		t.Error("Remove did not close the subscriber")
+	}
+	if err := removed.Err(); err != nil {
+		t.Errorf("a removed subscriber has error %v", err)
+	}
+
+	h.Close()
+	if err := open.Err(); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("the subscriber has error %v after Close, want %v", err, ErrHubClosed)
+	}
+	if err := h.Publish(cdc.ChangeEvent{}); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("Publish returned %v after Close, want %v", err, ErrHubClosed)
+	}
+	if _, err := h.Subscribe(h.NewSubscriber(), "inventory.orders", 0); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("Subscribe returned %v after Close, want %v", err, ErrHubClosed)
+	}
+}
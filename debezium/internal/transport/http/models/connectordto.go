@@ -0,0 +1,42 @@
+package models
+
+type ConnectorTemplateDTO struct {
+	Name    string            `json:"name"`
+	Config  map[string]string `json:"config"`
+	Vars    map[string]string `json:"vars"`
+	Outbox  *OutboxRouterDTO  `json:"outbox"`
+	Comment string            `json:"comment"`
+}
+
+type ConnectorConfigDTO struct {
+	Config  map[string]string `json:"config"`
+	Vars    map[string]string `json:"vars"`
+	Outbox  *OutboxRouterDTO  `json:"outbox"`
+	Comment string            `json:"comment"`
+}
+
+// OutboxRouterDTO adds the outbox EventRouter transform to the connector
+// config. Empty fields take the outbox package defaults.
+type OutboxRouterDTO struct {
+	TransformName     string `json:"transform_name"`
+	Schema            string `json:"schema"`
+	Table             string `json:"table"`
+	TopicTemplate     string `json:"topic_template"`
+	ExpandJSONPayload bool   `json:"expand_json_payload"`
+}
+
+type RollbackDTO struct {
+	Comment string `json:"comment"`
+}
+
+type CopyConnectorDTO struct {
+	Target     string            `json:"target"`
+	TargetName string            `json:"target_name"`
+	Offsets    bool              `json:"offsets"`
+	Overrides  map[string]string `json:"overrides"`
+}
+
+type LoggerLevelDTO struct {
+	Level string `json:"level"`
+	Scope string `json:"scope"`
+}
@@ -0,0 +1,12 @@
+package models
+
+type AdditionalConditionDTO struct {
+	DataCollection string `json:"data_collection"`
+	Filter         string `json:"filter"`
+}
+
+type SnapshotRequestDTO struct {
+	DataCollections      []string                 `json:"data_collections"`
+	Type                 string                   `json:"type"`
+	AdditionalConditions []AdditionalConditionDTO `json:"additional_conditions"`
+}
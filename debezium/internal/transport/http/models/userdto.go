@@ -12,3 +12,10 @@ type UserDTO struct {
 	Name     string `json:"name"`
 	LastName string `json:"last_name"`
 }
+
+type CreateUserDTO struct {
+	Email    string   `json:"email"`
+	Name     string   `json:"name"`
+	LastName string   `json:"last_name"`
+	Role     []string `json:"role"`
+}
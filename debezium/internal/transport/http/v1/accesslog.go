@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"bufio"
+	"context"
+	"debezium_server/internal/auth"
+	"debezium_server/pkg/logger"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type requestInfoKey struct{}
+
+// requestInfo is filled in by inner handlers so the access log, which runs
+// outside the mux and auth middleware, can report the route and principal.
+type requestInfo struct {
+	route     string
+	principal string
+}
+
+func requestInfoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+// AccessLogMiddleware logs one entry per request with its method, route,
+// status, response size, latency and principal.
+func AccessLogMiddleware(lg logger.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			info := &requestInfo{}
+			rec := &statusRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)))
+
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			fields := []zap.Field{
+				zap.String("method", r.Method),
+				zap.String("route", info.route),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", status),
+				zap.Int64("bytes", rec.bytes),
+				zap.Duration("latency", time.Since(start)),
+				zap.String("principal", info.principal),
+				zap.String("remote_addr", r.RemoteAddr),
+			}
+			if rec.hijacked {
+				fields = append(fields, zap.Bool("hijacked", true))
+			}
+
+			if status >= http.StatusInternalServerError {
+				lg.Error(r.Context(), "http request", fields...)
+				return
+			}
+			lg.Info(r.Context(), "http request", fields...)
+		})
+	}
+}
+
+// statusRecorder captures the status and body size. It supports flushing
+// for SSE, hijacking for WebSocket upgrades and http.ResponseController.
+type statusRecorder struct {
+	http.ResponseWriter
+	status   int
+	bytes    int64
+	hijacked bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.hijacked = true
+		if r.status == 0 {
+			r.status = http.StatusSwitchingProtocols
+		}
+	}
+	return conn, rw, err
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+var (
+	_ http.Flusher  = (*statusRecorder)(nil)
+	_ http.Hijacker = (*statusRecorder)(nil)
+)
+
+// recordPrincipal and recordRoute fill in the request info when the request
+// passes through AccessLogMiddleware and do nothing otherwise.
+func recordPrincipal(ctx context.Context, p auth.Principal) {
+	if info := requestInfoFrom(ctx); info != nil {
+		info.principal = p.Subject
+	}
+}
+
+func recordRoute(ctx context.Context, pattern string) {
+	if info := requestInfoFrom(ctx); info != nil {
+		info.route = pattern
+	}
+}
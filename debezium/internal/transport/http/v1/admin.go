@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"debezium_server/pkg/logger"
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type logLevelDTO struct {
+	Level string `json:"level"`
+}
+
+// LogLevelHandler reads and changes the level of the running logger.
+type LogLevelHandler struct {
+	lg logger.Logger
+}
+
+func NewLogLevelHandler(lg logger.Logger) *LogLevelHandler {
+	return &LogLevelHandler{lg: lg}
+}
+
+func (h *LogLevelHandler) Get(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, logLevelDTO{Level: h.lg.Level().String()})
+}
+
+// Set changes the level until the next restart.
+func (h *LogLevelHandler) Set(w http.ResponseWriter, r *http.Request) {
+	var req logLevelDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	prev := h.lg.Level()
+	h.lg.SetLevel(level)
+	h.lg.Warn(r.Context(), "log level changed",
+		zap.Stringer("from", prev),
+		zap.Stringer("to", level),
+	)
+
+	writeJSON(w, http.StatusOK, logLevelDTO{Level: level.String()})
+}
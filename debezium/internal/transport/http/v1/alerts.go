@@ -0,0 +1,23 @@
+package v1
+
+import (
+	"debezium_server/internal/alerting"
+	"net/http"
+)
+
+type AlertSource interface {
+	Active() []alerting.Alert
+}
+
+type AlertHandler struct {
+	alerts AlertSource
+}
+
+func NewAlertHandler(alerts AlertSource) *AlertHandler {
+	return &AlertHandler{alerts: alerts}
+}
+
+// List returns the firing alerts, oldest first.
+func (h *AlertHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.alerts.Active())
+}
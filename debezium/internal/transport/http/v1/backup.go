@@ -0,0 +1,141 @@
+package v1
+
+import (
+	"context"
+	"debezium_server/pkg/backup"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	maxRestoreBodySize = 64 << 20
+)
+
+type BackupService interface {
+	Export(ctx context.Context, cluster string, names []string, includeSecrets bool) (*backup.Archive, error)
+	Restore(
+		ctx context.Context,
+		cluster string,
+		a *backup.Archive,
+		opts backup.RestoreOptions,
+		vars map[string]string,
+	) ([]backup.Result, error)
+}
+
+type restoreResponse struct {
+	Results []backup.Result `json:"results"`
+}
+
+type BackupHandler struct {
+	service BackupService
+}
+
+func NewBackupHandler(service BackupService) *BackupHandler {
+	return &BackupHandler{service: service}
+}
+
+// Export downloads an archive of the cluster's connectors. Supported query
+// parameters are connector (repeatable, all by default), format (json, tar
+// or tar.gz) and secrets=include to export secret values unmasked.
+func (h *BackupHandler) Export(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	format := backup.FormatJSON
+	if v := q.Get("format"); v != "" {
+		var err error
+		if format, err = backup.ParseFormat(v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	a, err := h.service.Export(r.Context(), r.PathValue("cluster"), q["connector"], q.Get("secrets") == "include")
+	if err != nil {
+		writeClusterError(w, err)
+		return
+	}
+
+	filename := fmt.Sprintf("connectors-%s-%s%s", a.Cluster, a.CreatedAt.Format("20060102T150405Z"), format.Extension())
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	// The status is sent; an encoding error can only cut the body short.
+	_ = backup.Write(w, a, format)
+}
+
+// Restore recreates the connectors of the archive in the request body, in
+// any export format. Supported query parameters are connector (repeatable),
+// rename=old=new, override=key=value and var=key=value (all repeatable),
+// and offsets=false to restore the configs only. It responds 200 if every
+// connector was restored and 502 otherwise, with a result per connector.
+func (h *BackupHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	opts := backup.RestoreOptions{
+		Connectors:  q["connector"],
+		SkipOffsets: q.Get("offsets") == "false",
+	}
+	var err error
+	if opts.Rename, err = parsePairs(q["rename"]); err != nil {
+		http.Error(w, "invalid rename: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.Overrides, err = parsePairs(q["override"]); err != nil {
+		http.Error(w, "invalid override: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	vars, err := parsePairs(q["var"])
+	if err != nil {
+		http.Error(w, "invalid var: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a, err := backup.Read(http.MaxBytesReader(w, r.Body, maxRestoreBodySize))
+	if err != nil {
+		http.Error(w, "invalid archive: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.service.Restore(r.Context(), r.PathValue("cluster"), a, opts, vars)
+	if err != nil {
+		writeBackupError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if backup.Failed(results) {
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, restoreResponse{Results: results})
+}
+
+// parsePairs splits every value at the first = into a key and a value, the
+// same form connectorctl takes.
+func parsePairs(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	out := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q must be in the form key=value", v)
+		}
+		out[key] = value
+	}
+	return out, nil
+}
+
+func writeBackupError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, backup.ErrUnsupportedVersion),
+		errors.Is(err, backup.ErrNotInArchive),
+		errors.Is(err, backup.ErrDuplicateTarget):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeClusterError(w, err)
+	}
+}
@@ -0,0 +1,284 @@
+package v1
+
+import (
+	"debezium_server/internal/stream"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsTypeSubscribe    = "subscribe"
+	wsTypeUnsubscribe  = "unsubscribe"
+	wsTypeSubscribed   = "subscribed"
+	wsTypeUnsubscribed = "unsubscribed"
+	wsTypeChange       = "change"
+	wsTypeError        = "error"
+	wsTypeDisconnect   = "disconnect"
+
+	wsWriteTimeout = 10 * time.Second
+	wsReadLimit    = 4096
+)
+
+type ChangeStreamHandler struct {
+	hub      *stream.Hub
+	upgrader websocket.Upgrader
+}
+
+type wsMessage struct {
+	Type        string          `json:"type"`
+	Table       string          `json:"table,omitempty"`
+	LastEventID string          `json:"last_event_id,omitempty"`
+	ID          string          `json:"id,omitempty"`
+	Event       json.RawMessage `json:"event,omitempty"`
+	Message     string          `json:"message,omitempty"`
+}
+
+func NewChangeStreamHandler(hub *stream.Hub) *ChangeStreamHandler {
+	h := &ChangeStreamHandler{hub: hub}
+	h.upgrader = websocket.Upgrader{CheckOrigin: h.checkOrigin}
+
+	return h
+}
+
+// ServeSSE streams change events of a single table as Server-Sent Events.
+func (h *ChangeStreamHandler) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	table := r.PathValue("table")
+	if err := validateTable(table); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	lastEventID, err := parseEventID(lastID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+
+	sub := h.hub.NewSubscriber()
+	defer h.hub.Remove(sub)
+
+	replay, err := h.hub.Subscribe(sub, table, lastEventID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	for _, ev := range replay {
+		if err := writeSSE(w, ev); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.hub.HeartbeatInterval())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-sub.Events():
+			if err := writeSSE(w, ev); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-sub.Done():
+			if err := sub.Err(); err != nil {
+				_, _ = fmt.Fprintf(w, "event: disconnect\ndata: %s\n\n", strconv.Quote(err.Error()))
+				_ = rc.Flush()
+			}
+			return
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// ServeWebSocket streams change events over a WebSocket. Clients choose tables
+// with subscribe and unsubscribe messages and may resume with last_event_id.
+func (h *ChangeStreamHandler) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sub := h.hub.NewSubscriber()
+	defer h.hub.Remove(sub)
+
+	// Requests are handled here rather than in the reader, so the replay
+	// of a subscribe is written before the events that follow it.
+	requests := make(chan wsMessage, 1)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- h.readWebSocket(conn, sub, requests)
+	}()
+
+	heartbeat := time.NewTicker(h.hub.HeartbeatInterval())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-readErr:
+			return
+		case req := <-requests:
+			reply, replay := h.handleWebSocketMessage(sub, req)
+			if err := writeWebSocket(conn, reply); err != nil {
+				return
+			}
+			for _, ev := range replay {
+				if err := writeWebSocket(conn, changeMessage(ev)); err != nil {
+					return
+				}
+			}
+		case ev := <-sub.Events():
+			if err := writeWebSocket(conn, changeMessage(ev)); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			deadline := time.Now().Add(wsWriteTimeout)
+			if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				return
+			}
+		case <-sub.Done():
+			if err := sub.Err(); err != nil {
+				_ = writeWebSocket(conn, wsMessage{Type: wsTypeDisconnect, Message: err.Error()})
+			}
+			deadline := time.Now().Add(wsWriteTimeout)
+			closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "")
+			_ = conn.WriteControl(websocket.CloseMessage, closeMsg, deadline)
+			return
+		}
+	}
+}
+
+func (h *ChangeStreamHandler) readWebSocket(conn *websocket.Conn, sub *stream.Subscriber, requests chan<- wsMessage) error {
+	conn.SetReadLimit(wsReadLimit)
+
+	pongWait := 2 * h.hub.HeartbeatInterval()
+	if err := conn.SetReadDeadline(time.Now().Add(pongWait)); err != nil {
+		return err
+	}
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+
+		select {
+		case requests <- msg:
+		case <-sub.Done():
+			return stream.ErrSubscriberClosed
+		}
+	}
+}
+
+// handleWebSocketMessage returns the reply to a client message and, for a
+// resumed subscription, the history to send after it.
+func (h *ChangeStreamHandler) handleWebSocketMessage(sub *stream.Subscriber, msg wsMessage) (wsMessage, []stream.Event) {
+	if err := validateTable(msg.Table); err != nil {
+		return wsMessage{Type: wsTypeError, Table: msg.Table, Message: err.Error()}, nil
+	}
+
+	switch msg.Type {
+	case wsTypeSubscribe:
+		lastEventID, err := parseEventID(msg.LastEventID)
+		if err != nil {
+			return wsMessage{Type: wsTypeError, Table: msg.Table, Message: err.Error()}, nil
+		}
+		replay, err := h.hub.Subscribe(sub, msg.Table, lastEventID)
+		if err != nil {
+			return wsMessage{Type: wsTypeError, Table: msg.Table, Message: err.Error()}, nil
+		}
+		return wsMessage{Type: wsTypeSubscribed, Table: msg.Table}, replay
+	case wsTypeUnsubscribe:
+		h.hub.Unsubscribe(sub, msg.Table)
+		return wsMessage{Type: wsTypeUnsubscribed, Table: msg.Table}, nil
+	default:
+		return wsMessage{Type: wsTypeError, Message: fmt.Sprintf("unknown message type %q", msg.Type)}, nil
+	}
+}
+
+func (h *ChangeStreamHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	return slices.Contains(h.hub.AllowedOrigins(), origin)
+}
+
+func changeMessage(ev stream.Event) wsMessage {
+	return wsMessage{Type: wsTypeChange, Table: ev.Table, ID: strconv.FormatUint(ev.ID, 10), Event: ev.Data}
+}
+
+func writeSSE(w http.ResponseWriter, ev stream.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: change\ndata: %s\n\n", ev.ID, ev.Data)
+	return err
+}
+
+func writeWebSocket(conn *websocket.Conn, msg wsMessage) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+	return conn.WriteJSON(msg)
+}
+
+func validateTable(table string) error {
+	schema, name, ok := strings.Cut(table, ".")
+	if !ok || schema == "" || name == "" || strings.Contains(name, ".") {
+		return errors.New("table must be in the form schema.table")
+	}
+	return nil
+}
+
+func parseEventID(id string) (uint64, error) {
+	if id == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid event id %q", id)
+	}
+	return n, nil
+}
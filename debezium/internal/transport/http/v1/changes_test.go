@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"bufio"
+	"context"
+	"debezium_server/internal/stream"
+	"debezium_server/pkg/cdc"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestServeSSE checks that a stream resumed with Last-Event-ID replays the
+// newer events and sends heartbeats while there are no events.
+func TestServeSSE(t *testing.T) {
+	hub, err := stream.NewHub(stream.Config{
+		BufferSize:        16,
+		HistorySize:       16,
+		SlowConsumer:      stream.PolicyDisconnect,
+		HeartbeatInterval: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewHub: %v", err)
+	}
+	t.Cleanup(hub.Close)
+	for range 3 {
+		if err := hub.Publish(cdc.ChangeEvent{Op: "c", Source: cdc.Source{Schema: "inventory", Table: "orders"}}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/changes/{table}", NewChangeStreamHandler(hub).ServeSSE)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v1/changes/inventory.orders", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type is %q", ct)
+	}
+
+	var ids []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, id)
+		}
+		if line == ": heartbeat" {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("no heartbeat: %v", err)
+	}
+	if !slices.Equal(ids, []string{"2", "3"}) {
+		t.Errorf("replayed events %v, want 2 and 3", ids)
+	}
+}
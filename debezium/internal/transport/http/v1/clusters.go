@@ -0,0 +1,165 @@
+package v1
+
+import (
+	"context"
+	"debezium_server/internal/cluster"
+	"debezium_server/internal/service"
+	dto "debezium_server/internal/transport/http/models"
+	debezium_client "debezium_server/pkg/debezium-client"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type ClusterService interface {
+	List() []service.ClusterInfo
+	Connectors(ctx context.Context, expandStatus bool) []service.ClusterConnectors
+	Copy(ctx context.Context, req service.CopyRequest) (debezium_client.GetConnectorResponse, error)
+	Info(ctx context.Context, name string) (debezium_client.ServerInfo, error)
+	Loggers(ctx context.Context, name string) (map[string]debezium_client.LoggerLevel, error)
+	Logger(ctx context.Context, name, logger string) (debezium_client.LoggerLevel, error)
+	SetLoggerLevel(ctx context.Context, name, logger, level, scope string) ([]string, error)
+}
+
+type setLoggerResponse struct {
+	Changed []string `json:"changed,omitempty"`
+}
+
+// partialMigrationResponse reports a target that runs next to a source that
+// could not be deleted.
+type partialMigrationResponse struct {
+	Error  string                               `json:"error"`
+	Target debezium_client.GetConnectorResponse `json:"target,omitzero"`
+}
+
+type ClusterHandler struct {
+	service ClusterService
+}
+
+func NewClusterHandler(service ClusterService) *ClusterHandler {
+	return &ClusterHandler{service: service}
+}
+
+func (h *ClusterHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.service.List())
+}
+
+// Connectors lists the connectors of every cluster. Pass ?expand=status to
+// include their statuses. Unreachable clusters are reported with an error.
+func (h *ClusterHandler) Connectors(w http.ResponseWriter, r *http.Request) {
+	expand := r.URL.Query().Get("expand") == "status"
+	writeJSON(w, http.StatusOK, h.service.Connectors(r.Context(), expand))
+}
+
+// Copy creates the connector on the target cluster with the same config and,
+// if offsets is set, the same source offsets. overrides must give the copy
+// its own slot.name, publication.name and topic.prefix.
+func (h *ClusterHandler) Copy(w http.ResponseWriter, r *http.Request) {
+	h.copy(w, r, false)
+}
+
+// Migrate moves the connector to the target cluster with its offsets and
+// deletes it from the source cluster. If the delete fails, the response is
+// 502 with the running target.
+func (h *ClusterHandler) Migrate(w http.ResponseWriter, r *http.Request) {
+	h.copy(w, r, true)
+}
+
+func (h *ClusterHandler) copy(w http.ResponseWriter, r *http.Request, migrate bool) {
+	var req dto.CopyConnectorDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Target == "" {
+		http.Error(w, "target is required", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.Copy(r.Context(), service.CopyRequest{
+		SourceCluster: r.PathValue("cluster"),
+		Name:          r.PathValue("name"),
+		TargetCluster: req.Target,
+		TargetName:    req.TargetName,
+		Offsets:       req.Offsets,
+		Migrate:       migrate,
+		Overrides:     req.Overrides,
+	})
+	switch {
+	case errors.Is(err, service.ErrSameCluster), errors.Is(err, service.ErrCopyNeedsOverrides):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrMigrationIncomplete):
+		writeJSON(w, http.StatusBadGateway, partialMigrationResponse{Error: err.Error(), Target: resp})
+	case err != nil:
+		writeClusterError(w, err)
+	default:
+		writeJSON(w, http.StatusCreated, resp)
+	}
+}
+
+// Info returns the version of the worker that answered and the Kafka
+// cluster ID.
+func (h *ClusterHandler) Info(w http.ResponseWriter, r *http.Request) {
+	info, err := h.service.Info(r.Context(), r.PathValue("cluster"))
+	if err != nil {
+		writeClusterError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (h *ClusterHandler) Loggers(w http.ResponseWriter, r *http.Request) {
+	loggers, err := h.service.Loggers(r.Context(), r.PathValue("cluster"))
+	if err != nil {
+		writeClusterError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, loggers)
+}
+
+func (h *ClusterHandler) Logger(w http.ResponseWriter, r *http.Request) {
+	level, err := h.service.Logger(r.Context(), r.PathValue("cluster"), r.PathValue("logger"))
+	if err != nil {
+		writeClusterError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, level)
+}
+
+// SetLogger changes the level of a Kafka Connect logger, e.g. to debug
+// io.debezium for a while. The scope is cluster unless set to worker.
+func (h *ClusterHandler) SetLogger(w http.ResponseWriter, r *http.Request) {
+	var req dto.LoggerLevelDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Level == "" {
+		http.Error(w, "level is required", http.StatusBadRequest)
+		return
+	}
+
+	changed, err := h.service.SetLoggerLevel(
+		r.Context(), r.PathValue("cluster"), r.PathValue("logger"), req.Level, req.Scope,
+	)
+	if err != nil {
+		writeClusterError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, setLoggerResponse{Changed: changed})
+}
+
+func writeClusterError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, cluster.ErrUnknownCluster):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidLoggerScope), errors.Is(err, debezium_client.ErrEmptyLoggerName):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeConnectorError(w, err)
+	}
+}
@@ -0,0 +1,598 @@
+package v1
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	dto "debezium_server/internal/transport/http/models"
+	"debezium_server/pkg/configtemplate"
+	debezium_client "debezium_server/pkg/debezium-client"
+	"debezium_server/pkg/outbox"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+type ConnectorService interface {
+	Render(def configtemplate.Definition, vars map[string]string) (configtemplate.Definition, error)
+	Create(
+		ctx context.Context,
+		def configtemplate.Definition,
+		vars map[string]string,
+		comment string,
+	) (*debezium_client.CreateConnectorResponse, error)
+	UpdateConfig(
+		ctx context.Context,
+		name string,
+		config map[string]string,
+		vars map[string]string,
+		comment string,
+	) (debezium_client.GetConnectorResponse, error)
+	Upsert(
+		ctx context.Context,
+		name string,
+		config map[string]string,
+		vars map[string]string,
+		comment string,
+	) (debezium_client.GetConnectorResponse, bool, error)
+	List(ctx context.Context, expandStatus bool) (debezium_client.ListConnectorsResponse, error)
+	Get(ctx context.Context, name string) (debezium_client.GetConnectorResponse, error)
+	Status(ctx context.Context, name string) (debezium_client.ConnectorStatus, error)
+	Tasks(ctx context.Context, name string) ([]debezium_client.TaskInfo, error)
+	Pause(ctx context.Context, name string) error
+	Resume(ctx context.Context, name string) error
+	Restart(ctx context.Context, name string) error
+	RestartTask(ctx context.Context, name string, taskID int) error
+	Delete(ctx context.Context, name string) error
+	Stop(ctx context.Context, name string) error
+	Offsets(ctx context.Context, name string) (debezium_client.ConnectorOffsets, error)
+	AlterOffsets(ctx context.Context, name string, offsets debezium_client.ConnectorOffsets) error
+	ResetOffsets(ctx context.Context, name string) error
+	Topics(ctx context.Context, name string) ([]string, error)
+	ResetTopics(ctx context.Context, name string) error
+	Versions(ctx context.Context, name string, limit, offset int) ([]models.ConnectorVersion, error)
+	Version(ctx context.Context, name string, version int) (models.ConnectorVersion, error)
+	DiffVersions(ctx context.Context, name string, from, to int) (map[string]models.AuditChange, error)
+	Rollback(ctx context.Context, name string, version int, comment string) (debezium_client.GetConnectorResponse, error)
+}
+
+type listConnectorsResponse struct {
+	Names    []string                                   `json:"names"`
+	Statuses map[string]debezium_client.ConnectorStatus `json:"statuses,omitempty"`
+}
+
+// ConnectorHandler serves the connector routes of every cluster. Routes
+// without a {cluster} segment use the default cluster.
+type ConnectorHandler struct {
+	services       map[string]ConnectorService
+	defaultCluster string
+}
+
+type connectorTopicsResponse struct {
+	Name   string   `json:"name"`
+	Topics []string `json:"topics"`
+}
+
+type versionDiffResponse struct {
+	From int                           `json:"from"`
+	To   int                           `json:"to"`
+	Diff map[string]models.AuditChange `json:"diff"`
+}
+
+type templateErrorResponse struct {
+	Error    string                   `json:"error"`
+	Problems []configtemplate.Problem `json:"problems"`
+}
+
+func NewConnectorHandler(services map[string]ConnectorService, defaultCluster string) *ConnectorHandler {
+	return &ConnectorHandler{services: services, defaultCluster: defaultCluster}
+}
+
+func (h *ConnectorHandler) serviceFor(w http.ResponseWriter, r *http.Request) (ConnectorService, bool) {
+	name := r.PathValue("cluster")
+	if name == "" {
+		name = h.defaultCluster
+	}
+
+	svc, ok := h.services[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown cluster %q", name), http.StatusNotFound)
+		return nil, false
+	}
+	return svc, true
+}
+
+// Create renders a connector template and registers the connector.
+func (h *ConnectorHandler) Create(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.ConnectorTemplateDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	def := configtemplate.Definition{Name: req.Name, Config: withOutbox(req.Config, req.Outbox)}
+	resp, err := svc.Create(r.Context(), def, req.Vars, req.Comment)
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// Upsert creates the connector or replaces its config, keeping its offsets.
+// It responds 201 if the connector was created and 200 if it was updated.
+func (h *ConnectorHandler) Upsert(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.ConnectorTemplateDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := r.PathValue("name")
+	if req.Name != "" && req.Name != name {
+		http.Error(w, "name in body does not match the path", http.StatusBadRequest)
+		return
+	}
+
+	resp, created, err := svc.Upsert(r.Context(), name, withOutbox(req.Config, req.Outbox), req.Vars, req.Comment)
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, resp)
+}
+
+// Validate renders a connector template without applying it and reports
+// every unresolved placeholder.
+func (h *ConnectorHandler) Validate(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.ConnectorTemplateDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	def := configtemplate.Definition{Name: req.Name, Config: withOutbox(req.Config, req.Outbox)}
+	if _, err := svc.Render(def, req.Vars); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ConnectorHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.ConnectorConfigDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := svc.UpdateConfig(r.Context(), r.PathValue("name"), withOutbox(req.Config, req.Outbox), req.Vars, req.Comment)
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *ConnectorHandler) List(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	expand := r.URL.Query().Get("expand") == "status"
+
+	resp, err := svc.List(r.Context(), expand)
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listConnectorsResponse{Names: resp.Names, Statuses: resp.Statuses})
+}
+
+func (h *ConnectorHandler) Get(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := svc.Get(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *ConnectorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := svc.Status(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *ConnectorHandler) Tasks(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := svc.Tasks(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *ConnectorHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := svc.Pause(r.Context(), r.PathValue("name")); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *ConnectorHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := svc.Resume(r.Context(), r.PathValue("name")); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *ConnectorHandler) Restart(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := svc.Restart(r.Context(), r.PathValue("name")); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *ConnectorHandler) RestartTask(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := strconv.Atoi(r.PathValue("task"))
+	if err != nil || taskID < 0 {
+		http.Error(w, "invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	if err := svc.RestartTask(r.Context(), r.PathValue("name"), taskID); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *ConnectorHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := svc.Delete(r.Context(), r.PathValue("name")); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ConnectorHandler) Stop(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := svc.Stop(r.Context(), r.PathValue("name")); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *ConnectorHandler) Offsets(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := svc.Offsets(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// AlterOffsets overwrites offsets of a stopped connector. The body is the
+// Kafka Connect format: {"offsets": [{"partition": {...}, "offset": {...}}]}.
+func (h *ConnectorHandler) AlterOffsets(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	var req debezium_client.ConnectorOffsets
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Offsets) == 0 {
+		http.Error(w, "offsets are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := svc.AlterOffsets(r.Context(), r.PathValue("name"), req); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ConnectorHandler) ResetOffsets(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := svc.ResetOffsets(r.Context(), r.PathValue("name")); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Topics returns the topics the connector has written to or read from.
+func (h *ConnectorHandler) Topics(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	name := r.PathValue("name")
+	topics, err := svc.Topics(r.Context(), name)
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+	if topics == nil {
+		topics = []string{}
+	}
+
+	writeJSON(w, http.StatusOK, connectorTopicsResponse{Name: name, Topics: topics})
+}
+
+func (h *ConnectorHandler) ResetTopics(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := svc.ResetTopics(r.Context(), r.PathValue("name")); err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Versions returns the config history of the connector, newest first.
+// Supported query parameters are limit and offset.
+func (h *ConnectorHandler) Versions(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	limit, err := parseIntParam(q.Get("limit"))
+	if err != nil {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+	offset, err := parseIntParam(q.Get("offset"))
+	if err != nil {
+		http.Error(w, "invalid offset", http.StatusBadRequest)
+		return
+	}
+
+	versions, err := svc.Versions(r.Context(), r.PathValue("name"), limit, offset)
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+	if versions == nil {
+		versions = []models.ConnectorVersion{}
+	}
+
+	writeJSON(w, http.StatusOK, versions)
+}
+
+func (h *ConnectorHandler) Version(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	version, err := parseVersion(r.PathValue("version"))
+	if err != nil {
+		http.Error(w, "invalid version", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := svc.Version(r.Context(), r.PathValue("name"), version)
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// DiffVersions returns the config keys that differ between the versions
+// given by the from and to query parameters.
+func (h *ConnectorHandler) DiffVersions(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	from, err := parseVersion(q.Get("from"))
+	if err != nil {
+		http.Error(w, "invalid from", http.StatusBadRequest)
+		return
+	}
+	to, err := parseVersion(q.Get("to"))
+	if err != nil {
+		http.Error(w, "invalid to", http.StatusBadRequest)
+		return
+	}
+
+	diff, err := svc.DiffVersions(r.Context(), r.PathValue("name"), from, to)
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, versionDiffResponse{From: from, To: to, Diff: diff})
+}
+
+// Rollback applies the config of an earlier version. The body may carry a
+// comment for the new version.
+func (h *ConnectorHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	svc, ok := h.serviceFor(w, r)
+	if !ok {
+		return
+	}
+
+	version, err := parseVersion(r.PathValue("version"))
+	if err != nil {
+		http.Error(w, "invalid version", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.RollbackDTO
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp, err := svc.Rollback(r.Context(), r.PathValue("name"), version, req.Comment)
+	if err != nil {
+		writeConnectorError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// withOutbox merges the outbox router properties into the requested config
+// when the request asks for them.
+func withOutbox(config map[string]string, router *dto.OutboxRouterDTO) map[string]string {
+	if router == nil {
+		return config
+	}
+	return outbox.RouterConfig{
+		TransformName:     router.TransformName,
+		Schema:            router.Schema,
+		Table:             router.Table,
+		TopicTemplate:     router.TopicTemplate,
+		ExpandJSONPayload: router.ExpandJSONPayload,
+	}.Apply(config)
+}
+
+func parseVersion(v string) (int, error) {
+	version, err := strconv.Atoi(v)
+	if err != nil || version < 1 {
+		return 0, errors.New("version must be a positive integer")
+	}
+	return version, nil
+}
+
+func writeConnectorError(w http.ResponseWriter, err error) {
+	var validationErr *configtemplate.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		writeJSON(w, http.StatusUnprocessableEntity, templateErrorResponse{
+			Error:    "template validation failed",
+			Problems: validationErr.Problems,
+		})
+	case errors.Is(err, debezium_client.ErrEmptyConnectorName):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrVersionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	}
+}
@@ -0,0 +1,150 @@
+package v1
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"debezium_server/internal/service"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type DeadLetterService interface {
+	List(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetter, error)
+	Get(ctx context.Context, id int64) (models.DeadLetter, error)
+	Replay(ctx context.Context, id int64) error
+	Discard(ctx context.Context, id int64) error
+}
+
+type DeadLetterHandler struct {
+	service DeadLetterService
+}
+
+func NewDeadLetterHandler(service DeadLetterService) *DeadLetterHandler {
+	return &DeadLetterHandler{service: service}
+}
+
+// deadLetterResponse shows the key and value as JSON when they are JSON,
+// e.g. with the JSON converter, and as base64 otherwise.
+type deadLetterResponse struct {
+	models.DeadLetter
+	Key         json.RawMessage `json:"key,omitempty"`
+	KeyBase64   string          `json:"key_base64,omitempty"`
+	Value       json.RawMessage `json:"value"`
+	ValueBase64 string          `json:"value_base64,omitempty"`
+}
+
+// List returns dead letters without their keys and values, oldest first.
+// Supported query parameters are topic, limit and offset.
+func (h *DeadLetterHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filter := models.DeadLetterFilter{Topic: q.Get("topic")}
+
+	var err error
+	if filter.Limit, err = parseIntParam(q.Get("limit")); err != nil {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+	if filter.Offset, err = parseIntParam(q.Get("offset")); err != nil {
+		http.Error(w, "invalid offset", http.StatusBadRequest)
+		return
+	}
+
+	letters, err := h.service.List(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, letters)
+}
+
+// Get returns a dead letter with its key and value.
+func (h *DeadLetterHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := parseDeadLetterID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dl, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+
+	resp := deadLetterResponse{DeadLetter: dl}
+	if dl.Key != nil {
+		if json.Valid(dl.Key) {
+			resp.Key = dl.Key
+		} else {
+			resp.KeyBase64 = base64.StdEncoding.EncodeToString(dl.Key)
+		}
+	}
+	switch {
+	case dl.Value == nil:
+		resp.Value = json.RawMessage("null")
+	case json.Valid(dl.Value):
+		resp.Value = dl.Value
+	default:
+		resp.Value = json.RawMessage("null")
+		resp.ValueBase64 = base64.StdEncoding.EncodeToString(dl.Value)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Replay hands the event to the consumer's handler again. The dead letter
+// is removed if that succeeds and kept with the new error otherwise.
+func (h *DeadLetterHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	id, err := parseDeadLetterID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Replay(r.Context(), id); err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Discard removes a dead letter without handling it.
+func (h *DeadLetterHandler) Discard(w http.ResponseWriter, r *http.Request) {
+	id, err := parseDeadLetterID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Discard(r.Context(), id); err != nil {
+		writeDeadLetterError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseDeadLetterID(v string) (int64, error) {
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("id must be a positive integer")
+	}
+	return id, nil
+}
+
+func writeDeadLetterError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrDeadLetterNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrReplayFailed):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
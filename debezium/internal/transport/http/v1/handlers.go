@@ -3,12 +3,16 @@ package v1
 import (
 	"context"
 	"debezium_server/internal/models"
+	dto "debezium_server/internal/transport/http/models"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 )
 
 type UserService interface {
 	GetUsers(ctx context.Context, offset, limit int) ([]models.User, error)
+	CreateUser(ctx context.Context, user models.User) (models.User, error)
 }
 
 type HandlerFacade struct {
@@ -38,3 +42,51 @@ func (h *HandlerFacade) GetUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (h *HandlerFacade) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateUserDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+
+		return
+	}
+
+	if strings.TrimSpace(req.Email) == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
+
+		return
+	}
+
+	user, err := h.service.CreateUser(r.Context(), models.User{
+		Email:    req.Email,
+		Name:     req.Name,
+		LastName: req.LastName,
+		Role:     req.Role,
+	})
+	if errors.Is(err, models.ErrUserAlreadyExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -1,23 +1,142 @@
 package v1
 
 import (
+	"debezium_server/internal/auth"
 	"debezium_server/pkg/logger"
+	"debezium_server/pkg/tracing"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	requestIDHeader = "X-Request-ID"
+	traceIDHeader   = "X-Trace-ID"
+
+	maxRequestIDLength = 128
+)
+
+// LoggingMiddleware stores the request ID in the request context for the
+// logger and echoes the request and trace IDs in the response headers. A
+// missing or malformed X-Request-ID is replaced with a new UUID.
 func LoggingMiddleware() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			reqIDHeader := r.Header.Get("x-request-id")
-			if reqIDHeader != "" {
-				logger.WithRequestID(r.Context(), reqIDHeader)
-			} else {
-				logger.WithRequestID(r.Context(), uuid.NewString())
+			reqID := r.Header.Get(requestIDHeader)
+			if !validRequestID(reqID) {
+				reqID = uuid.NewString()
+			}
+
+			ctx := logger.WithRequestID(r.Context(), reqID)
+			w.Header().Set(requestIDHeader, reqID)
+			if traceID := tracing.TraceID(ctx); traceID != "" {
+				w.Header().Set(traceIDHeader, traceID)
 			}
+			trace.SpanFromContext(ctx).SetAttributes(attribute.String("http.request_id", reqID))
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-			next.ServeHTTP(w, r)
+// TracingMiddleware starts a server span per request, continuing the trace
+// of an incoming W3C traceparent header.
+func TracingMiddleware() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return otelhttp.NewHandler(next, "http.server",
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return r.Method
+			}),
+		)
+	}
+}
+
+// nameSpanByRoute renames the server span to the route pattern the mux
+// matched, e.g. "GET /api/v1/connectors/{name}", once it has routed the request.
+func nameSpanByRoute(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+
+		if r.Pattern == "" {
+			return
+		}
+		name := r.Pattern
+		if !strings.Contains(name, " ") {
+			name = r.Method + " " + name
+		}
+		recordRoute(r.Context(), r.Pattern)
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(name)
+		span.SetAttributes(attribute.String("http.route", r.Pattern))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// AuthMiddleware rejects requests that the authenticator cannot resolve to a
+// principal and attaches the principal to the request context otherwise.
+func AuthMiddleware(authn auth.Authenticator) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authn.Authenticate(r)
+			switch {
+			case errors.Is(err, auth.ErrNoCredentials):
+				w.Header().Set("WWW-Authenticate", `Bearer realm="debezium-api"`)
+				http.Error(w, "authentication required", http.StatusUnauthorized)
+				return
+			case errors.Is(err, auth.ErrInvalidCredentials):
+				w.Header().Set("WWW-Authenticate", `Bearer realm="debezium-api", error="invalid_token"`)
+				http.Error(w, auth.ErrInvalidCredentials.Error(), http.StatusUnauthorized)
+				return
+			case err != nil:
+				http.Error(w, "failed to authenticate request", http.StatusInternalServerError)
+				return
+			}
+
+			recordPrincipal(r.Context(), principal)
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+type forbiddenResponse struct {
+	Error             string          `json:"error"`
+	MissingPermission auth.Permission `json:"missing_permission"`
+}
+
+// RequirePermission lets the request through only if the principal attached
+// by AuthMiddleware has perm. Denials name the missing permission.
+func RequirePermission(policy auth.Policy, perm auth.Permission) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := policy.Authorize(r.Context(), perm)
+			var forbidden *auth.ForbiddenError
+			switch {
+			case err == nil:
+				next.ServeHTTP(w, r)
+			case errors.As(err, &forbidden):
+				writeJSON(w, http.StatusForbidden, forbiddenResponse{
+					Error:             forbidden.Error(),
+					MissingPermission: forbidden.Permission,
+				})
+			default:
+				http.Error(w, "authentication required", http.StatusUnauthorized)
+			}
 		})
 	}
 }
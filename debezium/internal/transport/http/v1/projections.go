@@ -0,0 +1,62 @@
+package v1
+
+import (
+	"context"
+	"debezium_server/internal/projection"
+	"debezium_server/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type ProjectionService interface {
+	List(ctx context.Context) ([]projection.Status, error)
+	Rebuild(ctx context.Context, name, connector string) (service.SnapshotProgress, error)
+}
+
+type ProjectionHandler struct {
+	service ProjectionService
+}
+
+func NewProjectionHandler(service ProjectionService) *ProjectionHandler {
+	return &ProjectionHandler{service: service}
+}
+
+type rebuildProjectionRequest struct {
+	Connector string `json:"connector"`
+}
+
+// List returns the enabled projections with the last applied source LSN
+// and the snapshot of the last rebuild.
+func (h *ProjectionHandler) List(w http.ResponseWriter, r *http.Request) {
+	statuses, err := h.service.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// Rebuild starts an incremental snapshot of the projection's source tables
+// on the connector in the body and drops the rows written before it. The response is the snapshot
+// progress, also available under the connector's snapshots.
+func (h *ProjectionHandler) Rebuild(w http.ResponseWriter, r *http.Request) {
+	var req rebuildProjectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Connector == "" {
+		http.Error(w, "connector is required", http.StatusBadRequest)
+		return
+	}
+
+	progress, err := h.service.Rebuild(r.Context(), r.PathValue("name"), req.Connector)
+	if errors.Is(err, projection.ErrUnknownProjection) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeSnapshotError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, progress)
+}
@@ -0,0 +1,89 @@
+package v1
+
+import (
+	"context"
+	"debezium_server/internal/models"
+	"debezium_server/pkg/cdc"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type SchemaHistoryService interface {
+	History(ctx context.Context, filter models.SchemaHistoryFilter) ([]models.TableSchemaVersion, error)
+	Check(ctx context.Context, table, source string, proposed cdc.TableSchema) (models.SchemaCheck, error)
+}
+
+type SchemaHistoryHandler struct {
+	service SchemaHistoryService
+}
+
+func NewSchemaHistoryHandler(service SchemaHistoryService) *SchemaHistoryHandler {
+	return &SchemaHistoryHandler{service: service}
+}
+
+// History returns the schema versions of a table, e.g. inventory.products,
+// newest first. Supported query parameters are breaking, limit and offset.
+func (h *SchemaHistoryHandler) History(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filter := models.SchemaHistoryFilter{Table: r.PathValue("table")}
+
+	var err error
+	if v := q.Get("breaking"); v != "" {
+		if filter.BreakingOnly, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "invalid breaking", http.StatusBadRequest)
+			return
+		}
+	}
+	if filter.Limit, err = parseIntParam(q.Get("limit")); err != nil {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+	if filter.Offset, err = parseIntParam(q.Get("offset")); err != nil {
+		http.Error(w, "invalid offset", http.StatusBadRequest)
+		return
+	}
+
+	versions, err := h.service.History(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// Check compares the columns in the body with the latest schema version of
+// the table and lists the changes. The source query parameter selects the
+// event (default) or ddl versions.
+func (h *SchemaHistoryHandler) Check(w http.ResponseWriter, r *http.Request) {
+	source := r.URL.Query().Get("source")
+	switch source {
+	case "":
+		source = models.SchemaSourceEvent
+	case models.SchemaSourceEvent, models.SchemaSourceDDL:
+	default:
+		http.Error(w, "source must be event or ddl", http.StatusBadRequest)
+		return
+	}
+
+	var proposed cdc.TableSchema
+	if err := json.NewDecoder(r.Body).Decode(&proposed); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	check, err := h.service.Check(r.Context(), r.PathValue("table"), source, proposed)
+	switch {
+	case errors.Is(err, models.ErrTableSchemaNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, check)
+}
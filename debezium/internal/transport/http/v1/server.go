@@ -2,8 +2,16 @@ package v1
 
 import (
 	"context"
+	"debezium_server/internal/alerting"
+	"debezium_server/internal/auth"
+	"debezium_server/internal/cluster"
+	"debezium_server/internal/projection"
 	"debezium_server/internal/repository"
 	"debezium_server/internal/service"
+	"debezium_server/internal/stream"
+	"debezium_server/pkg/configtemplate"
+	"debezium_server/pkg/logger"
+	"debezium_server/pkg/redact"
 	"net/http"
 	"strconv"
 	"time"
@@ -15,47 +23,207 @@ var (
 	defaultHeaderTimeout = time.Second * 5
 )
 
+// Dependencies are the shared clients the handlers are built from.
+type Dependencies struct {
+	DB        *pgxpool.Pool
+	Hub       *stream.Hub
+	Clusters  *cluster.Registry
+	Templates *configtemplate.Renderer
+	Redactor  *redact.Redactor
+	Logger    logger.Logger
+	// Auth is nil when authentication is disabled.
+	Auth auth.Authenticator
+	// Alerts is nil when alerting is disabled.
+	Alerts *alerting.Engine
+	// DeadLetters is nil unless the Kafka consumer keeps dead letters in
+	// Postgres.
+	DeadLetters *service.DeadLetterService
+	// Schemas is nil when the Kafka consumer is disabled.
+	Schemas *service.SchemaHistoryService
+	// Projector is nil when no projection is enabled.
+	Projector *projection.Projector
+}
+
 type Server struct {
-	srv *http.Server
-	db  *pgxpool.Pool
+	srv    *http.Server
+	deps   Dependencies
+	policy auth.Policy
 }
 
-func NewServer(port int, db *pgxpool.Pool) *Server {
+func NewServer(port int, deps Dependencies) *Server {
 	srv := http.Server{
 		Addr:              ":" + strconv.Itoa(port),
 		Handler:           nil,
 		ReadHeaderTimeout: defaultHeaderTimeout,
 	}
 	return &Server{
-		srv: &srv,
-		db:  db,
+		srv:    &srv,
+		deps:   deps,
+		policy: auth.DefaultPolicy(),
 	}
 }
 
 func (s *Server) RegisterHandlers() error {
-	userRepo := repository.NewUserRepository(s.db)
+	userRepo := repository.NewUserRepository(s.deps.DB)
 	userService := service.NewUserService(userRepo)
-	handler := NewHandlerFacade(userService)
+	users := NewHandlerFacade(userService)
+	changes := NewChangeStreamHandler(s.deps.Hub)
+	// Incremental snapshots are signalled through the default cluster's
+	// source database.
+	defaultCluster := s.deps.Clusters.Default()
+	snapshotService := service.NewSnapshotService(defaultCluster.Signals, defaultCluster.Client)
+	snapshots := NewSnapshotHandler(snapshotService)
+	auditRepo := repository.NewAuditRepository(s.deps.DB)
+	audit := NewAuditHandler(service.NewAuditService(auditRepo))
+	versionRepo := repository.NewVersionRepository(s.deps.DB)
+	services := make(map[string]ConnectorService)
+	for _, c := range s.deps.Clusters.All() {
+		services[c.Name] = service.NewConnectorService(
+			c.Name, c.Client, s.deps.Templates, s.deps.Redactor, auditRepo, versionRepo, s.deps.Logger,
+		)
+	}
+	connectors := NewConnectorHandler(services, s.deps.Clusters.Default().Name)
+	clusters := NewClusterHandler(service.NewClusterService(s.deps.Clusters, s.deps.Redactor, auditRepo, versionRepo))
+	backups := NewBackupHandler(
+		service.NewBackupService(s.deps.Clusters, s.deps.Templates, s.deps.Redactor, auditRepo, versionRepo),
+	)
 
 	mux := http.NewServeMux()
 
+	getUsers := s.allow(auth.PermUsersRead, users.GetUsers)
+	createUser := s.allow(auth.PermUsersWrite, users.CreateUser)
 	mux.HandleFunc("/api/v1/users", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
+		switch r.Method {
+		case http.MethodGet:
+			getUsers.ServeHTTP(w, r)
+		case http.MethodPost:
+			createUser.ServeHTTP(w, r)
+		default:
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-			return
 		}
-		handler.GetUsers(w, r)
 	})
 
-	s.srv.Handler = LoggingMiddleware()(mux)
+	mux.Handle("GET /api/v1/changes", s.allow(auth.PermChangesRead, changes.ServeWebSocket))
+	mux.Handle("GET /api/v1/changes/{table}", s.allow(auth.PermChangesRead, changes.ServeSSE))
+
+	s.registerConnectors(mux, "/api/v1/connectors", connectors)
+	s.registerConnectors(mux, "/api/v1/clusters/{cluster}/connectors", connectors)
+
+	mux.Handle("GET /api/v1/clusters", s.allow(auth.PermConnectorsRead, clusters.List))
+	mux.Handle("GET /api/v1/clusters/connectors", s.allow(auth.PermConnectorsRead, clusters.Connectors))
+	mux.Handle(
+		"POST /api/v1/clusters/{cluster}/connectors/{name}/copy",
+		s.allow(auth.PermConnectorsWrite, clusters.Copy),
+	)
+	mux.Handle(
+		"POST /api/v1/clusters/{cluster}/connectors/{name}/migrate",
+		s.allow(auth.PermConnectorsDelete, clusters.Migrate),
+	)
+	mux.Handle("GET /api/v1/backup", s.allow(auth.PermConnectorsBackup, backups.Export))
+	mux.Handle("POST /api/v1/restore", s.allow(auth.PermConnectorsWrite, backups.Restore))
+	mux.Handle("GET /api/v1/clusters/{cluster}/backup", s.allow(auth.PermConnectorsBackup, backups.Export))
+	mux.Handle("POST /api/v1/clusters/{cluster}/restore", s.allow(auth.PermConnectorsWrite, backups.Restore))
+	mux.Handle("GET /api/v1/clusters/{cluster}/info", s.allow(auth.PermConnectorsRead, clusters.Info))
+	mux.Handle("GET /api/v1/clusters/{cluster}/loggers", s.allow(auth.PermLoggingManage, clusters.Loggers))
+	mux.Handle("GET /api/v1/clusters/{cluster}/loggers/{logger}", s.allow(auth.PermLoggingManage, clusters.Logger))
+	mux.Handle("PUT /api/v1/clusters/{cluster}/loggers/{logger}", s.allow(auth.PermLoggingManage, clusters.SetLogger))
+
+	logLevel := NewLogLevelHandler(s.deps.Logger)
+	mux.Handle("GET /api/v1/admin/log-level", s.allow(auth.PermLoggingManage, logLevel.Get))
+	mux.Handle("PUT /api/v1/admin/log-level", s.allow(auth.PermLoggingManage, logLevel.Set))
+
+	mux.Handle("GET /api/v1/audit", s.allow(auth.PermAuditRead, audit.List))
+
+	if s.deps.Alerts != nil {
+		alerts := NewAlertHandler(s.deps.Alerts)
+		mux.Handle("GET /api/v1/alerts", s.allow(auth.PermConnectorsRead, alerts.List))
+	}
+
+	if s.deps.DeadLetters != nil {
+		deadLetters := NewDeadLetterHandler(s.deps.DeadLetters)
+		mux.Handle("GET /api/v1/dead-letters", s.allow(auth.PermDeadLettersRead, deadLetters.List))
+		mux.Handle("GET /api/v1/dead-letters/{id}", s.allow(auth.PermDeadLettersRead, deadLetters.Get))
+		mux.Handle("POST /api/v1/dead-letters/{id}/replay", s.allow(auth.PermDeadLettersWrite, deadLetters.Replay))
+		mux.Handle("DELETE /api/v1/dead-letters/{id}", s.allow(auth.PermDeadLettersWrite, deadLetters.Discard))
+	}
+
+	if s.deps.Schemas != nil {
+		schemas := NewSchemaHistoryHandler(s.deps.Schemas)
+		mux.Handle("GET /api/v1/schemas/{table}/history", s.allow(auth.PermSchemasRead, schemas.History))
+		mux.Handle("POST /api/v1/schemas/{table}/check", s.allow(auth.PermSchemasRead, schemas.Check))
+	}
+
+	if s.deps.Projector != nil {
+		projections := NewProjectionHandler(
+			service.NewProjectionService(s.deps.Projector, snapshotService),
+		)
+		mux.Handle("GET /api/v1/projections", s.allow(auth.PermProjectionsRead, projections.List))
+		mux.Handle("POST /api/v1/projections/{name}/rebuild", s.allow(auth.PermProjectionsWrite, projections.Rebuild))
+	}
+
+	mux.Handle("GET /api/v1/connectors/{name}/snapshots", s.allow(auth.PermSnapshotsRead, snapshots.List))
+	mux.Handle("POST /api/v1/connectors/{name}/snapshots", s.allow(auth.PermSnapshotsWrite, snapshots.Execute))
+	mux.Handle("GET /api/v1/connectors/{name}/snapshots/{id}", s.allow(auth.PermSnapshotsRead, snapshots.Get))
+	mux.Handle("POST /api/v1/connectors/{name}/snapshots/stop", s.allow(auth.PermSnapshotsWrite, snapshots.Stop))
+	mux.Handle("POST /api/v1/connectors/{name}/snapshots/pause", s.allow(auth.PermSnapshotsWrite, snapshots.Pause))
+	mux.Handle("POST /api/v1/connectors/{name}/snapshots/resume", s.allow(auth.PermSnapshotsWrite, snapshots.Resume))
+
+	handler := nameSpanByRoute(mux)
+	if s.deps.Auth != nil {
+		handler = AuthMiddleware(auth.WithRoles(s.deps.Auth, userRepo))(handler)
+	}
+	s.srv.Handler = TracingMiddleware()(LoggingMiddleware()(AccessLogMiddleware(s.deps.Logger)(handler)))
 
 	return nil
 }
 
+// registerConnectors registers the connector routes under prefix.
+func (s *Server) registerConnectors(mux *http.ServeMux, prefix string, connectors *ConnectorHandler) {
+	mux.Handle("GET "+prefix, s.allow(auth.PermConnectorsRead, connectors.List))
+	mux.Handle("POST "+prefix, s.allow(auth.PermConnectorsWrite, connectors.Create))
+	mux.Handle("GET "+prefix+"/{name}", s.allow(auth.PermConnectorsRead, connectors.Get))
+	mux.Handle("PUT "+prefix+"/{name}", s.allow(auth.PermConnectorsWrite, connectors.Upsert))
+	mux.Handle("DELETE "+prefix+"/{name}", s.allow(auth.PermConnectorsDelete, connectors.Delete))
+	mux.Handle("GET "+prefix+"/{name}/status", s.allow(auth.PermConnectorsRead, connectors.Status))
+	mux.Handle("GET "+prefix+"/{name}/tasks", s.allow(auth.PermConnectorsRead, connectors.Tasks))
+	mux.Handle("POST "+prefix+"/validate", s.allow(auth.PermConnectorsRead, connectors.Validate))
+	mux.Handle("PUT "+prefix+"/{name}/config", s.allow(auth.PermConnectorsWrite, connectors.UpdateConfig))
+	mux.Handle("POST "+prefix+"/{name}/pause", s.allow(auth.PermConnectorsOperate, connectors.Pause))
+	mux.Handle("POST "+prefix+"/{name}/resume", s.allow(auth.PermConnectorsOperate, connectors.Resume))
+	mux.Handle("POST "+prefix+"/{name}/restart", s.allow(auth.PermConnectorsOperate, connectors.Restart))
+	mux.Handle("POST "+prefix+"/{name}/stop", s.allow(auth.PermConnectorsOperate, connectors.Stop))
+	mux.Handle(
+		"POST "+prefix+"/{name}/tasks/{task}/restart",
+		s.allow(auth.PermConnectorsOperate, connectors.RestartTask),
+	)
+	mux.Handle("GET "+prefix+"/{name}/offsets", s.allow(auth.PermConnectorsRead, connectors.Offsets))
+	mux.Handle("PATCH "+prefix+"/{name}/offsets", s.allow(auth.PermConnectorsResetOffsets, connectors.AlterOffsets))
+	mux.Handle("DELETE "+prefix+"/{name}/offsets", s.allow(auth.PermConnectorsResetOffsets, connectors.ResetOffsets))
+	mux.Handle("GET "+prefix+"/{name}/topics", s.allow(auth.PermConnectorsRead, connectors.Topics))
+	mux.Handle("PUT "+prefix+"/{name}/topics/reset", s.allow(auth.PermConnectorsOperate, connectors.ResetTopics))
+	mux.Handle("GET "+prefix+"/{name}/versions", s.allow(auth.PermConnectorsRead, connectors.Versions))
+	mux.Handle("GET "+prefix+"/{name}/versions/diff", s.allow(auth.PermConnectorsRead, connectors.DiffVersions))
+	mux.Handle("GET "+prefix+"/{name}/versions/{version}", s.allow(auth.PermConnectorsRead, connectors.Version))
+	mux.Handle(
+		"POST "+prefix+"/{name}/versions/{version}/rollback",
+		s.allow(auth.PermConnectorsWrite, connectors.Rollback),
+	)
+}
+
+// allow guards h with the RBAC policy. Authorization is skipped when
+// authentication is disabled, since there is no principal to check.
+func (s *Server) allow(perm auth.Permission, h http.HandlerFunc) http.Handler {
+	if s.deps.Auth == nil {
+		return h
+	}
+	return RequirePermission(s.policy, perm)(h)
+}
+
 func (s *Server) Start() error {
 	return s.srv.ListenAndServe()
 }
 
 func (s *Server) Stop(ctx context.Context) error {
+	s.deps.Hub.Close()
 	return s.srv.Shutdown(ctx)
 }
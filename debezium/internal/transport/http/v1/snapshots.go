@@ -0,0 +1,137 @@
+package v1
+
+import (
+	"context"
+	"debezium_server/internal/service"
+	dto "debezium_server/internal/transport/http/models"
+	"debezium_server/pkg/signaling"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+type SnapshotService interface {
+	Execute(ctx context.Context, connector string, data signaling.SnapshotData) (service.SnapshotProgress, error)
+	Stop(ctx context.Context, connector string, data signaling.SnapshotData) error
+	Pause(ctx context.Context, connector string) error
+	Resume(ctx context.Context, connector string) error
+	Progress(ctx context.Context, connector, signalID string) (service.SnapshotProgress, error)
+	List(ctx context.Context, connector string) ([]service.SnapshotProgress, error)
+}
+
+type SnapshotHandler struct {
+	service SnapshotService
+}
+
+func NewSnapshotHandler(service SnapshotService) *SnapshotHandler {
+	return &SnapshotHandler{service: service}
+}
+
+func (h *SnapshotHandler) Execute(w http.ResponseWriter, r *http.Request) {
+	data, err := decodeSnapshotRequest(r)
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	progress, err := h.service.Execute(r.Context(), r.PathValue("name"), data)
+	if err != nil {
+		writeSnapshotError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, progress)
+}
+
+func (h *SnapshotHandler) Stop(w http.ResponseWriter, r *http.Request) {
+	data, err := decodeSnapshotRequest(r)
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Stop(r.Context(), r.PathValue("name"), data); err != nil {
+		writeSnapshotError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *SnapshotHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Pause(r.Context(), r.PathValue("name")); err != nil {
+		writeSnapshotError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *SnapshotHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Resume(r.Context(), r.PathValue("name")); err != nil {
+		writeSnapshotError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *SnapshotHandler) Get(w http.ResponseWriter, r *http.Request) {
+	progress, err := h.service.Progress(r.Context(), r.PathValue("name"), r.PathValue("id"))
+	if err != nil {
+		writeSnapshotError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, progress)
+}
+
+func (h *SnapshotHandler) List(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := h.service.List(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeSnapshotError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, snapshots)
+}
+
+// decodeSnapshotRequest accepts an empty body for stop requests that target
+// every data collection.
+func decodeSnapshotRequest(r *http.Request) (signaling.SnapshotData, error) {
+	var req dto.SnapshotRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return signaling.SnapshotData{}, err
+	}
+
+	data := signaling.SnapshotData{
+		DataCollections: req.DataCollections,
+		Type:            signaling.SnapshotType(req.Type),
+	}
+	for _, cond := range req.AdditionalConditions {
+		data.AdditionalConditions = append(data.AdditionalConditions, signaling.AdditionalCondition{
+			DataCollection: cond.DataCollection,
+			Filter:         cond.Filter,
+		})
+	}
+
+	return data, nil
+}
+
+func writeSnapshotError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrSnapshotNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, signaling.ErrNoDataCollections),
+		errors.Is(err, signaling.ErrUnknownSnapshotType),
+		errors.Is(err, signaling.ErrConditionCollection):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, signaling.ErrNoSignalTable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, signaling.ErrNoSignalDatabase):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
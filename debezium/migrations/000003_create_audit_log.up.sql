@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    principal VARCHAR(255) NOT NULL,
+    auth_method VARCHAR(32) NOT NULL DEFAULT '',
+    request_id VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL,
+    connector VARCHAR(255) NOT NULL,
+    diff JSONB NOT NULL DEFAULT '{}',
+    result VARCHAR(16) NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_connector_created_at_idx ON audit_log (connector, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_principal_idx ON audit_log (principal);
+
+-- Answers "who changed this config key" via diff ? 'key'.
+CREATE INDEX IF NOT EXISTS audit_log_diff_idx ON audit_log USING GIN (diff);
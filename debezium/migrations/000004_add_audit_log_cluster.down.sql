@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS audit_log_cluster_connector_idx;
+
+ALTER TABLE audit_log DROP COLUMN IF EXISTS cluster;
@@ -0,0 +1,3 @@
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS cluster VARCHAR(255) NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS audit_log_cluster_connector_idx ON audit_log (cluster, connector);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS connector_versions (
+    id BIGSERIAL PRIMARY KEY,
+    cluster VARCHAR(255) NOT NULL,
+    connector VARCHAR(255) NOT NULL,
+    version INTEGER NOT NULL,
+    -- Secret values are stored masked; a rollback keeps the current secrets.
+    config JSONB NOT NULL,
+    author VARCHAR(255) NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    request_id VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (cluster, connector, version)
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS cdc_dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    kafka_partition INTEGER NOT NULL,
+    kafka_offset BIGINT NOT NULL,
+    message_key BYTEA,
+    -- NULL for a tombstone.
+    message_value BYTEA,
+    headers JSONB NOT NULL DEFAULT '[]',
+    message_time TIMESTAMPTZ NOT NULL,
+    error TEXT NOT NULL,
+    -- Handler calls so far, including failed replays.
+    attempts INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- A message delivered again after a crash is stored once.
+    UNIQUE (topic, kafka_partition, kafka_offset)
+);
+
+CREATE INDEX IF NOT EXISTS cdc_dead_letters_created_at_idx ON cdc_dead_letters (created_at);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS cdc_schema_history;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS cdc_schema_history (
+    id BIGSERIAL PRIMARY KEY,
+    table_name VARCHAR(255) NOT NULL,
+    -- event: taken from the schema of change events; ddl: from the schema
+    -- change topic. Types are named differently, so each is compared with
+    -- its own previous version only.
+    source VARCHAR(16) NOT NULL,
+    ddl TEXT,
+    columns JSONB NOT NULL,
+    -- Differences from the previous version of the same source.
+    changes JSONB NOT NULL DEFAULT '[]',
+    breaking BOOLEAN NOT NULL DEFAULT FALSE,
+    -- The first message with the schema.
+    topic VARCHAR(255) NOT NULL,
+    kafka_partition INTEGER NOT NULL,
+    kafka_offset BIGINT NOT NULL,
+    message_time TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- A message delivered again after a crash is stored once.
+    UNIQUE (topic, kafka_partition, kafka_offset, table_name)
+);
+
+CREATE INDEX IF NOT EXISTS cdc_schema_history_table_idx ON cdc_schema_history (table_name, source, id);
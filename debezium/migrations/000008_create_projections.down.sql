@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS order_summaries;
+DROP TABLE IF EXISTS projections;
+DROP TABLE IF EXISTS projection_rows;
@@ -0,0 +1,42 @@
+-- The latest version of every source row a projection reads. Deleted rows
+-- are kept with deleted set, so an older event replayed later is ignored.
+CREATE TABLE IF NOT EXISTS projection_rows (
+    projection VARCHAR(100) NOT NULL,
+    source_table VARCHAR(255) NOT NULL,
+    -- JSON array of the primary key values.
+    row_key TEXT NOT NULL,
+    data JSONB NOT NULL,
+    -- Source LSN of the change that wrote the row; 0 if the event had none.
+    lsn BIGINT NOT NULL,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (projection, source_table, row_key)
+);
+
+CREATE INDEX IF NOT EXISTS projection_rows_data_idx ON projection_rows USING GIN (data jsonb_path_ops);
+
+CREATE TABLE IF NOT EXISTS projections (
+    name VARCHAR(100) PRIMARY KEY,
+    lsn BIGINT NOT NULL DEFAULT 0,
+    applied_at TIMESTAMPTZ,
+    rebuild_signal_id VARCHAR(64),
+    rebuild_requested_at TIMESTAMPTZ
+);
+
+-- Read model of the order_summaries projection.
+CREATE TABLE IF NOT EXISTS order_summaries (
+    order_id BIGINT PRIMARY KEY,
+    customer_id BIGINT,
+    customer_name VARCHAR(201),
+    customer_email VARCHAR(255),
+    status VARCHAR(50),
+    order_date TIMESTAMP,
+    total_amount NUMERIC(10, 2),
+    item_count INTEGER NOT NULL,
+    total_quantity BIGINT NOT NULL,
+    items_total NUMERIC(12, 2) NOT NULL,
+    source_lsn BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS order_summaries_customer_id_idx ON order_summaries (customer_id);
@@ -0,0 +1 @@
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS task_id INTEGER NULL;
@@ -0,0 +1,218 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatJSON  Format = "json"
+	FormatTar   Format = "tar"
+	FormatTarGz Format = "tar.gz"
+)
+
+const (
+	manifestFile  = "manifest.json"
+	connectorsDir = "connectors/"
+
+	// maxEntrySize bounds a single file of a tar archive.
+	maxEntrySize = 64 << 20
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown backup archive format")
+)
+
+// ParseFormat accepts json, tar and tar.gz (or tgz).
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "json":
+		return FormatJSON, nil
+	case "tar":
+		return FormatTar, nil
+	case "tar.gz", "tgz":
+		return FormatTarGz, nil
+	}
+	return "", fmt.Errorf("%w %q, use json, tar or tar.gz", ErrUnknownFormat, s)
+}
+
+// FormatFromPath picks the format from the file extension, JSON by default.
+func FormatFromPath(p string) Format {
+	p = strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+		return FormatTarGz
+	case strings.HasSuffix(p, ".tar"):
+		return FormatTar
+	default:
+		return FormatJSON
+	}
+}
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatTar:
+		return "application/x-tar"
+	case FormatTarGz:
+		return "application/gzip"
+	default:
+		return "application/json"
+	}
+}
+
+// Extension returns the file extension of the format, with the dot.
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// Write encodes the archive in the given format. A tar archive holds a
+// manifest.json without the connectors and one connectors/<name>.json per
+// connector, with the name escaped so a "/" or ".." in it cannot move the
+// file out of connectors/.
+func Write(w io.Writer, a *Archive, format Format) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(a)
+	case FormatTar:
+		return writeTar(w, a)
+	case FormatTarGz:
+		gz := gzip.NewWriter(w)
+		if err := writeTar(gz, a); err != nil {
+			return err
+		}
+		return gz.Close()
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+func writeTar(w io.Writer, a *Archive) error {
+	tw := tar.NewWriter(w)
+
+	manifest := *a
+	manifest.Connectors = nil
+	if err := writeTarFile(tw, manifestFile, manifest, a.CreatedAt); err != nil {
+		return err
+	}
+	for _, c := range a.Connectors {
+		if err := writeTarFile(tw, connectorEntry(c.Name), c, a.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+// connectorEntry returns the file name of a connector in a tar archive. Read
+// takes the name from the file content, so only the file name is escaped.
+func connectorEntry(name string) string {
+	return connectorsDir + url.PathEscape(name) + ".json"
+}
+
+func writeTarFile(tw *tar.Writer, name string, v any, modTime time.Time) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Read decodes an archive in any of the formats, detected from its content.
+func Read(r io.Reader) (*Archive, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+
+	switch {
+	case len(head) == 2 && head[0] == 0x1f && head[1] == 0x8b:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		defer gz.Close()
+		return readTar(gz)
+	case len(bytes.TrimSpace(head)) > 0 && bytes.TrimSpace(head)[0] == '{':
+		var a Archive
+		if err := json.NewDecoder(br).Decode(&a); err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		return &a, nil
+	default:
+		return readTar(br)
+	}
+}
+
+func readTar(r io.Reader) (*Archive, error) {
+	tr := tar.NewReader(r)
+
+	var (
+		a          *Archive
+		connectors []Connector
+	)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Size > maxEntrySize {
+			return nil, fmt.Errorf("read archive: %s is larger than %d bytes", hdr.Name, maxEntrySize)
+		}
+
+		name := path.Clean(hdr.Name)
+		switch {
+		case name == manifestFile:
+			a = &Archive{}
+			if err := json.NewDecoder(tr).Decode(a); err != nil {
+				return nil, fmt.Errorf("read archive: %s: %w", name, err)
+			}
+		case strings.HasPrefix(name, connectorsDir) && strings.HasSuffix(name, ".json"):
+			var c Connector
+			if err := json.NewDecoder(tr).Decode(&c); err != nil {
+				return nil, fmt.Errorf("read archive: %s: %w", name, err)
+			}
+			connectors = append(connectors, c)
+		}
+	}
+
+	if a == nil {
+		return nil, fmt.Errorf("read archive: no %s", manifestFile)
+	}
+	a.Connectors = connectors
+
+	return a, nil
+}
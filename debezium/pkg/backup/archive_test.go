@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	debezium_client "debezium_server/pkg/debezium-client"
+	"io"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testArchive() *Archive {
+	return &Archive{
+		Version:   FormatVersion,
+		CreatedAt: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		Cluster:   "primary",
+		Connectors: []Connector{
+			{
+				Name: "inventory",
+				Type: "source",
+				Config: map[string]string{
+					"connector.class":   "io.debezium.connector.postgresql.PostgresConnector",
+					"database.password": "s3cret",
+				},
+				Offsets: []debezium_client.ConnectorOffset{{
+					Partition: map[string]any{"server": "inventory"},
+					Offset:    map[string]any{"lsn": float64(24023128), "txId": float64(555)},
+				}},
+				Status: debezium_client.ConnectorStatus{
+					Name:      "inventory",
+					Connector: debezium_client.ConnectorState{State: debezium_client.StatePaused},
+				},
+			},
+			// Names Kafka Connect accepts but that are not file names.
+			{Name: "orders/v2", Config: map[string]string{"tasks.max": "1"}},
+			{Name: "../../etc/cron.d/x", Config: map[string]string{"tasks.max": "1"}},
+			{Name: "..", Config: map[string]string{"tasks.max": "1"}},
+		},
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatTar, FormatTarGz} {
+		t.Run(string(format), func(t *testing.T) {
+			want := testArchive()
+
+			var buf bytes.Buffer
+			if err := Write(&buf, want, format); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			got, err := Read(&buf)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("read back\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
+
+// TestTarEntryNames checks that every connector is stored as a file
+// directly in connectors/, whatever its name.
+func TestTarEntryNames(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testArchive(), FormatTarGz); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+
+	tr := tar.NewReader(gz)
+	var connectors int
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if hdr.Name == manifestFile {
+			continue
+		}
+		connectors++
+		if dir, _ := path.Split(path.Clean(hdr.Name)); dir != connectorsDir {
+			t.Errorf("entry %s is not in %s", hdr.Name, connectorsDir)
+		}
+	}
+	if connectors != len(testArchive().Connectors) {
+		t.Errorf("got %d connector entries, want %d", connectors, len(testArchive().Connectors))
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	var noManifest bytes.Buffer
+	tw := tar.NewWriter(&noManifest)
+	if err := writeTarFile(tw, connectorEntry("inventory"), Connector{Name: "inventory"}, time.Now()); err != nil {
+		t.Fatalf("writeTarFile: %v", err)
+	}
+	tw.Close()
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "tar without manifest", data: noManifest.Bytes(), want: "no manifest.json"},
+		{name: "broken JSON", data: []byte(`{"version": 1, "connectors": [`), want: "read archive"},
+		{name: "broken gzip", data: []byte{0x1f, 0x8b, 0x00}, want: "read archive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Read returned %v, want an error with %q", err, tt.want)
+			}
+		})
+	}
+}
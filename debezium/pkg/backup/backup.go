@@ -0,0 +1,319 @@
+package backup
+
+import (
+	"context"
+	debezium_client "debezium_server/pkg/debezium-client"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FormatVersion is the version of the archive layout written by Export.
+const FormatVersion = 1
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported backup archive version")
+	ErrNotInArchive       = errors.New("connector is not in the archive")
+	ErrDuplicateTarget    = errors.New("two connectors are restored under the same name")
+	ErrMaskedSecrets      = errors.New("masked secrets need an override")
+)
+
+// Client is the part of the Kafka Connect client a backup needs.
+type Client interface {
+	ListConnectors(ctx context.Context, expandStatus bool) (debezium_client.ListConnectorsResponse, error)
+	GetConnector(ctx context.Context, name string) (debezium_client.GetConnectorResponse, error)
+	GetConnectorStatus(ctx context.Context, name string) (debezium_client.ConnectorStatus, error)
+	GetConnectorOffsets(ctx context.Context, name string) (debezium_client.ConnectorOffsets, error)
+	CreateConnector(
+		ctx context.Context,
+		data debezium_client.CreateConnectorRequest,
+	) (*debezium_client.CreateConnectorResponse, error)
+	AlterConnectorOffsets(ctx context.Context, name string, offsets debezium_client.ConnectorOffsets) error
+	ResumeConnector(ctx context.Context, name string) error
+	PauseConnector(ctx context.Context, name string) error
+	DeleteConnector(ctx context.Context, name string) error
+}
+
+// Masker tells the secrets that an export without secrets has masked.
+type Masker interface {
+	Mask() string
+	IsSecret(key string) bool
+}
+
+// Archive is a snapshot of the connectors of one Kafka Connect cluster.
+type Archive struct {
+	Version    int         `json:"version"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Cluster    string      `json:"cluster,omitempty"`
+	Connectors []Connector `json:"connectors"`
+}
+
+// Connector is the config, source offsets and status of one connector at
+// the time of the backup.
+type Connector struct {
+	Name    string                            `json:"name"`
+	Type    string                            `json:"type,omitempty"`
+	Config  map[string]string                 `json:"config"`
+	Offsets []debezium_client.ConnectorOffset `json:"offsets"`
+	Status  debezium_client.ConnectorStatus   `json:"status"`
+}
+
+// State returns the state the connector had, RUNNING if it was unknown.
+func (c Connector) State() string {
+	switch c.Status.Connector.State {
+	case debezium_client.StatePaused, debezium_client.StateStopped:
+		return c.Status.Connector.State
+	default:
+		return debezium_client.StateRunning
+	}
+}
+
+// Export snapshots the named connectors, or every connector of the cluster
+// if names is empty. A connector that cannot be read fails the export, so
+// an archive is always complete.
+func Export(ctx context.Context, client Client, cluster string, names ...string) (*Archive, error) {
+	if len(names) == 0 {
+		list, err := client.ListConnectors(ctx, false)
+		if err != nil {
+			return nil, fmt.Errorf("list connectors: %w", err)
+		}
+		names = list.Names
+	}
+	names = slices.Clone(names)
+	sort.Strings(names)
+	names = slices.Compact(names)
+
+	a := &Archive{
+		Version:    FormatVersion,
+		CreatedAt:  time.Now().UTC(),
+		Cluster:    cluster,
+		Connectors: make([]Connector, 0, len(names)),
+	}
+	for _, name := range names {
+		c, err := exportConnector(ctx, client, name)
+		if err != nil {
+			return nil, fmt.Errorf("connector %s: %w", name, err)
+		}
+		a.Connectors = append(a.Connectors, c)
+	}
+
+	return a, nil
+}
+
+func exportConnector(ctx context.Context, client Client, name string) (Connector, error) {
+	info, err := client.GetConnector(ctx, name)
+	if err != nil {
+		return Connector{}, fmt.Errorf("config: %w", err)
+	}
+	status, err := client.GetConnectorStatus(ctx, name)
+	if err != nil {
+		return Connector{}, fmt.Errorf("status: %w", err)
+	}
+	offsets, err := client.GetConnectorOffsets(ctx, name)
+	if err != nil {
+		return Connector{}, fmt.Errorf("offsets: %w", err)
+	}
+
+	config := make(map[string]string, len(info.Config))
+	for k, v := range info.Config {
+		config[k] = fmt.Sprint(v)
+	}
+
+	return Connector{
+		Name:    name,
+		Type:    info.Type,
+		Config:  config,
+		Offsets: offsets.Offsets,
+		Status:  status,
+	}, nil
+}
+
+// RestoreOptions select and adjust the connectors of an archive.
+type RestoreOptions struct {
+	// Connectors limits the restore to these archived names.
+	Connectors []string
+	// Rename maps archived names to the names to restore them under.
+	Rename map[string]string
+	// Overrides are set in the config of every restored connector.
+	Overrides map[string]string
+	// SkipOffsets restores the configs only.
+	SkipOffsets bool
+	// Masker, if set, fails the connectors whose config still holds a
+	// masked secret.
+	Masker Masker
+}
+
+// Planned is an archived connector as it will be restored.
+type Planned struct {
+	Source string
+	Connector
+}
+
+// Plan returns the connectors of the archive selected by opts, renamed and
+// with the overrides applied.
+func (a *Archive) Plan(opts RestoreOptions) ([]Planned, error) {
+	if a.Version != FormatVersion {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, a.Version)
+	}
+
+	selected := a.Connectors
+	if len(opts.Connectors) > 0 {
+		byName := make(map[string]Connector, len(a.Connectors))
+		for _, c := range a.Connectors {
+			byName[c.Name] = c
+		}
+		selected = make([]Connector, 0, len(opts.Connectors))
+		for _, name := range opts.Connectors {
+			c, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrNotInArchive, name)
+			}
+			selected = append(selected, c)
+		}
+	}
+
+	planned := make([]Planned, 0, len(selected))
+	targets := make(map[string]string, len(selected))
+	for _, c := range selected {
+		p := Planned{Source: c.Name, Connector: c}
+		if name, ok := opts.Rename[c.Name]; ok && name != "" {
+			p.Name = name
+		}
+		if prev, ok := targets[p.Name]; ok {
+			return nil, fmt.Errorf("%w %s: %s and %s", ErrDuplicateTarget, p.Name, prev, c.Name)
+		}
+		targets[p.Name] = c.Name
+
+		p.Config = maps.Clone(c.Config)
+		maps.Copy(p.Config, opts.Overrides)
+		p.Config["name"] = p.Name
+		if opts.SkipOffsets {
+			p.Offsets = nil
+		}
+		planned = append(planned, p)
+	}
+
+	return planned, nil
+}
+
+// RestoreConnector creates the connector and brings it back to its archived
+// state. With offsets it is created stopped so the offsets can be written
+// before it starts. A connector that was created is deleted again if a
+// later step fails.
+func RestoreConnector(ctx context.Context, client Client, c Connector) error {
+	create := debezium_client.CreateConnectorRequest{
+		Name:         c.Name,
+		Config:       debezium_client.NewCreateConnectorConfig(c.Config),
+		InitialState: c.State(),
+	}
+	if len(c.Offsets) > 0 {
+		create.InitialState = debezium_client.StateStopped
+	}
+	if _, err := client.CreateConnector(ctx, create); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if len(c.Offsets) == 0 {
+		return nil
+	}
+
+	fail := func(err error) error {
+		if delErr := client.DeleteConnector(context.WithoutCancel(ctx), c.Name); delErr != nil {
+			return errors.Join(err, fmt.Errorf("delete after failed restore: %w", delErr))
+		}
+		return err
+	}
+
+	offsets := debezium_client.ConnectorOffsets{Offsets: c.Offsets}
+	if err := client.AlterConnectorOffsets(ctx, c.Name, offsets); err != nil {
+		return fail(fmt.Errorf("write offsets: %w", err))
+	}
+
+	// A stopped connector is resumed into RUNNING, so a paused one is
+	// paused afterwards.
+	switch c.State() {
+	case debezium_client.StateRunning:
+		if err := client.ResumeConnector(ctx, c.Name); err != nil {
+			return fail(fmt.Errorf("resume: %w", err))
+		}
+	case debezium_client.StatePaused:
+		if err := client.ResumeConnector(ctx, c.Name); err != nil {
+			return fail(fmt.Errorf("resume: %w", err))
+		}
+		if err := client.PauseConnector(ctx, c.Name); err != nil {
+			return fail(fmt.Errorf("pause: %w", err))
+		}
+	}
+
+	return nil
+}
+
+// Result is the outcome of restoring one connector.
+type Result struct {
+	Source string `json:"source"`
+	Name   string `json:"name"`
+	State  string `json:"state,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Restore recreates the connectors of the archive selected by opts. A
+// connector that fails does not stop the others; its error is in its
+// result.
+func Restore(ctx context.Context, client Client, a *Archive, opts RestoreOptions) ([]Result, error) {
+	planned, err := a.Plan(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(planned))
+	for _, p := range planned {
+		r := Result{Source: p.Source, Name: p.Name}
+		err := CheckMasked(p.Config, opts.Masker)
+		if err == nil {
+			err = RestoreConnector(ctx, client, p.Connector)
+		}
+		if err != nil {
+			r.Error = err.Error()
+		} else {
+			r.State = p.State()
+		}
+		results = append(results, r)
+	}
+
+	return results, nil
+}
+
+// CheckMasked rejects a config that still holds masked secrets, e.g. from
+// an export without secrets, since the connector would get the mask as
+// its value. A nil m accepts every config.
+func CheckMasked(config map[string]string, m Masker) error {
+	if m == nil {
+		return nil
+	}
+
+	var masked []string
+	for k, v := range config {
+		if v == m.Mask() && m.IsSecret(k) {
+			masked = append(masked, k)
+		}
+	}
+	if len(masked) == 0 {
+		return nil
+	}
+
+	sort.Strings(masked)
+	return fmt.Errorf("%w: %s", ErrMaskedSecrets, strings.Join(masked, ", "))
+}
+
+// Failed reports whether any connector of the restore failed.
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Error != "" {
+			return true
+		}
+	}
+	return false
+}
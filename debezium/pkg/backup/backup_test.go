@@ -0,0 +1,98 @@
+package backup
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  RestoreOptions
+		names []string
+		err   error
+	}{
+		{name: "all", names: []string{"inventory", "orders/v2", "../../etc/cron.d/x", ".."}},
+		{name: "selected", opts: RestoreOptions{Connectors: []string{"orders/v2", "inventory"}}, names: []string{"orders/v2", "inventory"}},
+		{name: "not in archive", opts: RestoreOptions{Connectors: []string{"billing"}}, err: ErrNotInArchive},
+		{
+			name:  "renamed",
+			opts:  RestoreOptions{Connectors: []string{"inventory"}, Rename: map[string]string{"inventory": "inventory-dr", "billing": "x"}},
+			names: []string{"inventory-dr"},
+		},
+		{
+			name: "two connectors under one name",
+			opts: RestoreOptions{Rename: map[string]string{"orders/v2": "inventory"}},
+			err:  ErrDuplicateTarget,
+		},
+		{
+			name: "swapped names",
+			opts: RestoreOptions{
+				Connectors: []string{"inventory", ".."},
+				Rename:     map[string]string{"inventory": "..", "..": "inventory"},
+			},
+			names: []string{"..", "inventory"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planned, err := testArchive().Plan(tt.opts)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Plan returned %v, want %v", err, tt.err)
+			}
+			var names []string
+			for _, p := range planned {
+				names = append(names, p.Name)
+				if p.Config["name"] != p.Name {
+					t.Errorf("%s has the name %q in its config", p.Name, p.Config["name"])
+				}
+			}
+			if !slices.Equal(names, tt.names) {
+				t.Errorf("planned %v, want %v", names, tt.names)
+			}
+		})
+	}
+}
+
+func TestPlanOverrides(t *testing.T) {
+	a := testArchive()
+	planned, err := a.Plan(RestoreOptions{
+		Connectors:  []string{"inventory"},
+		Rename:      map[string]string{"inventory": "inventory-dr"},
+		Overrides:   map[string]string{"database.password": "n3w", "database.hostname": "pg-dr"},
+		SkipOffsets: true,
+	})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+
+	p := planned[0]
+	if p.Source != "inventory" || p.Name != "inventory-dr" {
+		t.Errorf("planned %s as %s, want inventory as inventory-dr", p.Source, p.Name)
+	}
+	if p.Config["database.password"] != "n3w" || p.Config["database.hostname"] != "pg-dr" {
+		t.Errorf("the overrides were not applied: %v", p.Config)
+	}
+	if p.Offsets != nil {
+		t.Errorf("got offsets %v, want none", p.Offsets)
+	}
+	if p.State() != "PAUSED" {
+		t.Errorf("state is %s, want the archived PAUSED", p.State())
+	}
+
+	// The archive is left as it was.
+	c := a.Connectors[0]
+	if c.Config["database.password"] != "s3cret" || c.Config["name"] != "" || len(c.Offsets) != 1 {
+		t.Errorf("Plan changed the archive: %+v", c)
+	}
+}
+
+func TestPlanVersion(t *testing.T) {
+	a := testArchive()
+	a.Version = FormatVersion + 1
+	if _, err := a.Plan(RestoreOptions{}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Plan returned %v, want %v", err, ErrUnsupportedVersion)
+	}
+}
@@ -0,0 +1,167 @@
+// Package avro decodes change events written by the Confluent Avro
+// converter: a zero magic byte, the big-endian schema ID and the Avro
+// binary encoding of the value.
+package avro
+
+import (
+	"context"
+	"debezium_server/pkg/cdc"
+	"debezium_server/pkg/cdc/kafka"
+	"debezium_server/pkg/schemaregistry"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+const (
+	magicByte  = 0
+	headerSize = 5
+)
+
+var (
+	ErrNotWireFormat     = errors.New("value is not in the Confluent wire format")
+	ErrUnsupportedSchema = errors.New("schema is not an Avro schema")
+)
+
+// Decoder decodes wire format values into change events. Schemas are
+// fetched from the registry once per ID.
+type Decoder struct {
+	registry     schemaregistry.Registry
+	jsonFallback bool
+
+	mu      sync.Mutex
+	schemas map[int]*compiled
+}
+
+type Option func(*Decoder)
+
+// WithJSONFallback decodes values that are not in the wire format as JSON,
+// so topics can move from the JSON to the Avro converter one at a time.
+func WithJSONFallback() Option {
+	return func(d *Decoder) {
+		d.jsonFallback = true
+	}
+}
+
+func NewDecoder(registry schemaregistry.Registry, opts ...Option) *Decoder {
+	d := &Decoder{registry: registry, schemas: make(map[int]*compiled)}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+var _ kafka.Decoder = (*Decoder)(nil)
+
+// Decode returns the same ChangeEvent as cdc.DecodeJSON would for the value
+// written by the JSON converter with schemas.enable.
+func (d *Decoder) Decode(ctx context.Context, msg kafka.Message) (cdc.ChangeEvent, error) {
+	if !IsWireFormat(msg.Value) && d.jsonFallback {
+		return cdc.DecodeJSON(msg.Value)
+	}
+
+	s, native, err := d.decode(ctx, msg.Value)
+	if err != nil {
+		return cdc.ChangeEvent{}, err
+	}
+	if native == nil {
+		return cdc.ChangeEvent{}, cdc.ErrEmptyEvent
+	}
+
+	data, err := json.Marshal(native)
+	if err != nil {
+		return cdc.ChangeEvent{}, fmt.Errorf("Decode.Marshal: %w", err)
+	}
+	event, err := cdc.DecodeJSON(data)
+	if err != nil {
+		return cdc.ChangeEvent{}, err
+	}
+	event.Schema = s.rowSchema
+	return event, nil
+}
+
+// Native decodes a wire format value into maps, slices and scalars shaped
+// like the output of the JSON converter: unions are unwrapped, timestamps
+// are epoch numbers and decimals are float64.
+func (d *Decoder) Native(ctx context.Context, data []byte) (any, error) {
+	_, native, err := d.decode(ctx, data)
+	return native, err
+}
+
+func (d *Decoder) decode(ctx context.Context, data []byte) (*compiled, any, error) {
+	if !IsWireFormat(data) {
+		return nil, nil, ErrNotWireFormat
+	}
+
+	id := int(binary.BigEndian.Uint32(data[1:headerSize]))
+	s, err := d.schema(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	native, rest, err := s.codec.NativeFromBinary(data[headerSize:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("Native: schema %d: %w", id, err)
+	}
+	if len(rest) > 0 {
+		return nil, nil, fmt.Errorf("Native: schema %d: %d trailing bytes", id, len(rest))
+	}
+
+	return s, s.convert(s.schema, "", native), nil
+}
+
+// IsWireFormat reports whether data starts with the wire format header.
+func IsWireFormat(data []byte) bool {
+	return len(data) >= headerSize && data[0] == magicByte
+}
+
+// schema returns the compiled schema, fetching it on first use. Concurrent
+// first uses may fetch it more than once.
+func (d *Decoder) schema(ctx context.Context, id int) (*compiled, error) {
+	d.mu.Lock()
+	s, ok := d.schemas[id]
+	d.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	rs, err := d.registry.SchemaByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !rs.Avro() {
+		return nil, fmt.Errorf("%w: schema %d is %s", ErrUnsupportedSchema, id, rs.Type)
+	}
+	s, err = compile(rs.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+
+	d.mu.Lock()
+	d.schemas[id] = s
+	d.mu.Unlock()
+
+	return s, nil
+}
+
+// Encode writes native, in the form goavro expects, in the wire format
+// with the schema ID. It produces test data for the decoder.
+func Encode(id int, schema string, native any) ([]byte, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("Encode: %w", err)
+	}
+
+	buf := make([]byte, headerSize, 64)
+	buf[0] = magicByte
+	binary.BigEndian.PutUint32(buf[1:], uint32(id))
+	buf, err = codec.BinaryFromNative(buf, native)
+	if err != nil {
+		return nil, fmt.Errorf("Encode: %w", err)
+	}
+	return buf, nil
+}
@@ -0,0 +1,334 @@
+package avro
+
+import (
+	"context"
+	"debezium_server/pkg/cdc"
+	"debezium_server/pkg/cdc/kafka"
+	"debezium_server/pkg/schemaregistry"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const ordersSchemaID = 1
+
+var (
+	orderDate = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	createdAt = time.Date(2026, 10, 1, 9, 30, 15, 250_000_000, time.UTC)
+)
+
+// countingRegistry counts the lookups of the registry it wraps.
+type countingRegistry struct {
+	schemaregistry.Registry
+	lookups int
+}
+
+func (r *countingRegistry) SchemaByID(ctx context.Context, id int) (schemaregistry.Schema, error) {
+	r.lookups++
+	return r.Registry.SchemaByID(ctx, id)
+}
+
+type registryFunc func(ctx context.Context, id int) (schemaregistry.Schema, error)
+
+func (f registryFunc) SchemaByID(ctx context.Context, id int) (schemaregistry.Schema, error) {
+	return f(ctx, id)
+}
+
+func ordersSchema(t *testing.T) string {
+	t.Helper()
+	s, err := schemaregistry.NewFileRegistry("testdata").SchemaByID(context.Background(), ordersSchemaID)
+	if err != nil {
+		t.Fatalf("SchemaByID: %v", err)
+	}
+	return s.Schema
+}
+
+// orderRow is a row of inventory.orders in the form goavro encodes. A nil
+// total or status is NULL.
+func orderRow(id int32, total *big.Rat, status *string) map[string]any {
+	row := map[string]any{
+		"id":         id,
+		"order_date": orderDate,
+		"created_at": createdAt,
+		"total":      nil,
+		"status":     nil,
+		"quantity":   int32(3),
+	}
+	if total != nil {
+		row["total"] = map[string]any{"bytes.decimal": total}
+	}
+	if status != nil {
+		row["status"] = map[string]any{"string": *status}
+	}
+	return row
+}
+
+func orderEnvelope(op string, before, after map[string]any) map[string]any {
+	wrap := func(row map[string]any) any {
+		if row == nil {
+			return nil
+		}
+		return map[string]any{"postgres.inventory.orders.Value": row}
+	}
+	return map[string]any{
+		"before": wrap(before),
+		"after":  wrap(after),
+		"source": map[string]any{
+			"version":   "2.7.0.Final",
+			"connector": "postgresql",
+			"name":      "postgres",
+			"ts_ms":     int64(1790847015000),
+			"snapshot":  map[string]any{"string": "false"},
+			"db":        "testdb",
+			"schema":    "inventory",
+			"table":     "orders",
+			"txId":      map[string]any{"long": int64(780)},
+			"lsn":       nil,
+		},
+		"op":    op,
+		"ts_ms": map[string]any{"long": int64(1790847015321)},
+	}
+}
+
+func encode(t *testing.T, native map[string]any) []byte {
+	t.Helper()
+	data, err := Encode(ordersSchemaID, ordersSchema(t), native)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	return data
+}
+
+// jsonRow is orderRow as the JSON converter writes it with
+// decimal.format=NUMERIC.
+func jsonRow(id int, total, status string) string {
+	days := orderDate.Unix() / 86400
+	return fmt.Sprintf(`{"id":%d,"order_date":%d,"created_at":%d,"total":%s,"status":%s,"quantity":3}`,
+		id, days, createdAt.UnixMilli(), total, status)
+}
+
+// jsonEvent is the value the JSON converter writes with schemas.enable for
+// the same event.
+func jsonEvent(op, before, after string) []byte {
+	row := `{"type":"struct","optional":true,"name":"postgres.inventory.orders.Value","field":"%s","fields":[
+		{"type":"int32","optional":false,"default":0,"field":"id"},
+		{"type":"int32","optional":false,"name":"io.debezium.time.Date","version":1,"field":"order_date"},
+		{"type":"int64","optional":false,"name":"org.apache.kafka.connect.data.Timestamp","version":1,"field":"created_at"},
+		{"type":"bytes","optional":true,"name":"org.apache.kafka.connect.data.Decimal","version":1,
+			"parameters":{"scale":"2","connect.decimal.precision":"10"},"field":"total"},
+		{"type":"string","optional":true,
+			"parameters":{"__debezium.source.column.type":"VARCHAR","__debezium.source.column.length":"20"},"field":"status"},
+		{"type":"int16","optional":false,"field":"quantity"}]}`
+	schema := `{"type":"struct","optional":false,"name":"postgres.inventory.orders.Envelope","fields":[` +
+		fmt.Sprintf(row, "before") + `,` + fmt.Sprintf(row, "after") + `,
+		{"type":"struct","optional":false,"name":"io.debezium.connector.postgresql.Source","field":"source","fields":[]},
+		{"type":"string","optional":false,"field":"op"},
+		{"type":"int64","optional":true,"field":"ts_ms"}]}`
+	payload := fmt.Sprintf(`{"before":%s,"after":%s,"source":{"version":"2.7.0.Final","connector":"postgresql",
+		"name":"postgres","ts_ms":1790847015000,"snapshot":"false","db":"testdb","schema":"inventory",
+		"table":"orders","txId":780,"lsn":null},"op":"%s","ts_ms":1790847015321}`, before, after, op)
+	return []byte(`{"schema":` + schema + `,"payload":` + payload + `}`)
+}
+
+func TestDecodeMatchesJSON(t *testing.T) {
+	status := "NEW"
+	tests := []struct {
+		name string
+		avro map[string]any
+		json []byte
+	}{
+		{
+			name: "create",
+			avro: orderEnvelope("c", nil, orderRow(1, big.NewRat(12345, 100), &status)),
+			json: jsonEvent("c", "null", jsonRow(1, "123.45", `"NEW"`)),
+		},
+		{
+			name: "update to nulls",
+			avro: orderEnvelope("u", orderRow(2, big.NewRat(5, 1), &status), orderRow(2, nil, nil)),
+			json: jsonEvent("u", jsonRow(2, "5.00", `"NEW"`), jsonRow(2, "null", "null")),
+		},
+		{
+			name: "delete",
+			avro: orderEnvelope("d", orderRow(3, big.NewRat(-1, 100), nil), nil),
+			json: jsonEvent("d", jsonRow(3, "-0.01", "null"), "null"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(schemaregistry.NewFileRegistry("testdata"))
+			got, err := d.Decode(context.Background(), kafka.Message{Value: encode(t, tt.avro)})
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			want, err := cdc.DecodeJSON(tt.json)
+			if err != nil {
+				t.Fatalf("DecodeJSON: %v", err)
+			}
+
+			if !reflect.DeepEqual(got.Before, want.Before) || !reflect.DeepEqual(got.After, want.After) {
+				t.Errorf("rows differ:\navro before %v after %v\njson before %v after %v",
+					got.Before, got.After, want.Before, want.After)
+			}
+			if got.Source != want.Source || got.Op != want.Op || got.TsMs != want.TsMs {
+				t.Errorf("envelope differs:\navro %+v %s %d\njson %+v %s %d",
+					got.Source, got.Op, got.TsMs, want.Source, want.Op, want.TsMs)
+			}
+			if got.Schema == nil || want.Schema == nil || !got.Schema.Equal(*want.Schema) {
+				t.Errorf("schemas differ:\navro %+v\njson %+v", got.Schema, want.Schema)
+			}
+		})
+	}
+}
+
+func TestDecodeSchema(t *testing.T) {
+	d := NewDecoder(schemaregistry.NewFileRegistry("testdata"))
+	status := "NEW"
+	ev, err := d.Decode(context.Background(), kafka.Message{
+		Value: encode(t, orderEnvelope("c", nil, orderRow(1, big.NewRat(1, 1), &status))),
+	})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	want := cdc.TableSchema{Columns: []cdc.Column{
+		{Name: "id", Type: "int32"},
+		{Name: "order_date", Type: "io.debezium.time.Date"},
+		{Name: "created_at", Type: "org.apache.kafka.connect.data.Timestamp"},
+		{Name: "total", Type: "org.apache.kafka.connect.data.Decimal", Optional: true, Scale: 2},
+		{Name: "status", Type: "string", Optional: true, Length: 20},
+		{Name: "quantity", Type: "int16"},
+	}}
+	if ev.Schema == nil || !ev.Schema.Equal(want) {
+		t.Errorf("got schema %+v, want %+v", ev.Schema, want)
+	}
+}
+
+func TestNative(t *testing.T) {
+	d := NewDecoder(schemaregistry.NewFileRegistry("testdata"))
+	status := "PAID"
+	native, err := d.Native(context.Background(),
+		encode(t, orderEnvelope("u", orderRow(7, nil, nil), orderRow(7, big.NewRat(999, 4), &status))))
+	if err != nil {
+		t.Fatalf("Native: %v", err)
+	}
+
+	env, ok := native.(map[string]any)
+	if !ok {
+		t.Fatalf("got %T, want a record", native)
+	}
+	after, ok := env["after"].(map[string]any)
+	if !ok {
+		t.Fatalf("after is %T, want the unwrapped Value record", env["after"])
+	}
+	want := map[string]any{
+		"id":         int32(7),
+		"order_date": orderDate.Unix() / 86400,
+		"created_at": createdAt.UnixMilli(),
+		"total":      249.75,
+		"status":     "PAID",
+		"quantity":   int32(3),
+	}
+	if !reflect.DeepEqual(after, want) {
+		t.Errorf("after is %#v, want %#v", after, want)
+	}
+
+	before, ok := env["before"].(map[string]any)
+	if !ok || before["total"] != nil || before["status"] != nil {
+		t.Errorf("before is %#v, want NULL total and status", env["before"])
+	}
+	source, _ := env["source"].(map[string]any)
+	if source["snapshot"] != "false" || source["txId"] != int64(780) || source["lsn"] != nil {
+		t.Errorf("source is %#v, want unwrapped unions", source)
+	}
+	if env["ts_ms"] != int64(1790847015321) {
+		t.Errorf("ts_ms is %#v", env["ts_ms"])
+	}
+}
+
+func TestDecodeHeaderErrors(t *testing.T) {
+	status := "NEW"
+	valid := encode(t, orderEnvelope("c", nil, orderRow(1, nil, &status)))
+	withID := func(id byte) []byte {
+		data := append([]byte(nil), valid...)
+		data[4] = id
+		return data
+	}
+	jsonValue := jsonEvent("c", "null", jsonRow(1, "1.00", `"NEW"`))
+
+	tests := []struct {
+		name     string
+		value    []byte
+		registry schemaregistry.Registry
+		fallback bool
+		errIs    error
+		errText  string
+	}{
+		{name: "empty", value: nil, errIs: ErrNotWireFormat},
+		{name: "shorter than the header", value: []byte{0, 0, 0, 1}, errIs: ErrNotWireFormat},
+		{name: "wrong magic byte", value: append([]byte{1}, valid[1:]...), errIs: ErrNotWireFormat},
+		{name: "JSON without fallback", value: jsonValue, errIs: ErrNotWireFormat},
+		{name: "unknown schema ID", value: withID(99), errIs: schemaregistry.ErrSchemaNotFound},
+		{
+			name:  "not an Avro schema",
+			value: valid,
+			registry: registryFunc(func(_ context.Context, id int) (schemaregistry.Schema, error) {
+				return schemaregistry.Schema{ID: id, Type: "PROTOBUF", Schema: "syntax = \"proto3\";"}, nil
+			}),
+			errIs: ErrUnsupportedSchema,
+		},
+		{name: "trailing bytes", value: append(append([]byte(nil), valid...), 0), errText: "trailing bytes"},
+		{name: "truncated body", value: valid[:len(valid)-3], errText: "schema 1"},
+		{name: "JSON with fallback", value: jsonValue, fallback: true},
+		{name: "wire format with fallback", value: withID(99), fallback: true, errIs: schemaregistry.ErrSchemaNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := tt.registry
+			if registry == nil {
+				registry = schemaregistry.NewFileRegistry("testdata")
+			}
+			var opts []Option
+			if tt.fallback {
+				opts = append(opts, WithJSONFallback())
+			}
+
+			ev, err := NewDecoder(registry, opts...).Decode(context.Background(), kafka.Message{Value: tt.value})
+			switch {
+			case tt.errIs != nil:
+				if !errors.Is(err, tt.errIs) {
+					t.Errorf("got error %v, want %v", err, tt.errIs)
+				}
+			case tt.errText != "":
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Errorf("got error %v, want one mentioning %q", err, tt.errText)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				if ev.Op != cdc.OpCreate || ev.After["status"] != "NEW" {
+					t.Errorf("got event %+v", ev)
+				}
+			}
+		})
+	}
+}
+
+func TestDecoderCachesSchemas(t *testing.T) {
+	registry := &countingRegistry{Registry: schemaregistry.NewFileRegistry("testdata")}
+	d := NewDecoder(registry)
+	value := encode(t, orderEnvelope("r", nil, orderRow(1, nil, nil)))
+
+	for range 3 {
+		if _, err := d.Decode(context.Background(), kafka.Message{Value: value}); err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+	}
+	if registry.lookups != 1 {
+		t.Errorf("looked the schema up %d times, want once", registry.lookups)
+	}
+}
@@ -0,0 +1,23 @@
+package avro
+
+// ConverterClass is the Kafka Connect converter that writes the format
+// Decoder reads.
+const ConverterClass = "io.confluent.connect.avro.AvroConverter"
+
+// ConverterConfig returns the connector config keys that switch its key
+// and value converters to Avro with the registry at registryURL. The URL is
+// the one Kafka Connect sees, which may differ from the server's. userInfo
+// is "user:password" for basic auth and may be empty; it may also be a
+// configtemplate placeholder.
+func ConverterConfig(registryURL, userInfo string) map[string]string {
+	config := make(map[string]string)
+	for _, prefix := range []string{"key.converter", "value.converter"} {
+		config[prefix] = ConverterClass
+		config[prefix+".schema.registry.url"] = registryURL
+		if userInfo != "" {
+			config[prefix+".basic.auth.credentials.source"] = "USER_INFO"
+			config[prefix+".basic.auth.user.info"] = userInfo
+		}
+	}
+	return config
+}
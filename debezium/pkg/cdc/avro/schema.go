@@ -0,0 +1,372 @@
+package avro
+
+import (
+	"debezium_server/pkg/cdc"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+var primitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+// compiled is a codec with its parsed schema, which guides the conversion
+// of decoded values.
+type compiled struct {
+	codec  *goavro.Codec
+	schema any
+	// names maps the full names of records, enums and fixed types to
+	// their definitions.
+	names map[string]named
+	// rowSchema is the schema of the before and after fields, nil if the
+	// schema is not a Debezium envelope.
+	rowSchema *cdc.TableSchema
+}
+
+type named struct {
+	schema    map[string]any
+	namespace string
+}
+
+func compile(schema string) (*compiled, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed any
+	if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+		return nil, err
+	}
+
+	c := &compiled{codec: codec, schema: parsed, names: make(map[string]named)}
+	// A type may be defined in a union branch that is not taken and
+	// referenced elsewhere, like Debezium's Value in before and after.
+	c.collect(parsed, "")
+	c.rowSchema = c.envelopeRow(parsed)
+	return c, nil
+}
+
+// avroTypes maps Avro primitives to the Kafka Connect types the converter
+// wrote them for. int8 and int16 carry connect.type instead.
+var avroTypes = map[string]string{
+	"int": "int32", "long": "int64", "float": "float", "double": "double",
+	"boolean": "boolean", "string": "string", "bytes": "bytes",
+}
+
+func (c *compiled) envelopeRow(schema any) *cdc.TableSchema {
+	env, ok := schema.(map[string]any)
+	if !ok || env["type"] != "record" {
+		return nil
+	}
+	_, ns := fullName(env, "")
+	fields, _ := env["fields"].([]any)
+	for _, name := range []string{"after", "before"} {
+		for _, f := range fields {
+			fm, ok := f.(map[string]any)
+			if !ok || fm["name"] != name {
+				continue
+			}
+			typ, typeNS, _ := c.resolve(fm["type"], ns)
+			rec, ok := typ.(map[string]any)
+			if !ok || rec["type"] != "record" {
+				continue
+			}
+			return c.columns(rec, typeNS)
+		}
+	}
+	return nil
+}
+
+func (c *compiled) columns(record map[string]any, enclosing string) *cdc.TableSchema {
+	_, ns := fullName(record, enclosing)
+	fields, _ := record["fields"].([]any)
+	s := &cdc.TableSchema{Columns: make([]cdc.Column, 0, len(fields))}
+	for _, f := range fields {
+		fm, ok := f.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := fm["name"].(string)
+		typ, _, optional := c.resolve(fm["type"], ns)
+		s.Columns = append(s.Columns, column(name, typ, optional))
+	}
+	return s
+}
+
+// resolve unwraps a union with null and named references. It reports
+// whether the union made the type optional.
+func (c *compiled) resolve(schema any, namespace string) (any, string, bool) {
+	optional := false
+	if union, ok := schema.([]any); ok {
+		var nonNull []any
+		for _, b := range union {
+			if b == "null" {
+				optional = true
+				continue
+			}
+			nonNull = append(nonNull, b)
+		}
+		if len(nonNull) != 1 {
+			return union, namespace, optional
+		}
+		schema = nonNull[0]
+	}
+	if s, ok := schema.(string); ok && !primitives[s] {
+		if n, ok := c.lookup(s, namespace); ok {
+			return n.schema, n.namespace, optional
+		}
+	}
+	return schema, namespace, optional
+}
+
+// column maps a field schema to a column the way the JSON converter would
+// describe it, using the connect.* properties of the Avro converter.
+func column(name string, schema any, optional bool) cdc.Column {
+	var (
+		typ, semantic string
+		params        = map[string]string{}
+	)
+	switch s := schema.(type) {
+	case string:
+		typ = avroTypes[s]
+	case []any:
+		typ = "union"
+	case map[string]any:
+		t, _ := s["type"].(string)
+		switch t {
+		case "record":
+			typ = "struct"
+		case "enum":
+			typ = "string"
+		case "fixed":
+			typ = "bytes"
+		case "array", "map":
+			typ = t
+		default:
+			typ = avroTypes[t]
+		}
+		if ct, ok := s["connect.type"].(string); ok {
+			typ = ct
+		}
+		semantic, _ = s["connect.name"].(string)
+		if p, ok := s["connect.parameters"].(map[string]any); ok {
+			for k, v := range p {
+				params[k] = fmt.Sprint(v)
+			}
+		}
+		if scale, ok := s["scale"].(float64); ok && params["scale"] == "" {
+			params["scale"] = fmt.Sprint(int(scale))
+		}
+	}
+	return cdc.ColumnFromConnect(name, typ, semantic, optional, params)
+}
+
+func (c *compiled) collect(schema any, namespace string) {
+	switch s := schema.(type) {
+	case []any:
+		for _, branch := range s {
+			c.collect(branch, namespace)
+		}
+	case map[string]any:
+		switch s["type"] {
+		case "record", "error", "enum", "fixed":
+			full, ns := fullName(s, namespace)
+			c.names[full] = named{schema: s, namespace: ns}
+			if fields, ok := s["fields"].([]any); ok {
+				for _, f := range fields {
+					if fm, ok := f.(map[string]any); ok {
+						c.collect(fm["type"], ns)
+					}
+				}
+			}
+		case "array":
+			c.collect(s["items"], namespace)
+		case "map":
+			c.collect(s["values"], namespace)
+		default:
+			c.collect(s["type"], namespace)
+		}
+	}
+}
+
+// convert walks a value decoded by goavro alongside its schema.
+func (c *compiled) convert(schema any, namespace string, v any) any {
+	switch s := schema.(type) {
+	case string:
+		if primitives[s] {
+			return v
+		}
+		if n, ok := c.lookup(s, namespace); ok {
+			return c.convert(n.schema, n.namespace, v)
+		}
+		return v
+
+	case []any:
+		m, ok := v.(map[string]any)
+		if v == nil || !ok || len(m) != 1 {
+			return v
+		}
+		for name, inner := range m {
+			if branch, ok := c.branch(s, namespace, name); ok {
+				return c.convert(branch, namespace, inner)
+			}
+		}
+		return v
+
+	case map[string]any:
+		switch s["type"] {
+		case "record", "error":
+			rec, ok := v.(map[string]any)
+			if !ok {
+				return v
+			}
+			_, ns := fullName(s, namespace)
+			fields, _ := s["fields"].([]any)
+			out := make(map[string]any, len(rec))
+			for _, f := range fields {
+				fm, ok := f.(map[string]any)
+				if !ok {
+					continue
+				}
+				name, _ := fm["name"].(string)
+				if fv, ok := rec[name]; ok {
+					out[name] = c.convert(fm["type"], ns, fv)
+				}
+			}
+			return out
+		case "enum", "fixed":
+			return v
+		case "array":
+			items, ok := v.([]any)
+			if !ok {
+				return v
+			}
+			out := make([]any, len(items))
+			for i, item := range items {
+				out[i] = c.convert(s["items"], namespace, item)
+			}
+			return out
+		case "map":
+			values, ok := v.(map[string]any)
+			if !ok {
+				return v
+			}
+			out := make(map[string]any, len(values))
+			for k, item := range values {
+				out[k] = c.convert(s["values"], namespace, item)
+			}
+			return out
+		default:
+			if logical, ok := s["logicalType"].(string); ok {
+				return convertLogical(logical, v)
+			}
+			return c.convert(s["type"], namespace, v)
+		}
+	}
+	return v
+}
+
+// branch returns the union branch goavro named name: the type name for
+// primitives, "<type>.<logicalType>" for logical types and the full name
+// for named types. A union of null and one type always takes that type.
+func (c *compiled) branch(union []any, namespace, name string) (any, bool) {
+	var nonNull []any
+	for _, b := range union {
+		if b == "null" {
+			continue
+		}
+		nonNull = append(nonNull, b)
+		if c.branchName(b, namespace) == name {
+			return b, true
+		}
+	}
+	if len(nonNull) == 1 {
+		return nonNull[0], true
+	}
+	return nil, false
+}
+
+func (c *compiled) branchName(schema any, namespace string) string {
+	switch s := schema.(type) {
+	case string:
+		if primitives[s] {
+			return s
+		}
+		if n, ok := c.lookup(s, namespace); ok {
+			full, _ := fullName(n.schema, n.namespace)
+			return full
+		}
+		return s
+	case map[string]any:
+		typ, _ := s["type"].(string)
+		switch typ {
+		case "record", "error", "enum", "fixed":
+			full, _ := fullName(s, namespace)
+			return full
+		}
+		if logical, ok := s["logicalType"].(string); ok {
+			return typ + "." + logical
+		}
+		return typ
+	}
+	return ""
+}
+
+func (c *compiled) lookup(name, namespace string) (named, bool) {
+	if !strings.Contains(name, ".") && namespace != "" {
+		if n, ok := c.names[namespace+"."+name]; ok {
+			return n, true
+		}
+	}
+	n, ok := c.names[name]
+	return n, ok
+}
+
+// fullName returns the full name of a named type and the namespace of the
+// types defined inside it.
+func fullName(s map[string]any, enclosing string) (string, string) {
+	name, _ := s["name"].(string)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name, name[:i]
+	}
+	ns := enclosing
+	if explicit, ok := s["namespace"].(string); ok {
+		ns = explicit
+	}
+	if ns == "" {
+		return name, ""
+	}
+	return ns + "." + name, ns
+}
+
+// convertLogical turns goavro's time.Time, time.Duration and *big.Rat
+// back into the numbers the JSON converter writes.
+func convertLogical(logical string, v any) any {
+	switch x := v.(type) {
+	case time.Time:
+		switch logical {
+		case "date":
+			return x.Unix() / int64(24*time.Hour/time.Second)
+		case "timestamp-micros":
+			return x.UnixMicro()
+		default:
+			return x.UnixMilli()
+		}
+	case time.Duration:
+		if logical == "time-micros" {
+			return x.Microseconds()
+		}
+		return x.Milliseconds()
+	case *big.Rat:
+		f, _ := x.Float64()
+		return f
+	}
+	return v
+}
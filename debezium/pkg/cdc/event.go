@@ -0,0 +1,137 @@
+package cdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+type Op string
+
+const (
+	OpCreate   Op = "c"
+	OpUpdate   Op = "u"
+	OpDelete   Op = "d"
+	OpRead     Op = "r"
+	OpTruncate Op = "t"
+	OpMessage  Op = "m"
+)
+
+var (
+	ErrEmptyEvent = errors.New("change event payload is empty")
+)
+
+type Source struct {
+	Version   string `json:"version"`
+	Connector string `json:"connector"`
+	Name      string `json:"name"`
+	TsMs      int64  `json:"ts_ms"`
+	Snapshot  string `json:"snapshot,omitempty"`
+	DB        string `json:"db"`
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	TxID      int64  `json:"txId,omitempty"`
+	LSN       int64  `json:"lsn,omitempty"`
+}
+
+// ChangeEvent is the value of a Debezium change event with the connector schema stripped.
+type ChangeEvent struct {
+	Before map[string]any `json:"before"`
+	After  map[string]any `json:"after"`
+	Source Source         `json:"source"`
+	Op     Op             `json:"op"`
+	TsMs   int64          `json:"ts_ms"`
+
+	// DDL and TableChanges are set instead of the row fields in the events
+	// of the schema change topic.
+	DDL          string        `json:"ddl,omitempty"`
+	TableChanges []TableChange `json:"tableChanges,omitempty"`
+
+	// Schema is the row schema taken from the connector schema, nil if the
+	// value had none.
+	Schema *TableSchema `json:"-"`
+}
+
+type envelope struct {
+	Schema  json.RawMessage `json:"schema"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// connectSchema is a schema written by the JSON converter with
+// schemas.enable.
+type connectSchema struct {
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Optional   bool              `json:"optional"`
+	Field      string            `json:"field"`
+	Fields     []connectSchema   `json:"fields"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+// IsSchemaChange reports whether the event came from the schema change
+// topic rather than a table topic.
+func (e ChangeEvent) IsSchemaChange() bool {
+	return e.Op == "" && (e.DDL != "" || len(e.TableChanges) > 0)
+}
+
+// Table returns the fully qualified "schema.table" name the event belongs to.
+func (e ChangeEvent) Table() string {
+	if e.Source.Schema == "" {
+		return e.Source.Table
+	}
+	return e.Source.Schema + "." + e.Source.Table
+}
+
+// DecodeJSON decodes a Debezium JSON value produced either with or without
+// value.converter.schemas.enable.
+func DecodeJSON(data []byte) (ChangeEvent, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return ChangeEvent{}, ErrEmptyEvent
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return ChangeEvent{}, fmt.Errorf("DecodeJSON.Unmarshal: %w", err)
+	}
+	var schema *TableSchema
+	if env.Schema != nil && env.Payload != nil {
+		data = env.Payload
+		if bytes.Equal(data, []byte("null")) {
+			return ChangeEvent{}, ErrEmptyEvent
+		}
+
+		var cs connectSchema
+		if err := json.Unmarshal(env.Schema, &cs); err != nil {
+			return ChangeEvent{}, fmt.Errorf("DecodeJSON.Unmarshal: %w", err)
+		}
+		schema = rowSchema(cs)
+	}
+
+	var event ChangeEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return ChangeEvent{}, fmt.Errorf("DecodeJSON.Unmarshal: %w", err)
+	}
+	event.Schema = schema
+
+	return event, nil
+}
+
+// rowSchema returns the schema of the after, or else the before, field of
+// an envelope schema.
+func rowSchema(envelope connectSchema) *TableSchema {
+	for _, name := range []string{"after", "before"} {
+		for _, f := range envelope.Fields {
+			if f.Field != name || f.Type != "struct" {
+				continue
+			}
+			s := &TableSchema{Columns: make([]Column, 0, len(f.Fields))}
+			for _, c := range f.Fields {
+				s.Columns = append(s.Columns, ColumnFromConnect(c.Field, c.Type, c.Name, c.Optional, c.Parameters))
+			}
+			return s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,388 @@
+package kafka
+
+import (
+	"context"
+	"debezium_server/pkg/cdc"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConcurrency  = 8
+	defaultAttempts     = 3
+	defaultRetryBackoff = 500 * time.Millisecond
+	maxRetryBackoff     = 30 * time.Second
+)
+
+var (
+	ErrClosed = errors.New("kafka client is closed")
+	ErrDecode = errors.New("cannot decode change event")
+)
+
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Message is one record of a topic partition.
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+	Timestamp time.Time
+}
+
+// Tombstone reports whether the message has no value. Debezium writes a
+// tombstone after every delete so that compaction can drop the key.
+func (m Message) Tombstone() bool {
+	return m.Value == nil
+}
+
+// Client reads the partitions assigned to one consumer group member. The
+// assignment must not change between a Fetch and the next one, so that a
+// batch can be handled and committed before its partitions move to
+// another member.
+type Client interface {
+	// Fetch blocks until records are available or ctx is done.
+	Fetch(ctx context.Context) ([]Message, error)
+	// Commit stores, per partition, the offset of the next message to read.
+	Commit(ctx context.Context, offsets map[TopicPartition]int64) error
+	Close()
+}
+
+// Event is a message with its decoded change event. Change is nil for
+// tombstones.
+type Event struct {
+	Message
+	Change *cdc.ChangeEvent
+}
+
+type Handler interface {
+	Handle(ctx context.Context, ev Event) error
+}
+
+type HandlerFunc func(ctx context.Context, ev Event) error
+
+func (f HandlerFunc) Handle(ctx context.Context, ev Event) error {
+	return f(ctx, ev)
+}
+
+// Decoder turns a message value into a change event.
+type Decoder interface {
+	Decode(ctx context.Context, msg Message) (cdc.ChangeEvent, error)
+}
+
+type DecoderFunc func(ctx context.Context, msg Message) (cdc.ChangeEvent, error)
+
+func (f DecoderFunc) Decode(ctx context.Context, msg Message) (cdc.ChangeEvent, error) {
+	return f(ctx, msg)
+}
+
+// JSONDecoder decodes values written by the JSON converter, with or
+// without schemas.
+var JSONDecoder Decoder = DecoderFunc(func(_ context.Context, msg Message) (cdc.ChangeEvent, error) {
+	return cdc.DecodeJSON(msg.Value)
+})
+
+// ErrorHandler decides what happens to a message that could not be
+// decoded or whose handler still failed after the retries. If it returns
+// nil the message counts as handled; otherwise the message goes to the
+// dead letter queue, or the consumer stops if there is none.
+type ErrorHandler func(ctx context.Context, ev Event, err error) error
+
+// DeadLetter is a message that could not be handled, with where it was
+// read from and why it failed.
+type DeadLetter struct {
+	Message
+	Error string
+	// Attempts is how often the handler was called; 0 if the message
+	// could not be decoded.
+	Attempts int
+	FailedAt time.Time
+}
+
+// DeadLetterQueue keeps the messages that could not be handled, so that a
+// poison message does not block its partition.
+type DeadLetterQueue interface {
+	Put(ctx context.Context, dl DeadLetter) error
+}
+
+type Option func(*options)
+
+type options struct {
+	concurrency  int
+	tombstones   bool
+	attempts     int
+	backoff      time.Duration
+	decoder      Decoder
+	errorHandler ErrorHandler
+	deadLetters  DeadLetterQueue
+}
+
+// WithConcurrency sets how many messages are handled at once. Messages
+// with the same key are always handled one after another, in order.
+// Defaults to 8.
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = n
+	}
+}
+
+// WithTombstones hands tombstones to the handler. By default they are
+// skipped and committed.
+func WithTombstones() Option {
+	return func(o *options) {
+		o.tombstones = true
+	}
+}
+
+// WithRetry sets how often a failing handler is called for one message
+// and the backoff before the first retry, which doubles for every
+// further one. Defaults to 3 attempts and 500ms.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// WithDecoder replaces JSONDecoder.
+func WithDecoder(d Decoder) Option {
+	return func(o *options) {
+		o.decoder = d
+	}
+}
+
+// WithErrorHandler is called for messages that cannot be handled, before
+// the dead letter queue.
+func WithErrorHandler(fn ErrorHandler) Option {
+	return func(o *options) {
+		o.errorHandler = fn
+	}
+}
+
+// WithDeadLetterQueue stores the messages that cannot be handled and moves
+// on. Later messages with the same key are still handled.
+func WithDeadLetterQueue(q DeadLetterQueue) Option {
+	return func(o *options) {
+		o.deadLetters = q
+	}
+}
+
+// Consumer hands the messages of a Client to a Handler. Offsets are only
+// committed once every earlier message of the partition was handled, so
+// delivery is at least once.
+type Consumer struct {
+	client  Client
+	handler Handler
+	opts    options
+}
+
+func NewConsumer(client Client, handler Handler, opts ...Option) *Consumer {
+	o := options{
+		concurrency: defaultConcurrency,
+		attempts:    defaultAttempts,
+		backoff:     defaultRetryBackoff,
+		decoder:     JSONDecoder,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.concurrency < 1 {
+		o.concurrency = 1
+	}
+	if o.attempts < 1 {
+		o.attempts = 1
+	}
+
+	return &Consumer{client: client, handler: handler, opts: o}
+}
+
+// Run consumes until ctx is done, which returns nil, or until a message
+// can neither be handled nor dead-lettered. The offsets handled so far are
+// committed either way.
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		msgs, err := c.client.Fetch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Run.Fetch: %w", err)
+		}
+		if len(msgs) == 0 {
+			continue
+		}
+
+		handleErr := c.handleBatch(ctx, msgs)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if handleErr != nil {
+			return handleErr
+		}
+	}
+}
+
+// handleBatch spreads the messages over lanes by key, handles each lane in
+// order and commits what was handled.
+func (c *Consumer) handleBatch(ctx context.Context, msgs []Message) error {
+	lanes := make([][]int, min(c.opts.concurrency, len(msgs)))
+	for i, m := range msgs {
+		l := laneOf(m, len(lanes))
+		lanes[l] = append(lanes[l], i)
+	}
+
+	done := make([]bool, len(msgs))
+	errs := make([]error, len(lanes))
+	var wg sync.WaitGroup
+	for l, lane := range lanes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, i := range lane {
+				if err := c.handle(ctx, msgs[i]); err != nil {
+					errs[l] = err
+					return
+				}
+				done[i] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The commit must land even if ctx was cancelled mid-batch.
+	if err := c.client.Commit(context.WithoutCancel(ctx), commitOffsets(msgs, done)); err != nil {
+		return fmt.Errorf("Run.Commit: %w", err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// laneOf keeps messages with the same key in the same lane. Messages
+// without a key keep the order of their partition.
+func laneOf(m Message, lanes int) int {
+	h := fnv.New32a()
+	h.Write([]byte(m.Topic))
+	if m.Key != nil {
+		h.Write(m.Key)
+	} else {
+		h.Write([]byte{byte(m.Partition >> 24), byte(m.Partition >> 16), byte(m.Partition >> 8), byte(m.Partition)})
+	}
+	return int(h.Sum32() % uint32(lanes))
+}
+
+// commitOffsets returns, per partition, the offset of the first message
+// not handled, or one past the last message.
+func commitOffsets(msgs []Message, done []bool) map[TopicPartition]int64 {
+	offsets := make(map[TopicPartition]int64)
+	blocked := make(map[TopicPartition]bool)
+	for i, m := range msgs {
+		tp := TopicPartition{Topic: m.Topic, Partition: m.Partition}
+		if done[i] {
+			continue
+		}
+		if !blocked[tp] || m.Offset < offsets[tp] {
+			offsets[tp] = m.Offset
+		}
+		blocked[tp] = true
+	}
+
+	// Messages of other keys handled after a failure are delivered again.
+	for _, m := range msgs {
+		tp := TopicPartition{Topic: m.Topic, Partition: m.Partition}
+		if !blocked[tp] && m.Offset+1 > offsets[tp] {
+			offsets[tp] = m.Offset + 1
+		}
+	}
+
+	return offsets
+}
+
+// handle decodes and handles one message, retrying the handler.
+func (c *Consumer) handle(ctx context.Context, m Message) error {
+	if m.Tombstone() && !c.opts.tombstones {
+		return nil
+	}
+	ev, err := c.decode(ctx, m)
+	if err != nil {
+		return c.fail(ctx, ev, err, 0)
+	}
+
+	backoff := c.opts.backoff
+	attempt := 1
+	for ; ; attempt++ {
+		if err = c.handler.Handle(ctx, ev); err == nil {
+			return nil
+		}
+		if attempt == c.opts.attempts || ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+
+	return c.fail(ctx, ev, err, attempt)
+}
+
+// Replay handles one message once, without retries, the error handler or
+// the dead letter queue. It is meant for messages taken from a dead letter
+// queue.
+func (c *Consumer) Replay(ctx context.Context, m Message) error {
+	ev, err := c.decode(ctx, m)
+	if err != nil {
+		return err
+	}
+	return c.handler.Handle(ctx, ev)
+}
+
+func (c *Consumer) decode(ctx context.Context, m Message) (Event, error) {
+	ev := Event{Message: m}
+	if m.Tombstone() {
+		return ev, nil
+	}
+
+	change, err := c.opts.decoder.Decode(ctx, m)
+	if err != nil {
+		return ev, fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	ev.Change = &change
+	return ev, nil
+}
+
+func (c *Consumer) fail(ctx context.Context, ev Event, err error, attempts int) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s/%d@%d: %w", ev.Topic, ev.Partition, ev.Offset, err)
+	}
+
+	if c.opts.errorHandler != nil {
+		if err = c.opts.errorHandler(ctx, ev, err); err == nil {
+			return nil
+		}
+	}
+	if c.opts.deadLetters != nil {
+		dl := DeadLetter{Message: ev.Message, Error: err.Error(), Attempts: attempts, FailedAt: time.Now()}
+		putErr := c.opts.deadLetters.Put(ctx, dl)
+		if putErr == nil {
+			return nil
+		}
+		err = fmt.Errorf("%w; dead letter queue: %w", err, putErr)
+	}
+	return fmt.Errorf("%s/%d@%d: %w", ev.Topic, ev.Partition, ev.Offset, err)
+}
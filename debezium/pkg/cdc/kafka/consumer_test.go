@@ -0,0 +1,466 @@
+package kafka_test
+
+import (
+	"context"
+	"debezium_server/pkg/cdc"
+	"debezium_server/pkg/cdc/kafka"
+	"debezium_server/pkg/cdc/kafka/kafkatest"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testGroup = "test-group"
+
+// rawDecoder skips the JSON decoding, the tests only look at the messages.
+var rawDecoder = kafka.WithDecoder(kafka.DecoderFunc(func(context.Context, kafka.Message) (cdc.ChangeEvent, error) {
+	return cdc.ChangeEvent{}, nil
+}))
+
+// recorder is a handler that remembers the values it handled, per key.
+type recorder struct {
+	mu    sync.Mutex
+	byKey map[string][]string
+	total int
+	fail  func(ev kafka.Event) error
+	delay time.Duration
+}
+
+func newRecorder() *recorder {
+	return &recorder{byKey: make(map[string][]string)}
+}
+
+func (r *recorder) Handle(_ context.Context, ev kafka.Event) error {
+	if r.delay > 0 {
+		time.Sleep(time.Duration(rand.Int64N(int64(r.delay))))
+	}
+	if r.fail != nil {
+		if err := r.fail(ev); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byKey[string(ev.Key)] = append(r.byKey[string(ev.Key)], string(ev.Value))
+	r.total++
+	return nil
+}
+
+func (r *recorder) handled() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.total
+}
+
+// start runs the consumer until the test ends and returns its result.
+func start(t *testing.T, c *kafka.Consumer) (stop func() error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	var once sync.Once
+	var err error
+	stop = func() error {
+		once.Do(func() {
+			cancel()
+			err = <-done
+		})
+		return err
+	}
+	t.Cleanup(func() { _ = stop() })
+	return stop
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestConsumerKeepsOrderPerKey(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	broker.CreateTopic("orders", 1)
+	keys := []string{"1", "2", "3", "4", "5"}
+	const perKey = 30
+	for i := range perKey {
+		for _, k := range keys {
+			broker.Produce("orders", []byte(k), []byte(fmt.Sprint(i)))
+		}
+	}
+
+	client := broker.NewClient(testGroup, "orders")
+	client.MaxFetch = 7
+	rec := newRecorder()
+	rec.delay = time.Millisecond
+	stop := start(t, kafka.NewConsumer(client, rec, kafka.WithConcurrency(4), rawDecoder))
+
+	waitFor(t, "every message", func() bool { return rec.handled() == len(keys)*perKey })
+	if err := stop(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	for _, k := range keys {
+		got := rec.byKey[k]
+		if len(got) != perKey {
+			t.Fatalf("key %s: handled %d messages, want %d", k, len(got), perKey)
+		}
+		for i, v := range got {
+			if v != fmt.Sprint(i) {
+				t.Fatalf("key %s: message %d is %s, order lost: %v", k, i, v, got)
+			}
+		}
+	}
+	if got := broker.Committed(testGroup, "orders", 0); got != int64(len(keys)*perKey) {
+		t.Errorf("committed %d, want %d", got, len(keys)*perKey)
+	}
+}
+
+func TestConsumerCommitsContiguousSuccess(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	broker.CreateTopic("orders", 1)
+	for i, k := range []string{"a", "b", "c", "a", "c"} {
+		broker.Produce("orders", []byte(k), []byte(fmt.Sprint(i)))
+	}
+
+	// The message at offset 1 fails; offset 0 and the later messages of
+	// other keys are handled, but only offset 0 may be committed.
+	errPoison := errors.New("poison")
+	rec := newRecorder()
+	rec.fail = func(ev kafka.Event) error {
+		if ev.Offset == 1 {
+			return errPoison
+		}
+		return nil
+	}
+	failing := broker.NewClient(testGroup, "orders")
+	err := kafka.NewConsumer(failing, rec, kafka.WithConcurrency(3), kafka.WithRetry(2, 0), rawDecoder).
+		Run(context.Background())
+	failing.Close()
+	if !errors.Is(err, errPoison) {
+		t.Fatalf("Run returned %v, want the handler error", err)
+	}
+	if got := broker.Committed(testGroup, "orders", 0); got != 1 {
+		t.Fatalf("committed %d, want 1", got)
+	}
+
+	// The member that takes over starts at the failed message and gets the messages
+	// handled after it again.
+	rec = newRecorder()
+	client := broker.NewClient(testGroup, "orders")
+	stop := start(t, kafka.NewConsumer(client, rec, kafka.WithConcurrency(3), rawDecoder))
+	waitFor(t, "the redelivered messages", func() bool { return rec.handled() == 4 })
+	if err := stop(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := broker.Committed(testGroup, "orders", 0); got != 5 {
+		t.Errorf("committed %d, want 5", got)
+	}
+	if got := rec.byKey["b"]; !slices.Equal(got, []string{"1"}) {
+		t.Errorf("key b got %v, want the failed message again", got)
+	}
+}
+
+func TestConsumerRebalance(t *testing.T) {
+	const partitions = 4
+	broker := kafkatest.NewBroker()
+	broker.CreateTopic("orders", partitions)
+	produce := func(from, to int) {
+		for i := from; i < to; i++ {
+			broker.Produce("orders", []byte(fmt.Sprint(i%16)), []byte(fmt.Sprint(i)))
+		}
+	}
+
+	first := broker.NewClient(testGroup, "orders")
+	firstRec := newRecorder()
+	start(t, kafka.NewConsumer(first, firstRec, rawDecoder))
+
+	produce(0, 100)
+	waitFor(t, "the first batch", func() bool { return firstRec.handled() == 100 })
+	if got := len(first.Assigned()); got != partitions {
+		t.Fatalf("the only member has %d partitions, want %d", got, partitions)
+	}
+	committed := func() int64 {
+		var sum int64
+		for p := range int32(partitions) {
+			sum += max(broker.Committed(testGroup, "orders", p), 0)
+		}
+		return sum
+	}
+	waitFor(t, "the commit of the first batch", func() bool { return committed() == 100 })
+
+	second := broker.NewClient(testGroup, "orders")
+	secondRec := newRecorder()
+	start(t, kafka.NewConsumer(second, secondRec, rawDecoder))
+
+	produce(100, 200)
+	waitFor(t, "the second batch", func() bool { return firstRec.handled()+secondRec.handled() >= 200 })
+
+	if got := len(first.Assigned()) + len(second.Assigned()); got != partitions {
+		t.Errorf("members have %d partitions, want %d", got, partitions)
+	}
+	if len(second.Assigned()) == 0 || secondRec.handled() == 0 {
+		t.Errorf("the new member got no partitions or messages")
+	}
+	// Every batch was committed before the partitions moved, so nothing
+	// was handled twice.
+	if got := firstRec.handled() + secondRec.handled(); got != 200 {
+		t.Errorf("handled %d messages, want 200", got)
+	}
+	waitFor(t, "the commit of the second batch", func() bool { return committed() == 200 })
+}
+
+// memoryQueue is a dead letter queue that keeps the dead letters in memory.
+type memoryQueue struct {
+	mu      sync.Mutex
+	letters []kafka.DeadLetter
+	err     error
+}
+
+func (q *memoryQueue) Put(_ context.Context, dl kafka.DeadLetter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.err != nil {
+		return q.err
+	}
+	q.letters = append(q.letters, dl)
+	return nil
+}
+
+func (q *memoryQueue) get() []kafka.DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return slices.Clone(q.letters)
+}
+
+func TestConsumerDeadLetters(t *testing.T) {
+	errPoison := errors.New("poison")
+	failOffset1 := func(ev kafka.Event) error {
+		if ev.Offset == 1 {
+			return errPoison
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name string
+		// values are produced under the key "k" in this order.
+		values       []string
+		fail         func(ev kafka.Event) error
+		errorHandler kafka.ErrorHandler
+		// handled is how many messages reach the handler without error.
+		handled  int
+		letters  int
+		attempts int
+		errIs    error
+	}{
+		{
+			name:     "handler keeps failing",
+			values:   []string{`{"op":"c"}`, `{"op":"u"}`, `{"op":"u"}`},
+			fail:     failOffset1,
+			handled:  2,
+			letters:  1,
+			attempts: 3,
+			errIs:    errPoison,
+		},
+		{
+			name:    "cannot decode",
+			values:  []string{`{"op":"c"}`, `not json`, `{"op":"d"}`},
+			handled: 2,
+			letters: 1,
+			errIs:   kafka.ErrDecode,
+		},
+		{
+			name:   "error handler accepts",
+			values: []string{`{"op":"c"}`, `{"op":"u"}`, `{"op":"u"}`},
+			fail:   failOffset1,
+			errorHandler: func(context.Context, kafka.Event, error) error {
+				return nil
+			},
+			handled: 2,
+		},
+		{
+			name:   "error handler rewrites the error",
+			values: []string{`{"op":"c"}`, `{"op":"u"}`, `{"op":"u"}`},
+			fail:   failOffset1,
+			errorHandler: func(_ context.Context, _ kafka.Event, err error) error {
+				return fmt.Errorf("orders projection: %w", err)
+			},
+			handled:  2,
+			letters:  1,
+			attempts: 3,
+			errIs:    errPoison,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := kafkatest.NewBroker()
+			broker.CreateTopic("orders", 1)
+			for _, v := range tt.values {
+				broker.Produce("orders", []byte("k"), []byte(v))
+			}
+
+			rec := newRecorder()
+			rec.fail = tt.fail
+			queue := &memoryQueue{}
+			opts := []kafka.Option{kafka.WithRetry(3, 0), kafka.WithDeadLetterQueue(queue)}
+			if tt.errorHandler != nil {
+				opts = append(opts, kafka.WithErrorHandler(tt.errorHandler))
+			}
+			stop := start(t, kafka.NewConsumer(broker.NewClient(testGroup, "orders"), rec, opts...))
+
+			// The failed message does not block the partition: the later
+			// message of the same key is handled and everything committed.
+			waitFor(t, "the commit", func() bool {
+				return broker.Committed(testGroup, "orders", 0) == int64(len(tt.values))
+			})
+			if err := stop(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got := rec.handled(); got != tt.handled {
+				t.Errorf("handled %d messages, want %d", got, tt.handled)
+			}
+
+			letters := queue.get()
+			if len(letters) != tt.letters {
+				t.Fatalf("got %d dead letters, want %d", len(letters), tt.letters)
+			}
+			if tt.letters == 0 {
+				return
+			}
+			dl := letters[0]
+			if dl.Topic != "orders" || dl.Offset != 1 || string(dl.Key) != "k" || string(dl.Value) != tt.values[1] {
+				t.Errorf("dead letter is %s/%d@%d %q=%q, want the message at offset 1",
+					dl.Topic, dl.Partition, dl.Offset, dl.Key, dl.Value)
+			}
+			if dl.Attempts != tt.attempts {
+				t.Errorf("dead letter has %d attempts, want %d", dl.Attempts, tt.attempts)
+			}
+			want := tt.errIs.Error()
+			if dl.Error == "" || !strings.Contains(dl.Error, want) {
+				t.Errorf("dead letter error %q does not mention %q", dl.Error, want)
+			}
+			if dl.FailedAt.IsZero() {
+				t.Errorf("dead letter has no failure time")
+			}
+		})
+	}
+}
+
+func TestConsumerStopsWhenDeadLetterQueueFails(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	broker.CreateTopic("orders", 1)
+	for _, v := range []string{"0", "1", "2"} {
+		broker.Produce("orders", []byte("k"), []byte(v))
+	}
+
+	errPoison := errors.New("poison")
+	errQueue := errors.New("queue is down")
+	rec := newRecorder()
+	rec.fail = func(ev kafka.Event) error {
+		if ev.Offset == 1 {
+			return errPoison
+		}
+		return nil
+	}
+	err := kafka.NewConsumer(broker.NewClient(testGroup, "orders"), rec,
+		kafka.WithRetry(1, 0), kafka.WithDeadLetterQueue(&memoryQueue{err: errQueue}), rawDecoder).
+		Run(context.Background())
+	if !errors.Is(err, errPoison) || !errors.Is(err, errQueue) {
+		t.Fatalf("Run returned %v, want the handler and the queue error", err)
+	}
+	if got := broker.Committed(testGroup, "orders", 0); got != 1 {
+		t.Errorf("committed %d, want 1", got)
+	}
+}
+
+func TestTopicDeadLetterQueue(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	broker.CreateTopic("orders", 1)
+	broker.Produce("orders", []byte("k"), []byte("not json"))
+
+	client := broker.NewClient(testGroup, "orders")
+	queue := kafka.NewTopicDeadLetterQueue(client, "orders.dlq")
+	stop := start(t, kafka.NewConsumer(client, newRecorder(), kafka.WithDeadLetterQueue(queue)))
+	waitFor(t, "the dead letter", func() bool { return len(broker.Messages("orders.dlq", 0)) == 1 })
+	if err := stop(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	m := broker.Messages("orders.dlq", 0)[0]
+	if string(m.Key) != "k" || string(m.Value) != "not json" {
+		t.Errorf("dead letter is %q=%q, want the original message", m.Key, m.Value)
+	}
+	headers := map[string]string{}
+	for _, h := range m.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	want := map[string]string{
+		kafka.HeaderDLQTopic:     "orders",
+		kafka.HeaderDLQPartition: "0",
+		kafka.HeaderDLQOffset:    "0",
+		kafka.HeaderDLQAttempts:  "0",
+	}
+	for k, v := range want {
+		if headers[k] != v {
+			t.Errorf("header %s is %q, want %q", k, headers[k], v)
+		}
+	}
+	if !strings.Contains(headers[kafka.HeaderDLQError], kafka.ErrDecode.Error()) {
+		t.Errorf("header %s is %q", kafka.HeaderDLQError, headers[kafka.HeaderDLQError])
+	}
+}
+
+func TestConsumerReplay(t *testing.T) {
+	errPoison := errors.New("poison")
+	calls := 0
+	handler := kafka.HandlerFunc(func(_ context.Context, ev kafka.Event) error {
+		calls++
+		if ev.Change == nil || ev.Change.Op != cdc.OpUpdate {
+			t.Errorf("replayed event has change %+v", ev.Change)
+		}
+		if string(ev.Key) == "poison" {
+			return errPoison
+		}
+		return nil
+	})
+	queue := &memoryQueue{}
+	c := kafka.NewConsumer(kafkatest.NewBroker().NewClient(testGroup), handler,
+		kafka.WithRetry(3, 0), kafka.WithDeadLetterQueue(queue))
+
+	msg := kafka.Message{Topic: "orders", Offset: 4, Key: []byte("k"), Value: []byte(`{"op":"u","after":{"id":1}}`)}
+	if err := c.Replay(context.Background(), msg); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+
+	// A replay is a single attempt that reports its error instead of
+	// dead-lettering the message again.
+	msg.Key = []byte("poison")
+	if err := c.Replay(context.Background(), msg); !errors.Is(err, errPoison) {
+		t.Errorf("Replay returned %v, want the handler error", err)
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+	if got := queue.get(); len(got) != 0 {
+		t.Errorf("replay put %d dead letters", len(got))
+	}
+
+	msg.Value = []byte("not json")
+	if err := c.Replay(context.Background(), msg); !errors.Is(err, kafka.ErrDecode) {
+		t.Errorf("Replay returned %v, want ErrDecode", err)
+	}
+}
@@ -0,0 +1,60 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Headers added to the messages of a dead letter topic. The key, value and
+// original headers are kept as they were.
+const (
+	HeaderDLQTopic     = "dlq.topic"
+	HeaderDLQPartition = "dlq.partition"
+	HeaderDLQOffset    = "dlq.offset"
+	HeaderDLQError     = "dlq.error"
+	HeaderDLQAttempts  = "dlq.attempts"
+	HeaderDLQFailedAt  = "dlq.failed_at"
+)
+
+// Producer writes messages. Their partition and offset are ignored; the
+// partition is chosen from the key.
+type Producer interface {
+	Produce(ctx context.Context, msgs ...Message) error
+}
+
+// TopicDeadLetterQueue routes dead letters to a Kafka topic, where they can
+// be inspected with the usual tools and replayed by consuming the topic.
+type TopicDeadLetterQueue struct {
+	producer Producer
+	topic    string
+}
+
+func NewTopicDeadLetterQueue(producer Producer, topic string) *TopicDeadLetterQueue {
+	return &TopicDeadLetterQueue{producer: producer, topic: topic}
+}
+
+func (q *TopicDeadLetterQueue) Put(ctx context.Context, dl DeadLetter) error {
+	headers := append([]Header(nil), dl.Headers...)
+	headers = append(headers,
+		Header{Key: HeaderDLQTopic, Value: []byte(dl.Topic)},
+		Header{Key: HeaderDLQPartition, Value: []byte(strconv.FormatInt(int64(dl.Partition), 10))},
+		Header{Key: HeaderDLQOffset, Value: []byte(strconv.FormatInt(dl.Offset, 10))},
+		Header{Key: HeaderDLQError, Value: []byte(dl.Error)},
+		Header{Key: HeaderDLQAttempts, Value: []byte(strconv.Itoa(dl.Attempts))},
+		Header{Key: HeaderDLQFailedAt, Value: []byte(dl.FailedAt.UTC().Format(time.RFC3339Nano))},
+	)
+
+	err := q.producer.Produce(ctx, Message{
+		Topic:     q.topic,
+		Key:       dl.Key,
+		Value:     dl.Value,
+		Headers:   headers,
+		Timestamp: dl.Timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("Put.Produce: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package kafka
+
+import (
+	"context"
+	"debezium_server/pkg/schemaregistry"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+const (
+	StartEarliest = "earliest"
+	StartLatest   = "latest"
+
+	DeadLetterNone     = "none"
+	DeadLetterPostgres = "postgres"
+	DeadLetterTopic    = "topic"
+
+	FormatJSON = "json"
+	FormatAvro = "avro"
+
+	defaultMaxPollRecords = 500
+)
+
+// Config is the consumer group. Topics are regular expressions when
+// TopicRegex is set, e.g. "^(products|orders)$" for the topics left by the
+// RegexRouter of init/postgres-connector.json.
+type Config struct {
+	Enabled     bool     `yaml:"enabled"      env:"KAFKA_ENABLED"      env-default:"false"`
+	Brokers     []string `yaml:"brokers"      env:"KAFKA_BROKERS"      env-separator:","`
+	Group       string   `yaml:"group"        env:"KAFKA_GROUP"        env-default:"debezium-server"`
+	Topics      []string `yaml:"topics"       env:"KAFKA_TOPICS"       env-separator:","`
+	TopicRegex  bool     `yaml:"topic_regex"  env:"KAFKA_TOPIC_REGEX"`
+	ClientID    string   `yaml:"client_id"    env:"KAFKA_CLIENT_ID"    env-default:"debezium-server"`
+	StartOffset string   `yaml:"start_offset" env:"KAFKA_START_OFFSET" env-default:"earliest"`
+	// Concurrency is how many messages with different keys are handled at once.
+	Concurrency    int `yaml:"concurrency"      env:"KAFKA_CONCURRENCY"      env-default:"8"`
+	MaxPollRecords int `yaml:"max_poll_records" env:"KAFKA_MAX_POLL_RECORDS" env-default:"500"`
+
+	// RetryAttempts is how often the handler is called for one message
+	// before it is dead-lettered.
+	RetryAttempts int           `yaml:"retry_attempts" env:"KAFKA_RETRY_ATTEMPTS" env-default:"3"`
+	RetryBackoff  time.Duration `yaml:"retry_backoff"  env:"KAFKA_RETRY_BACKOFF"  env-default:"500ms"`
+
+	DeadLetter DeadLetterConfig `yaml:"dead_letter"`
+
+	// Format is the converter the connectors use: json, or avro with the
+	// schemas in SchemaRegistry. With avro, JSON values are still read, so
+	// connectors can be switched one at a time.
+	Format         string                `yaml:"format" env:"KAFKA_FORMAT" env-default:"json"`
+	SchemaRegistry schemaregistry.Config `yaml:"schema_registry"`
+}
+
+// DeadLetterConfig selects where messages go that cannot be handled:
+// postgres, the default, stores them in cdc_dead_letters, where the API can
+// replay them, topic writes them to Topic and none stops the consumer, so
+// one poison message blocks its partition.
+type DeadLetterConfig struct {
+	Mode  string `yaml:"mode"  env:"KAFKA_DEAD_LETTER_MODE"  env-default:"postgres"`
+	Topic string `yaml:"topic" env:"KAFKA_DEAD_LETTER_TOPIC"`
+}
+
+// Validate returns a description of the first problem with the config.
+func (c Config) Validate() error {
+	if len(c.Brokers) == 0 {
+		return errors.New("brokers must not be empty")
+	}
+	if c.Group == "" {
+		return errors.New("group must not be empty")
+	}
+	if len(c.Topics) == 0 {
+		return errors.New("topics must not be empty")
+	}
+	if c.TopicRegex {
+		for _, t := range c.Topics {
+			if _, err := regexp.Compile(t); err != nil {
+				return fmt.Errorf("topic pattern %q: %w", t, err)
+			}
+		}
+	}
+	switch c.StartOffset {
+	case StartEarliest, StartLatest:
+	default:
+		return fmt.Errorf("start_offset must be %s or %s", StartEarliest, StartLatest)
+	}
+	if c.Concurrency < 1 || c.MaxPollRecords < 1 || c.RetryAttempts < 1 {
+		return errors.New("concurrency, max_poll_records and retry_attempts must be positive")
+	}
+	if c.RetryBackoff < 0 {
+		return errors.New("retry_backoff must not be negative")
+	}
+	switch c.DeadLetter.Mode {
+	case DeadLetterNone, DeadLetterPostgres:
+	case DeadLetterTopic:
+		if c.DeadLetter.Topic == "" {
+			return errors.New("dead_letter.topic is required with the topic mode")
+		}
+	default:
+		return fmt.Errorf("dead_letter.mode must be %s, %s or %s", DeadLetterNone, DeadLetterPostgres, DeadLetterTopic)
+	}
+	switch c.Format {
+	case FormatJSON:
+	case FormatAvro:
+		if c.SchemaRegistry.URL == "" {
+			return errors.New("schema_registry.url is required with the avro format")
+		}
+	default:
+		return fmt.Errorf("format must be %s or %s", FormatJSON, FormatAvro)
+	}
+	return nil
+}
+
+// GroupClient is a Client backed by a franz-go consumer group. Partitions
+// are only revoked between a Commit and the next Fetch.
+type GroupClient struct {
+	cl      *kgo.Client
+	maxPoll int
+
+	// OnFetchError is called with the fetch errors franz-go recovers from
+	// by itself, e.g. a broker that is briefly unreachable.
+	OnFetchError func(err error)
+}
+
+var (
+	_ Client   = (*GroupClient)(nil)
+	_ Producer = (*GroupClient)(nil)
+)
+
+// NewGroupClient joins the group. Offsets are never committed
+// automatically.
+func NewGroupClient(cfg Config, opts ...kgo.Opt) (*GroupClient, error) {
+	start := kgo.NewOffset().AtStart()
+	if cfg.StartOffset == StartLatest {
+		start = kgo.NewOffset().AtEnd()
+	}
+
+	kopts := []kgo.Opt{
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.ConsumerGroup(cfg.Group),
+		kgo.ConsumeTopics(cfg.Topics...),
+		kgo.ConsumeResetOffset(start),
+		kgo.DisableAutoCommit(),
+		kgo.BlockRebalanceOnPoll(),
+	}
+	if cfg.TopicRegex {
+		kopts = append(kopts, kgo.ConsumeRegex())
+	}
+	if cfg.ClientID != "" {
+		kopts = append(kopts, kgo.ClientID(cfg.ClientID))
+	}
+	kopts = append(kopts, opts...)
+
+	cl, err := kgo.NewClient(kopts...)
+	if err != nil {
+		return nil, fmt.Errorf("NewGroupClient: %w", err)
+	}
+
+	maxPoll := cfg.MaxPollRecords
+	if maxPoll < 1 {
+		maxPoll = defaultMaxPollRecords
+	}
+	return &GroupClient{cl: cl, maxPoll: maxPoll}, nil
+}
+
+// Fetch lets a pending rebalance go ahead, since the previous batch is
+// committed by now, and polls the next records. Errors franz-go retries are
+// passed to OnFetchError and the records that did arrive are returned; only
+// a closed client, failed authentication or a lost group session end the
+// fetch.
+func (c *GroupClient) Fetch(ctx context.Context) ([]Message, error) {
+	c.cl.AllowRebalance()
+
+	fetches := c.cl.PollRecords(ctx, c.maxPoll)
+	if fetches.IsClientClosed() {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	msgs, retried, err := readFetches(fetches)
+	if err != nil {
+		return nil, err
+	}
+	if c.OnFetchError != nil {
+		for _, err := range retried {
+			c.OnFetchError(err)
+		}
+	}
+	return msgs, nil
+}
+
+// readFetches returns the records of the fetches, the errors franz-go
+// recovers from and the first fatal error.
+func readFetches(fetches kgo.Fetches) ([]Message, []error, error) {
+	var retried []error
+	for _, fe := range fetches.Errors() {
+		err := fmt.Errorf("%s/%d: %w", fe.Topic, fe.Partition, fe.Err)
+		if fatalFetchError(fe.Err) {
+			return nil, nil, err
+		}
+		retried = append(retried, err)
+	}
+
+	msgs := make([]Message, 0, fetches.NumRecords())
+	fetches.EachRecord(func(r *kgo.Record) {
+		m := Message{
+			Topic:     r.Topic,
+			Partition: r.Partition,
+			Offset:    r.Offset,
+			Key:       r.Key,
+			Value:     r.Value,
+			Timestamp: r.Timestamp,
+		}
+		for _, h := range r.Headers {
+			m.Headers = append(m.Headers, Header{Key: h.Key, Value: h.Value})
+		}
+		msgs = append(msgs, m)
+	})
+
+	return msgs, retried, nil
+}
+
+// fatalFetchError reports whether polling again cannot help: the client is
+// closed, the member lost its group session or it is not authorized.
+func fatalFetchError(err error) bool {
+	var session *kgo.ErrGroupSession
+	switch {
+	case errors.Is(err, kgo.ErrClientClosed), errors.As(err, &session):
+		return true
+	case errors.Is(err, kerr.SaslAuthenticationFailed),
+		errors.Is(err, kerr.TopicAuthorizationFailed),
+		errors.Is(err, kerr.GroupAuthorizationFailed),
+		errors.Is(err, kerr.ClusterAuthorizationFailed):
+		return true
+	}
+	return false
+}
+
+func (c *GroupClient) Commit(ctx context.Context, offsets map[TopicPartition]int64) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	uncommitted := make(map[string]map[int32]kgo.EpochOffset)
+	for tp, offset := range offsets {
+		if uncommitted[tp.Topic] == nil {
+			uncommitted[tp.Topic] = make(map[int32]kgo.EpochOffset)
+		}
+		uncommitted[tp.Topic][tp.Partition] = kgo.EpochOffset{Epoch: -1, Offset: offset}
+	}
+
+	var commitErr error
+	c.cl.CommitOffsetsSync(ctx, uncommitted, func(_ *kgo.Client, _ *kmsg.OffsetCommitRequest, resp *kmsg.OffsetCommitResponse, err error) {
+		if err != nil {
+			commitErr = err
+			return
+		}
+		var errs []error
+		for _, t := range resp.Topics {
+			for _, p := range t.Partitions {
+				if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+					errs = append(errs, fmt.Errorf("%s/%d: %w", t.Topic, p.Partition, err))
+				}
+			}
+		}
+		commitErr = errors.Join(errs...)
+	})
+
+	return commitErr
+}
+
+// Produce writes the messages and waits until they are acknowledged.
+func (c *GroupClient) Produce(ctx context.Context, msgs ...Message) error {
+	records := make([]*kgo.Record, 0, len(msgs))
+	for _, m := range msgs {
+		r := &kgo.Record{Topic: m.Topic, Key: m.Key, Value: m.Value, Timestamp: m.Timestamp}
+		for _, h := range m.Headers {
+			r.Headers = append(r.Headers, kgo.RecordHeader{Key: h.Key, Value: h.Value})
+		}
+		records = append(records, r)
+	}
+
+	if err := c.cl.ProduceSync(ctx, records...).FirstErr(); err != nil {
+		return fmt.Errorf("Produce: %w", err)
+	}
+	return nil
+}
+
+// Close leaves the group.
+func (c *GroupClient) Close() {
+	c.cl.Close()
+}
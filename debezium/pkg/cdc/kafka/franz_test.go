@@ -0,0 +1,67 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestReadFetches(t *testing.T) {
+	records := []*kgo.Record{
+		{Topic: "orders", Partition: 0, Offset: 7, Key: []byte("1"), Value: []byte("{}")},
+		{Topic: "orders", Partition: 0, Offset: 8, Key: []byte("2"), Value: []byte("{}")},
+	}
+	fetches := func(errs ...error) kgo.Fetches {
+		parts := []kgo.FetchPartition{{Partition: 0, Records: records}}
+		for i, err := range errs {
+			parts = append(parts, kgo.FetchPartition{Partition: int32(i + 1), Err: err})
+		}
+		return kgo.Fetches{{Topics: []kgo.FetchTopic{{Topic: "orders", Partitions: parts}}}}
+	}
+
+	tests := []struct {
+		name    string
+		errs    []error
+		retried int
+		fatal   bool
+	}{
+		{name: "records only"},
+		{name: "retriable broker error", errs: []error{kerr.NotLeaderForPartition}, retried: 1},
+		{name: "data loss", errs: []error{&kgo.ErrDataLoss{Topic: "orders", Partition: 1}}, retried: 1},
+		{name: "unexpected error", errs: []error{errors.New("corrupt batch"), kerr.UnknownTopicOrPartition}, retried: 2},
+		{name: "client closed", errs: []error{kgo.ErrClientClosed}, fatal: true},
+		{name: "group session lost", errs: []error{&kgo.ErrGroupSession{Err: kerr.RebalanceInProgress}}, fatal: true},
+		{name: "not authorized", errs: []error{kerr.NotLeaderForPartition, kerr.TopicAuthorizationFailed}, fatal: true},
+		{name: "authentication failed", errs: []error{kerr.SaslAuthenticationFailed}, fatal: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, retried, err := readFetches(fetches(tt.errs...))
+			if tt.fatal {
+				if err == nil {
+					t.Fatalf("got no error, want a fatal one")
+				}
+				if msgs != nil {
+					t.Errorf("got %d messages with a fatal error", len(msgs))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if len(retried) != tt.retried {
+				t.Errorf("got %d retried errors, want %d", len(retried), tt.retried)
+			}
+			if len(msgs) != len(records) {
+				t.Fatalf("got %d messages, want %d", len(msgs), len(records))
+			}
+			for i, m := range msgs {
+				if m.Offset != records[i].Offset || string(m.Key) != string(records[i].Key) {
+					t.Errorf("message %d is %s/%d@%d key %q", i, m.Topic, m.Partition, m.Offset, m.Key)
+				}
+			}
+		})
+	}
+}
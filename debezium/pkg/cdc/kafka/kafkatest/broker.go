@@ -0,0 +1,306 @@
+// Package kafkatest provides an in-memory stand-in for a Kafka cluster, so
+// that consumers can be exercised without a broker.
+package kafkatest
+
+import (
+	"context"
+	"debezium_server/pkg/cdc/kafka"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"time"
+)
+
+const defaultMaxFetch = 100
+
+// Broker keeps topics, partitions and committed group offsets in memory.
+// Group members split the partitions of their topics round-robin. A member
+// picks up a changed assignment on its next Fetch and resumes from the
+// committed offsets, like a real consumer after a rebalance; unlike Kafka,
+// members do not wait for each other to give up partitions.
+type Broker struct {
+	mu      sync.Mutex
+	topics  map[string][][]kafka.Message
+	groups  map[string]*group
+	changed chan struct{}
+}
+
+type group struct {
+	members   []*Client
+	committed map[kafka.TopicPartition]int64
+	// generation changes whenever a member joins or leaves or a topic is
+	// created. Members of a group are expected to consume the same topics.
+	generation int
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		topics:  make(map[string][][]kafka.Message),
+		groups:  make(map[string]*group),
+		changed: make(chan struct{}),
+	}
+}
+
+// CreateTopic adds a topic. Producing to an unknown topic creates it with
+// one partition.
+func (b *Broker) CreateTopic(name string, partitions int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.topics[name]; !ok {
+		b.topics[name] = make([][]kafka.Message, max(partitions, 1))
+		b.rebalanceLocked()
+	}
+}
+
+// Produce appends a message to the partition its key hashes to, or to
+// partition 0 if it has none. A nil value is a tombstone.
+func (b *Broker) Produce(topic string, key, value []byte) (int32, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	m := b.appendLocked(kafka.Message{Topic: topic, Key: key, Value: value, Timestamp: time.Now()})
+	return m.Partition, m.Offset
+}
+
+// Messages returns the messages of a topic partition.
+func (b *Broker) Messages(topic string, partition int32) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	parts := b.topics[topic]
+	if int(partition) >= len(parts) {
+		return nil
+	}
+	return slices.Clone(parts[partition])
+}
+
+func (b *Broker) appendLocked(m kafka.Message) kafka.Message {
+	parts, ok := b.topics[m.Topic]
+	if !ok {
+		parts = make([][]kafka.Message, 1)
+		b.topics[m.Topic] = parts
+		b.rebalanceLocked()
+	}
+
+	m.Partition = 0
+	if m.Key != nil {
+		h := fnv.New32a()
+		h.Write(m.Key)
+		m.Partition = int32(h.Sum32() % uint32(len(parts)))
+	}
+	m.Offset = int64(len(parts[m.Partition]))
+	parts[m.Partition] = append(parts[m.Partition], m)
+	b.notifyLocked()
+
+	return m
+}
+
+// Committed returns the committed offset of the group, or -1.
+func (b *Broker) Committed(groupName, topic string, partition int32) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g, ok := b.groups[groupName]
+	if !ok {
+		return -1
+	}
+	offset, ok := g.committed[kafka.TopicPartition{Topic: topic, Partition: partition}]
+	if !ok {
+		return -1
+	}
+	return offset
+}
+
+// NewClient joins the group as a new member consuming the topics.
+func (b *Broker) NewClient(groupName string, topics ...string) *Client {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g, ok := b.groups[groupName]
+	if !ok {
+		g = &group{committed: make(map[kafka.TopicPartition]int64)}
+		b.groups[groupName] = g
+	}
+
+	c := &Client{
+		broker:     b,
+		group:      g,
+		topics:     topics,
+		generation: -1,
+		MaxFetch:   defaultMaxFetch,
+	}
+	g.members = append(g.members, c)
+	g.generation++
+	b.notifyLocked()
+
+	return c
+}
+
+// rebalanceLocked makes every member pick up new partitions.
+func (b *Broker) rebalanceLocked() {
+	for _, g := range b.groups {
+		g.generation++
+	}
+	b.notifyLocked()
+}
+
+// notifyLocked wakes up every blocked Fetch.
+func (b *Broker) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// Client is one group member. It implements kafka.Client.
+type Client struct {
+	broker *Broker
+	group  *group
+	topics []string
+
+	// MaxFetch caps the messages returned by one Fetch.
+	MaxFetch int
+
+	generation int
+	assigned   []kafka.TopicPartition
+	position   map[kafka.TopicPartition]int64
+	closed     bool
+}
+
+var (
+	_ kafka.Client   = (*Client)(nil)
+	_ kafka.Producer = (*Client)(nil)
+)
+
+func (c *Client) Fetch(ctx context.Context) ([]kafka.Message, error) {
+	for {
+		c.broker.mu.Lock()
+		if c.closed {
+			c.broker.mu.Unlock()
+			return nil, kafka.ErrClosed
+		}
+		if c.generation != c.group.generation {
+			c.rebalanceLocked()
+		}
+		msgs := c.pollLocked()
+		changed := c.broker.changed
+		c.broker.mu.Unlock()
+
+		if len(msgs) > 0 {
+			return msgs, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Commit stores the offsets of the partitions assigned on the last Fetch.
+// As with a blocked rebalance, the assignment only moves on the next Fetch.
+func (c *Client) Commit(_ context.Context, offsets map[kafka.TopicPartition]int64) error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+
+	if c.closed {
+		return kafka.ErrClosed
+	}
+	for tp, offset := range offsets {
+		if !slices.Contains(c.assigned, tp) {
+			return fmt.Errorf("%s/%d is not assigned to this member", tp.Topic, tp.Partition)
+		}
+		c.group.committed[tp] = offset
+	}
+	return nil
+}
+
+// Produce appends the messages like Broker.Produce, keeping their
+// headers and timestamps.
+func (c *Client) Produce(_ context.Context, msgs ...kafka.Message) error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+
+	if c.closed {
+		return kafka.ErrClosed
+	}
+	for _, m := range msgs {
+		if m.Timestamp.IsZero() {
+			m.Timestamp = time.Now()
+		}
+		c.broker.appendLocked(m)
+	}
+	return nil
+}
+
+// Close leaves the group.
+func (c *Client) Close() {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.group.members = slices.DeleteFunc(c.group.members, func(m *Client) bool { return m == c })
+	c.group.generation++
+	c.broker.notifyLocked()
+}
+
+// Assigned returns the partitions the member consumed on its last Fetch.
+func (c *Client) Assigned() []kafka.TopicPartition {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+
+	return slices.Clone(c.assigned)
+}
+
+// rebalanceLocked takes every n-th partition of the sorted partitions of
+// the member's topics and resumes them from the committed offsets.
+func (c *Client) rebalanceLocked() {
+	var all []kafka.TopicPartition
+	for _, t := range c.topics {
+		for p := range c.broker.topics[t] {
+			all = append(all, kafka.TopicPartition{Topic: t, Partition: int32(p)})
+		}
+	}
+	slices.SortFunc(all, func(a, b kafka.TopicPartition) int {
+		if a.Topic != b.Topic {
+			if a.Topic < b.Topic {
+				return -1
+			}
+			return 1
+		}
+		return int(a.Partition - b.Partition)
+	})
+
+	idx := slices.Index(c.group.members, c)
+	n := len(c.group.members)
+	c.assigned = c.assigned[:0]
+	c.position = make(map[kafka.TopicPartition]int64)
+	for i, tp := range all {
+		if i%n != idx {
+			continue
+		}
+		c.assigned = append(c.assigned, tp)
+		if offset, ok := c.group.committed[tp]; ok {
+			c.position[tp] = offset
+		}
+	}
+	c.generation = c.group.generation
+}
+
+func (c *Client) pollLocked() []kafka.Message {
+	var msgs []kafka.Message
+	for _, tp := range c.assigned {
+		log := c.broker.topics[tp.Topic][tp.Partition]
+		pos := c.position[tp]
+		for pos < int64(len(log)) && len(msgs) < c.MaxFetch {
+			msgs = append(msgs, log[pos])
+			pos++
+		}
+		c.position[tp] = pos
+	}
+	return msgs
+}
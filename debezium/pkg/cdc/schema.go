@@ -0,0 +1,252 @@
+package cdc
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ChangeKind is the kind of a difference between two schemas of a table.
+type ChangeKind string
+
+const (
+	ColumnAdded          ChangeKind = "column_added"
+	ColumnDropped        ChangeKind = "column_dropped"
+	TypeWidened          ChangeKind = "type_widened"
+	TypeNarrowed         ChangeKind = "type_narrowed"
+	TypeChanged          ChangeKind = "type_changed"
+	NullabilityRelaxed   ChangeKind = "nullability_relaxed"
+	NullabilityTightened ChangeKind = "nullability_tightened"
+)
+
+// Breaking reports whether consumers written against the old schema may
+// fail on events with the new one.
+func (k ChangeKind) Breaking() bool {
+	switch k {
+	case ColumnDropped, TypeNarrowed, TypeChanged, NullabilityTightened:
+		return true
+	}
+	return false
+}
+
+// Column is a column as the connector describes it. Type is the semantic
+// type, e.g. io.debezium.time.MicroTimestamp, when there is one and the
+// Kafka Connect type otherwise; for DDL events it is the database type.
+// Length and Scale are zero when unknown.
+type Column struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional"`
+	Length   int    `json:"length,omitempty"`
+	Scale    int    `json:"scale,omitempty"`
+}
+
+// TableSchema is the row schema of a table.
+type TableSchema struct {
+	Columns []Column `json:"columns"`
+}
+
+// Equal reports whether both schemas have the same columns in the same order.
+func (s TableSchema) Equal(other TableSchema) bool {
+	if len(s.Columns) != len(other.Columns) {
+		return false
+	}
+	for i := range s.Columns {
+		if s.Columns[i] != other.Columns[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ColumnChange is a difference between two schemas of a table. A column
+// whose type and nullability both changed has two changes.
+type ColumnChange struct {
+	Column   string     `json:"column"`
+	Kind     ChangeKind `json:"kind"`
+	Breaking bool       `json:"breaking"`
+	Before   *Column    `json:"before,omitempty"`
+	After    *Column    `json:"after,omitempty"`
+}
+
+// CompareSchemas returns the changes from before to after: changed and
+// dropped columns in the order of before, then added columns.
+func CompareSchemas(before, after TableSchema) []ColumnChange {
+	afterByName := make(map[string]Column, len(after.Columns))
+	for _, c := range after.Columns {
+		afterByName[c.Name] = c
+	}
+
+	changes := make([]ColumnChange, 0)
+	add := func(kind ChangeKind, b, a *Column) {
+		name := ""
+		if b != nil {
+			name = b.Name
+		} else {
+			name = a.Name
+		}
+		changes = append(changes, ColumnChange{Column: name, Kind: kind, Breaking: kind.Breaking(), Before: b, After: a})
+	}
+
+	beforeNames := make(map[string]bool, len(before.Columns))
+	for _, b := range before.Columns {
+		beforeNames[b.Name] = true
+		a, ok := afterByName[b.Name]
+		if !ok {
+			add(ColumnDropped, &b, nil)
+			continue
+		}
+		if kind := typeChange(b, a); kind != "" {
+			add(kind, &b, &a)
+		}
+		switch {
+		case b.Optional && !a.Optional:
+			add(NullabilityTightened, &b, &a)
+		case !b.Optional && a.Optional:
+			add(NullabilityRelaxed, &b, &a)
+		}
+	}
+	for _, a := range after.Columns {
+		if !beforeNames[a.Name] {
+			add(ColumnAdded, nil, &a)
+		}
+	}
+
+	return changes
+}
+
+// Breaking reports whether any of the changes is breaking.
+func Breaking(changes []ColumnChange) bool {
+	for _, c := range changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+type typeWidth struct {
+	family string
+	rank   int
+}
+
+// typeWidths orders the types a value can be widened to without loss, in
+// both the Kafka Connect and the SQL vocabulary. int8 is the Connect type.
+var typeWidths = map[string]typeWidth{
+	"int8": {"int", 1}, "tinyint": {"int", 1},
+	"int16": {"int", 2}, "smallint": {"int", 2},
+	"int32": {"int", 3}, "int": {"int", 3}, "integer": {"int", 3},
+	"int64": {"int", 4}, "bigint": {"int", 4},
+	"float": {"float", 1}, "real": {"float", 1},
+	"double": {"float", 2}, "double precision": {"float", 2},
+	"char": {"text", 1}, "varchar": {"text", 2}, "character varying": {"text", 2}, "text": {"text", 3},
+}
+
+// typeChange returns the kind of the type change of a column, or "" if
+// its type did not change.
+func typeChange(before, after Column) ChangeKind {
+	b, a := strings.ToLower(before.Type), strings.ToLower(after.Type)
+	if b != a {
+		bw, bok := typeWidths[b]
+		aw, aok := typeWidths[a]
+		switch {
+		case !bok || !aok || bw.family != aw.family:
+			return TypeChanged
+		case aw.rank > bw.rank:
+			return TypeWidened
+		case aw.rank < bw.rank:
+			return TypeNarrowed
+		}
+	}
+
+	switch {
+	case shrunk(before.Length, after.Length) || shrunk(before.Scale, after.Scale):
+		return TypeNarrowed
+	case shrunk(after.Length, before.Length) || shrunk(after.Scale, before.Scale):
+		return TypeWidened
+	}
+	return ""
+}
+
+func shrunk(before, after int) bool {
+	return before > 0 && after > 0 && after < before
+}
+
+// Parameters Debezium adds to the column schemas with
+// column.propagate.source.type, and the scale of Connect decimals.
+const (
+	paramSourceLength = "__debezium.source.column.length"
+	paramSourceScale  = "__debezium.source.column.scale"
+	paramDecimalScale = "scale"
+)
+
+func columnSize(params map[string]string) (length, scale int) {
+	length, _ = strconv.Atoi(params[paramSourceLength])
+	if s, ok := params[paramSourceScale]; ok {
+		scale, _ = strconv.Atoi(s)
+	} else {
+		scale, _ = strconv.Atoi(params[paramDecimalScale])
+	}
+	return length, scale
+}
+
+// ColumnFromConnect builds a column from a Kafka Connect field schema.
+func ColumnFromConnect(name, typ, semanticName string, optional bool, params map[string]string) Column {
+	c := Column{Name: name, Type: typ, Optional: optional}
+	if semanticName != "" {
+		c.Type = semanticName
+	}
+	c.Length, c.Scale = columnSize(params)
+	return c
+}
+
+// TableChange is a table in an event of the schema change topic.
+type TableChange struct {
+	// Type is CREATE, ALTER or DROP.
+	Type string `json:"type"`
+	// ID is the quoted table name, e.g. "testdb"."inventory"."products".
+	ID    string `json:"id"`
+	Table *struct {
+		Columns []DDLColumn `json:"columns"`
+	} `json:"table"`
+}
+
+// DDLColumn is a column of a TableChange.
+type DDLColumn struct {
+	Name     string `json:"name"`
+	TypeName string `json:"typeName"`
+	Length   int    `json:"length"`
+	Scale    int    `json:"scale"`
+	Optional bool   `json:"optional"`
+}
+
+// TableName returns the name in the form ChangeEvent.Table returns:
+// "schema.table" when ID has a database, schema and table, and the table
+// name otherwise.
+func (t TableChange) TableName() string {
+	var parts []string
+	for _, p := range strings.Split(t.ID, `"."`) {
+		parts = append(parts, strings.Trim(p, `"`))
+	}
+	if len(parts) == 3 {
+		return parts[1] + "." + parts[2]
+	}
+	return parts[len(parts)-1]
+}
+
+// Schema returns the columns after the change; a dropped table has none.
+func (t TableChange) Schema() TableSchema {
+	s := TableSchema{Columns: make([]Column, 0)}
+	if t.Table == nil || strings.EqualFold(t.Type, "DROP") {
+		return s
+	}
+	for _, c := range t.Table.Columns {
+		s.Columns = append(s.Columns, Column{
+			Name:     c.Name,
+			Type:     c.TypeName,
+			Optional: c.Optional,
+			Length:   c.Length,
+			Scale:    c.Scale,
+		})
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package cdc
+
+import (
+	"testing"
+)
+
+func TestCompareSchemas(t *testing.T) {
+	id := Column{Name: "id", Type: "int32"}
+	name := Column{Name: "name", Type: "string", Optional: true, Length: 255}
+	// varchar builds a string column the way the connector describes it
+	// with column.propagate.source.type.
+	varchar := func(length string, optional bool) Column {
+		return ColumnFromConnect("name", "string", "", optional,
+			map[string]string{paramSourceLength: length})
+	}
+
+	type change struct {
+		column   string
+		kind     ChangeKind
+		breaking bool
+	}
+	tests := []struct {
+		name   string
+		before []Column
+		after  []Column
+		want   []change
+	}{
+		{
+			name:   "unchanged",
+			before: []Column{id, name},
+			after:  []Column{id, name},
+		},
+		{
+			name:   "column added",
+			before: []Column{id},
+			after:  []Column{id, name},
+			want:   []change{{"name", ColumnAdded, false}},
+		},
+		{
+			name:   "column dropped",
+			before: []Column{id, name},
+			after:  []Column{id},
+			want:   []change{{"name", ColumnDropped, true}},
+		},
+		{
+			name:   "integer narrowed",
+			before: []Column{{Name: "id", Type: "int64"}},
+			after:  []Column{id},
+			want:   []change{{"id", TypeNarrowed, true}},
+		},
+		{
+			name:   "integer widened",
+			before: []Column{id},
+			after:  []Column{{Name: "id", Type: "int64"}},
+			want:   []change{{"id", TypeWidened, false}},
+		},
+		{
+			name:   "varchar narrowed",
+			before: []Column{varchar("255", true)},
+			after:  []Column{varchar("100", true)},
+			want:   []change{{"name", TypeNarrowed, true}},
+		},
+		{
+			name:   "varchar widened",
+			before: []Column{varchar("100", true)},
+			after:  []Column{varchar("255", true)},
+			want:   []change{{"name", TypeWidened, false}},
+		},
+		{
+			name:   "decimal scale narrowed",
+			before: []Column{{Name: "total", Type: "org.apache.kafka.connect.data.Decimal", Scale: 4}},
+			after:  []Column{{Name: "total", Type: "org.apache.kafka.connect.data.Decimal", Scale: 2}},
+			want:   []change{{"total", TypeNarrowed, true}},
+		},
+		{
+			name:   "type changed",
+			before: []Column{id},
+			after:  []Column{{Name: "id", Type: "string"}},
+			want:   []change{{"id", TypeChanged, true}},
+		},
+		{
+			name:   "nullability tightened",
+			before: []Column{name},
+			after:  []Column{{Name: "name", Type: "string", Length: 255}},
+			want:   []change{{"name", NullabilityTightened, true}},
+		},
+		{
+			name:   "nullability relaxed",
+			before: []Column{{Name: "name", Type: "string", Length: 255}},
+			after:  []Column{name},
+			want:   []change{{"name", NullabilityRelaxed, false}},
+		},
+		{
+			name:   "narrowed and tightened",
+			before: []Column{varchar("255", true)},
+			after:  []Column{varchar("100", false)},
+			want: []change{
+				{"name", TypeNarrowed, true},
+				{"name", NullabilityTightened, true},
+			},
+		},
+		{
+			name:   "unknown length is no change",
+			before: []Column{varchar("255", true)},
+			after:  []Column{{Name: "name", Type: "string", Optional: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := CompareSchemas(TableSchema{Columns: tt.before}, TableSchema{Columns: tt.after})
+			if len(changes) != len(tt.want) {
+				t.Fatalf("got %d changes %+v, want %d", len(changes), changes, len(tt.want))
+			}
+			breaking := false
+			for i, w := range tt.want {
+				c := changes[i]
+				if c.Column != w.column || c.Kind != w.kind || c.Breaking != w.breaking {
+					t.Errorf("change %d is %s %s (breaking %t), want %s %s (breaking %t)",
+						i, c.Column, c.Kind, c.Breaking, w.column, w.kind, w.breaking)
+				}
+				breaking = breaking || w.breaking
+			}
+			if Breaking(changes) != breaking {
+				t.Errorf("Breaking = %t, want %t", Breaking(changes), breaking)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package configtemplate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	ProviderEnv  = "env"
+	ProviderFile = "file"
+	ProviderVar  = "var"
+)
+
+var (
+	ErrFileNotAllowed = errors.New("file is outside the allowed template roots")
+	ErrEnvNotAllowed  = errors.New("is not in the allowed template environment variables")
+)
+
+// placeholderPattern matches ${env:NAME}, ${file:/path} and ${var:name},
+// and the same escaped with a second $. Other ${...} expressions, such as
+// the EventRouter ${routedByValue}, are left for the connector to resolve.
+// A Kafka Connect config provider named like ours, e.g.
+// ${file:/opt/connect.properties:password}, is written as $${file:...}.
+var placeholderPattern = regexp.MustCompile(`\$?\$\{(env|file|var):([^}]*)\}`)
+
+// Config restricts what server-side templates can read: ${file:...} only
+// under FileRoots and ${env:...} only for the names in EnvAllow. Both are
+// denied when empty.
+type Config struct {
+	VarsFile  string   `yaml:"vars_file"  env:"TEMPLATE_VARS_FILE"`
+	FileRoots []string `yaml:"file_roots" env:"TEMPLATE_FILE_ROOTS" env-separator:","`
+	EnvAllow  []string `yaml:"env_allow"  env:"TEMPLATE_ENV_ALLOW"  env-separator:","`
+}
+
+// Definition is a connector definition in the format accepted by POST /connectors.
+type Definition struct {
+	Name   string            `json:"name"`
+	Config map[string]string `json:"config"`
+}
+
+type Problem struct {
+	Key         string `json:"key"`
+	Placeholder string `json:"placeholder"`
+	Reason      string `json:"reason"`
+}
+
+// ValidationError lists every placeholder that could not be rendered.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		msgs = append(msgs, fmt.Sprintf("%s: %s %s", p.Key, p.Placeholder, p.Reason))
+	}
+	return "template validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Renderer resolves placeholders. By default ${file:...} and ${env:...} are
+// denied, since a template sent to the API would otherwise read any file or
+// variable of the server.
+type Renderer struct {
+	vars      map[string]string
+	lookupEnv func(string) (string, bool)
+	readFile  func(string) ([]byte, error)
+	fileRoots []string
+	envAllow  map[string]bool
+	anyFile   bool
+	anyEnv    bool
+}
+
+type Option func(*Renderer)
+
+// WithFileRoots allows ${file:...} placeholders for files under the given
+// directories.
+func WithFileRoots(roots ...string) Option {
+	return func(r *Renderer) {
+		for _, root := range roots {
+			if root != "" {
+				r.fileRoots = append(r.fileRoots, filepath.Clean(root))
+			}
+		}
+	}
+}
+
+// WithEnvAllowlist allows ${env:...} placeholders for the given variables.
+func WithEnvAllowlist(names ...string) Option {
+	return func(r *Renderer) {
+		if r.envAllow == nil {
+			r.envAllow = make(map[string]bool, len(names))
+		}
+		for _, name := range names {
+			if name != "" {
+				r.envAllow[name] = true
+			}
+		}
+	}
+}
+
+// Unrestricted allows every environment variable, and every file unless
+// file roots are set. It is meant for local tools such as connectorctl,
+// whose user can read those anyway.
+func Unrestricted() Option {
+	return func(r *Renderer) {
+		r.anyFile = true
+		r.anyEnv = true
+	}
+}
+
+// WithLookupEnv replaces os.LookupEnv as the source of ${env:...} values.
+func WithLookupEnv(lookup func(string) (string, bool)) Option {
+	return func(r *Renderer) {
+		r.lookupEnv = lookup
+	}
+}
+
+func NewRenderer(vars map[string]string, opts ...Option) *Renderer {
+	r := &Renderer{
+		vars:      vars,
+		lookupEnv: os.LookupEnv,
+		readFile:  os.ReadFile,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// WithVars returns a renderer whose variables are overridden by vars.
+func (r *Renderer) WithVars(vars map[string]string) *Renderer {
+	merged := make(map[string]string, len(r.vars)+len(vars))
+	for k, v := range r.vars {
+		merged[k] = v
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+
+	clone := *r
+	clone.vars = merged
+
+	return &clone
+}
+
+// Render substitutes the placeholders in the name and every config value.
+// All unresolved placeholders are reported together in a *ValidationError.
+func (r *Renderer) Render(def Definition) (Definition, error) {
+	var problems []Problem
+
+	name, nameProblems := r.renderValue("name", def.Name)
+	problems = append(problems, nameProblems...)
+
+	config, configProblems := r.RenderConfig(def.Config)
+	problems = append(problems, configProblems...)
+
+	if len(problems) > 0 {
+		return Definition{}, &ValidationError{Problems: problems}
+	}
+
+	return Definition{Name: name, Config: config}, nil
+}
+
+// RenderConfig renders a bare config map and returns the problems found, sorted by key.
+func (r *Renderer) RenderConfig(config map[string]string) (map[string]string, []Problem) {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var problems []Problem
+	rendered := make(map[string]string, len(config))
+	for _, k := range keys {
+		v, p := r.renderValue("config."+k, config[k])
+		rendered[k] = v
+		problems = append(problems, p...)
+	}
+
+	return rendered, problems
+}
+
+func (r *Renderer) renderValue(key, value string) (string, []Problem) {
+	var problems []Problem
+
+	rendered := placeholderPattern.ReplaceAllStringFunc(value, func(placeholder string) string {
+		if escaped, ok := strings.CutPrefix(placeholder, "$$"); ok {
+			return "$" + escaped
+		}
+		m := placeholderPattern.FindStringSubmatch(placeholder)
+		resolved, err := r.resolve(m[1], m[2])
+		if err != nil {
+			problems = append(problems, Problem{Key: key, Placeholder: placeholder, Reason: err.Error()})
+			return placeholder
+		}
+		return resolved
+	})
+
+	return rendered, problems
+}
+
+func (r *Renderer) resolve(provider, arg string) (string, error) {
+	if arg == "" {
+		return "", errors.New("has an empty name")
+	}
+
+	switch provider {
+	case ProviderEnv:
+		if !r.anyEnv && !r.envAllow[arg] {
+			return "", ErrEnvNotAllowed
+		}
+		v, ok := r.lookupEnv(arg)
+		if !ok {
+			return "", errors.New("is not defined in the environment")
+		}
+		return v, nil
+	case ProviderVar:
+		v, ok := r.vars[arg]
+		if !ok {
+			return "", errors.New("is not defined")
+		}
+		return v, nil
+	case ProviderFile:
+		if err := r.checkFile(arg); err != nil {
+			return "", err
+		}
+		data, err := r.readFile(arg)
+		if err != nil {
+			return "", fmt.Errorf("cannot be read: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	default:
+		return "", fmt.Errorf("uses unknown provider %q", provider)
+	}
+}
+
+// checkFile allows a file under one of the roots. The path is checked before
+// symlinks are resolved, so files outside the roots cannot be probed, and
+// again after, so a link cannot point out of them.
+func (r *Renderer) checkFile(path string) error {
+	if len(r.fileRoots) == 0 {
+		if r.anyFile {
+			return nil
+		}
+		return ErrFileNotAllowed
+	}
+
+	clean := filepath.Clean(path)
+	if !r.underRoot(clean) {
+		return ErrFileNotAllowed
+	}
+	resolved, err := filepath.EvalSymlinks(clean)
+	if err != nil {
+		return fmt.Errorf("cannot be read: %w", err)
+	}
+	if resolved != clean && !r.underRoot(resolved) {
+		return ErrFileNotAllowed
+	}
+
+	return nil
+}
+
+func (r *Renderer) underRoot(path string) bool {
+	for _, root := range r.fileRoots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads a connector definition template from a JSON file.
+func Load(path string) (Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Definition{}, fmt.Errorf("Load.ReadFile: %w", err)
+	}
+
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return Definition{}, fmt.Errorf("Load.Unmarshal: %w", err)
+	}
+
+	return def, nil
+}
+
+// LoadVars reads the variables of one environment from a flat JSON object.
+func LoadVars(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadVars.ReadFile: %w", err)
+	}
+
+	var vars map[string]string
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("LoadVars.Unmarshal: %w", err)
+	}
+
+	return vars, nil
+}
@@ -0,0 +1,186 @@
+package configtemplate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderValue(t *testing.T) {
+	env := map[string]string{"POSTGRES_PASSWORD": "s3cret", "HOME": "/root"}
+	r := NewRenderer(map[string]string{"tenant": "acme", "empty": ""},
+		WithEnvAllowlist("POSTGRES_PASSWORD", "UNSET"),
+		WithLookupEnv(func(name string) (string, bool) {
+			v, ok := env[name]
+			return v, ok
+		}),
+	)
+
+	tests := []struct {
+		name   string
+		value  string
+		want   string
+		reason string
+	}{
+		{name: "plain", value: "postgres", want: "postgres"},
+		{name: "var", value: "${var:tenant}", want: "acme"},
+		{name: "empty var", value: "x${var:empty}x", want: "xx"},
+		{name: "var in text", value: "dbserver_${var:tenant}_1", want: "dbserver_acme_1"},
+		{name: "allowed env", value: "${env:POSTGRES_PASSWORD}", want: "s3cret"},
+		{name: "several", value: "${var:tenant}:${env:POSTGRES_PASSWORD}", want: "acme:s3cret"},
+		{name: "other expression", value: "outbox.${routedByValue}", want: "outbox.${routedByValue}"},
+		{name: "unknown provider", value: "${vault:secret/pg}", want: "${vault:secret/pg}"},
+		{name: "escaped", value: "$${file:/opt/connect.properties:password}", want: "${file:/opt/connect.properties:password}"},
+		{name: "escaped var", value: "$${var:tenant}-${var:tenant}", want: "${var:tenant}-acme"},
+		{name: "undefined var", value: "${var:region}", reason: "is not defined"},
+		{name: "empty name", value: "${var:}", reason: "has an empty name"},
+		{name: "env not allowed", value: "${env:HOME}", reason: ErrEnvNotAllowed.Error()},
+		{name: "allowed env not set", value: "${env:UNSET}", reason: "is not defined in the environment"},
+		{name: "file without roots", value: "${file:/etc/passwd}", reason: ErrFileNotAllowed.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, problems := r.renderValue("config.x", tt.value)
+			if tt.reason == "" {
+				if len(problems) != 0 {
+					t.Fatalf("got problems %+v", problems)
+				}
+				if got != tt.want {
+					t.Errorf("rendered %q, want %q", got, tt.want)
+				}
+				return
+			}
+			if len(problems) != 1 || problems[0].Reason != tt.reason || problems[0].Placeholder != tt.value {
+				t.Errorf("got problems %+v, want %q for %s", problems, tt.reason, tt.value)
+			}
+			if got != tt.value {
+				t.Errorf("an unresolved placeholder was rendered as %q", got)
+			}
+		})
+	}
+}
+
+func TestFileRoots(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "secrets")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{root, filepath.Join(root, "pg"), outside} {
+		if err := os.Mkdir(d, 0o700); err != nil {
+			t.Fatalf("Mkdir: %v", err)
+		}
+	}
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	write(filepath.Join(root, "pg", "password"), "s3cret\n")
+	write(filepath.Join(outside, "token"), "leaked")
+	if err := os.Symlink(filepath.Join(outside, "token"), filepath.Join(root, "escape")); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "pg", "password"), filepath.Join(root, "current")); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+
+	r := NewRenderer(nil, WithFileRoots(root+"/"))
+
+	tests := []struct {
+		name string
+		path string
+		want string
+		err  error
+	}{
+		{name: "file under the root", path: root + "/pg/password", want: "s3cret"},
+		{name: "link within the root", path: root + "/current", want: "s3cret"},
+		{name: "unclean path within the root", path: root + "/pg/../pg/./password", want: "s3cret"},
+		{name: "relative traversal", path: "../../etc/passwd", err: ErrFileNotAllowed},
+		{name: "traversal from the root", path: root + "/../outside/token", err: ErrFileNotAllowed},
+		{name: "deep traversal from the root", path: root + "/pg/../../../../etc/passwd", err: ErrFileNotAllowed},
+		{name: "sibling with the root as prefix", path: root + "-old/password", err: ErrFileNotAllowed},
+		{name: "link out of the root", path: root + "/escape", err: ErrFileNotAllowed},
+		{name: "absolute path", path: "/etc/passwd", err: ErrFileNotAllowed},
+		{name: "missing file", path: root + "/pg/user", err: os.ErrNotExist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.resolve(ProviderFile, tt.path)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("resolve returned %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnrestricted(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "password")
+	if err := os.WriteFile(path, []byte("s3cret"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	t.Setenv("TEMPLATE_TEST_VALUE", "from-env")
+
+	r := NewRenderer(nil, Unrestricted())
+	got, problems := r.renderValue("config.x", "${file:"+path+"}/${env:TEMPLATE_TEST_VALUE}")
+	if len(problems) != 0 || got != "s3cret/from-env" {
+		t.Errorf("rendered %q with problems %+v", got, problems)
+	}
+
+	// File roots still apply to an unrestricted renderer.
+	r = NewRenderer(nil, Unrestricted(), WithFileRoots(filepath.Join(dir, "secrets")))
+	if _, err := r.resolve(ProviderFile, path); !errors.Is(err, ErrFileNotAllowed) {
+		t.Errorf("resolve returned %v, want %v", err, ErrFileNotAllowed)
+	}
+}
+
+func TestRender(t *testing.T) {
+	r := NewRenderer(map[string]string{"tenant": "acme"})
+	base := Definition{
+		Name: "inventory-${var:tenant}",
+		Config: map[string]string{
+			"topic.prefix":      "${var:tenant}",
+			"database.password": "${env:POSTGRES_PASSWORD}",
+			"database.dbname":   "${var:db}",
+		},
+	}
+
+	_, err := r.Render(base)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Render returned %v, want a ValidationError", err)
+	}
+	var keys []string
+	for _, p := range verr.Problems {
+		keys = append(keys, p.Key)
+	}
+	if want := []string{"config.database.dbname", "config.database.password"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got problems for %v, want %v", keys, want)
+	}
+	if !strings.Contains(err.Error(), "${var:db} is not defined") {
+		t.Errorf("the error %q does not name the placeholder", err)
+	}
+
+	def, err := r.WithVars(map[string]string{"db": "inventory", "tenant": "globex"}).Render(Definition{
+		Name:   base.Name,
+		Config: map[string]string{"topic.prefix": "${var:tenant}", "database.dbname": "${var:db}"},
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	want := Definition{
+		Name:   "inventory-globex",
+		Config: map[string]string{"topic.prefix": "globex", "database.dbname": "inventory"},
+	}
+	if !reflect.DeepEqual(def, want) {
+		t.Errorf("rendered %+v, want %+v", def, want)
+	}
+}
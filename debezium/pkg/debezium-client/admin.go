@@ -0,0 +1,167 @@
+package debezium_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	getServerInfo = "/"
+	listLoggers   = "/admin/loggers"
+	adminLogger   = "/admin/loggers/%s"
+)
+
+var (
+	ErrEmptyLoggerName = errors.New("logger name cannot be empty")
+)
+
+// GetServerInfo returns the version and commit of a worker and the ID of
+// the Kafka cluster it uses.
+func (c *Client) GetServerInfo(ctx context.Context) (ServerInfo, error) {
+	var info ServerInfo
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getServerInfo, nil)
+	if err != nil {
+		return ServerInfo{}, fmt.Errorf("GetServerInfo.NewRequestWithContext: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return ServerInfo{}, fmt.Errorf("GetServerInfo.Client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
+			return ServerInfo{}, fmt.Errorf("GetServerInfo.DecodeError: %w", err)
+		}
+		return ServerInfo{}, fmt.Errorf("GetServerInfo: %s", errorResponse.Message)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return ServerInfo{}, fmt.Errorf("GetServerInfo.UnmarshalJSON: %w", err)
+	}
+
+	return info, nil
+}
+
+// ListLoggers returns the levels of the loggers a worker has set explicitly.
+func (c *Client) ListLoggers(ctx context.Context) (map[string]LoggerLevel, error) {
+	var loggers map[string]LoggerLevel
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, listLoggers, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ListLoggers.NewRequestWithContext: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ListLoggers.Client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
+			return nil, fmt.Errorf("ListLoggers.DecodeError: %w", err)
+		}
+		return nil, fmt.Errorf("ListLoggers: %s", errorResponse.Message)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&loggers); err != nil {
+		return nil, fmt.Errorf("ListLoggers.UnmarshalJSON: %w", err)
+	}
+
+	return loggers, nil
+}
+
+func (c *Client) GetLogger(ctx context.Context, logger string) (LoggerLevel, error) {
+	if strings.TrimSpace(logger) == "" {
+		return LoggerLevel{}, ErrEmptyLoggerName
+	}
+
+	var level LoggerLevel
+
+	endpoint := fmt.Sprintf(adminLogger, url.PathEscape(logger))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return LoggerLevel{}, fmt.Errorf("GetLogger.NewRequestWithContext: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return LoggerLevel{}, fmt.Errorf("GetLogger.Client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
+			return LoggerLevel{}, fmt.Errorf("GetLogger.DecodeError: %w", err)
+		}
+		return LoggerLevel{}, fmt.Errorf("GetLogger: %s", errorResponse.Message)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&level); err != nil {
+		return LoggerLevel{}, fmt.Errorf("GetLogger.UnmarshalJSON: %w", err)
+	}
+
+	return level, nil
+}
+
+// SetLoggerLevel sets the level of a logger and its descendants. scope is
+// LoggerScopeWorker or LoggerScopeCluster. For the worker scope it returns
+// the loggers that were changed; the cluster scope is applied
+// asynchronously and returns none.
+func (c *Client) SetLoggerLevel(ctx context.Context, logger, level, scope string) ([]string, error) {
+	if strings.TrimSpace(logger) == "" {
+		return nil, ErrEmptyLoggerName
+	}
+
+	data, err := json.Marshal(LoggerLevel{Level: level})
+	if err != nil {
+		return nil, fmt.Errorf("SetLoggerLevel.Marshal: %w", err)
+	}
+
+	endpoint := fmt.Sprintf(adminLogger, url.PathEscape(logger))
+	if scope != "" {
+		endpoint += "?scope=" + url.QueryEscape(scope)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("SetLoggerLevel.NewRequestWithContext: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("SetLoggerLevel.Client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		var errorResponse ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
+			return nil, fmt.Errorf("SetLoggerLevel.DecodeError: %w", err)
+		}
+		return nil, fmt.Errorf("SetLoggerLevel: %s", errorResponse.Message)
+	}
+
+	var changed []string
+	if err := json.NewDecoder(resp.Body).Decode(&changed); err != nil {
+		return nil, fmt.Errorf("SetLoggerLevel.UnmarshalJSON: %w", err)
+	}
+
+	return changed, nil
+}
@@ -1,19 +1,232 @@
 package debezium_client
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"net/http"
-	"strings"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const (
+	defaultCooldown = 30 * time.Second
 )
 
+// Client talks to the REST API of one Kafka Connect cluster. Requests are
+// spread round-robin over the cluster's workers and fail over to the next
+// worker on connection errors.
 type Client struct {
 	cc      *http.Client
-	baseURL string
+	workers *pool
+	stop    chan struct{}
+	closed  sync.Once
+}
+
+type Option func(*options)
+
+type options struct {
+	httpClient  *http.Client
+	transport   http.RoundTripper
+	tlsConfig   *tls.Config
+	rootCAs     *x509.CertPool
+	certs       []tls.Certificate
+	injectors   []func(*http.Request)
+	cooldown    time.Duration
+	healthCheck time.Duration
+}
+
+// WithHTTPClient uses hc as the base of the client. Its transport, jar and,
+// if New gets no timeout, its timeout are kept. Redirects are always
+// handled by the client itself.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = hc
+	}
+}
+
+// WithTransport sends requests through rt instead of http.DefaultTransport.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = rt
+	}
+}
+
+// WithTLSConfig sets the TLS config of the transport. WithRootCAs and
+// WithClientCertificate are applied on top of it. TLS options only take
+// effect if the transport is an *http.Transport.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = config
+	}
+}
+
+// WithRootCAs verifies the workers' certificates against pool instead of
+// the system roots.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(o *options) {
+		o.rootCAs = pool
+	}
 }
 
-func New(baseUrl string, timeout time.Duration) *Client {
-	return &Client{
-		baseURL: strings.TrimRight(baseUrl, "/"),
-		cc:      &http.Client{Timeout: timeout},
+// WithClientCertificate presents cert to the workers for mutual TLS.
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(o *options) {
+		o.certs = append(o.certs, cert)
+	}
+}
+
+// WithHeaderInjector calls fn on every request before it is sent, e.g. to
+// add default headers. Injectors run in the order they were given. The
+// Authorization header is dropped on redirects to hosts outside the cluster.
+func WithHeaderInjector(fn func(*http.Request)) Option {
+	return func(o *options) {
+		o.injectors = append(o.injectors, fn)
+	}
+}
+
+// WithBasicAuth sends HTTP basic credentials with every request.
+func WithBasicAuth(username, password string) Option {
+	return WithHeaderInjector(func(r *http.Request) {
+		r.SetBasicAuth(username, password)
+	})
+}
+
+// WithBearerToken sends the token in the Authorization header of every request.
+func WithBearerToken(token string) Option {
+	return WithHeaderInjector(func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token)
+	})
+}
+
+// WithCooldown sets how long a worker that failed is skipped. Defaults to 30s.
+func WithCooldown(d time.Duration) Option {
+	return func(o *options) {
+		o.cooldown = d
+	}
+}
+
+// WithHealthCheck probes every worker at the given interval and marks it up
+// or down. Without it, a worker that is down is tried again once its
+// cooldown ends. Close stops the probes.
+func WithHealthCheck(interval time.Duration) Option {
+	return func(o *options) {
+		o.healthCheck = interval
+	}
+}
+
+// New returns a client for the Kafka Connect workers at baseURLs. Any worker
+// can serve any request; writes that reach a follower are redirected to the
+// leader by Kafka Connect.
+func New(baseURLs []string, timeout time.Duration, opts ...Option) *Client {
+	o := options{cooldown: defaultCooldown}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cc := &http.Client{}
+	if o.httpClient != nil {
+		*cc = *o.httpClient
+	}
+	if timeout > 0 {
+		cc.Timeout = timeout
+	}
+
+	transport := o.transport
+	if transport == nil {
+		transport = cc.Transport
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	transport = o.withTLS(transport)
+	if len(o.injectors) > 0 {
+		transport = &headerTransport{base: transport, injectors: o.injectors}
+	}
+
+	// Propagates the trace context to Kafka Connect and records a span per call.
+	cc.Transport = otelhttp.NewTransport(transport,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "connect " + r.Method
+		}),
+	)
+	// Redirects are followed by do, which keeps the method and body.
+	cc.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	c := &Client{
+		cc:      cc,
+		workers: newPool(o.cooldown),
+		stop:    make(chan struct{}),
+	}
+	c.SetBaseURLs(baseURLs)
+
+	if o.healthCheck > 0 {
+		go c.healthCheck(o.healthCheck)
+	}
+
+	return c
+}
+
+// SetBaseURLs replaces the workers of the cluster. It is safe to call while
+// requests are in flight; they finish against the old workers.
+func (c *Client) SetBaseURLs(baseURLs []string) {
+	c.workers.set(baseURLs)
+}
+
+// BaseURLs returns the worker URLs the client currently uses.
+func (c *Client) BaseURLs() []string {
+	return c.workers.urls()
+}
+
+// Close stops the health checks.
+func (c *Client) Close() {
+	c.closed.Do(func() { close(c.stop) })
+}
+
+// withTLS applies the TLS options to a copy of rt. Other round trippers are
+// returned as they are.
+func (o *options) withTLS(rt http.RoundTripper) http.RoundTripper {
+	if o.tlsConfig == nil && o.rootCAs == nil && len(o.certs) == 0 {
+		return rt
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return rt
+	}
+
+	t = t.Clone()
+	config := o.tlsConfig
+	if config == nil {
+		config = t.TLSClientConfig
+	}
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	config = config.Clone()
+	if o.rootCAs != nil {
+		config.RootCAs = o.rootCAs
+	}
+	config.Certificates = append(config.Certificates, o.certs...)
+	t.TLSClientConfig = config
+
+	return t
+}
+
+type headerTransport struct {
+	base      http.RoundTripper
+	injectors []func(*http.Request)
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for _, inject := range t.injectors {
+		inject(req)
+	}
+	if toForeignHost(req.Context()) {
+		stripCredentials(req.Header)
 	}
+	return t.base.RoundTrip(req)
 }
@@ -0,0 +1,122 @@
+package debezium_client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	keyConnectorClass     = "connector.class"
+	keyTasksMax           = "tasks.max"
+	keyDatabaseHostname   = "database.hostname"
+	keyDatabasePort       = "database.port"
+	keyDatabaseUser       = "database.user"
+	keyDatabasePassword   = "database.password"
+	keyDatabaseDbname     = "database.dbname"
+	keyDatabaseServerName = "database.server.name"
+)
+
+// ConnectorConfig is a typed connector config that flattens into the
+// property map Kafka Connect expects.
+type ConnectorConfig interface {
+	ToMap() map[string]string
+}
+
+// ConfigMap is an untyped connector config.
+type ConfigMap map[string]string
+
+func (m ConfigMap) ToMap() map[string]string {
+	return m
+}
+
+// NewCreateConnectorConfig splits a flat connector config into the typed
+// fields and AdditionalParameters.
+func NewCreateConnectorConfig(config map[string]string) CreateConnectorConfig {
+	var c CreateConnectorConfig
+	c.AdditionalParameters = make(map[string]string)
+
+	for k, v := range config {
+		switch k {
+		case keyConnectorClass:
+			c.ConnectorClass = v
+		case keyTasksMax:
+			c.TasksMax = v
+		case keyDatabaseHostname:
+			c.DatabaseHostname = v
+		case keyDatabasePort:
+			c.DatabasePort = v
+		case keyDatabaseUser:
+			c.DatabaseUser = v
+		case keyDatabasePassword:
+			c.DatabasePassword = v
+		case keyDatabaseDbname:
+			c.DatabaseDbname = v
+		case keyDatabaseServerName:
+			c.DatabaseServerName = v
+		default:
+			c.AdditionalParameters[k] = v
+		}
+	}
+
+	return c
+}
+
+// ToMap flattens the typed fields and AdditionalParameters into the property
+// map Kafka Connect expects. Empty typed fields are omitted.
+func (c CreateConnectorConfig) ToMap() map[string]string {
+	m := make(map[string]string, len(c.AdditionalParameters)+8)
+	for k, v := range c.AdditionalParameters {
+		m[k] = v
+	}
+
+	for k, v := range map[string]string{
+		keyConnectorClass:     c.ConnectorClass,
+		keyTasksMax:           c.TasksMax,
+		keyDatabaseHostname:   c.DatabaseHostname,
+		keyDatabasePort:       c.DatabasePort,
+		keyDatabaseUser:       c.DatabaseUser,
+		keyDatabasePassword:   c.DatabasePassword,
+		keyDatabaseDbname:     c.DatabaseDbname,
+		keyDatabaseServerName: c.DatabaseServerName,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+
+	return m
+}
+
+func (c CreateConnectorConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.ToMap())
+}
+
+func (c *CreateConnectorConfig) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("CreateConnectorConfig.UnmarshalJSON: %w", err)
+	}
+
+	*c = NewCreateConnectorConfig(m)
+
+	return nil
+}
+
+// ToMap flattens the fields and AdditionalParameters. Empty fields are omitted.
+func (c UpdateConnectorConfigRequest) ToMap() map[string]string {
+	m := make(map[string]string, len(c.AdditionalParameters)+2)
+	for k, v := range c.AdditionalParameters {
+		m[k] = v
+	}
+	if c.ConnectorClass != "" {
+		m[keyConnectorClass] = c.ConnectorClass
+	}
+	if c.TasksMax != "" {
+		m[keyTasksMax] = c.TasksMax
+	}
+	return m
+}
+
+func (c UpdateConnectorConfigRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.ToMap())
+}
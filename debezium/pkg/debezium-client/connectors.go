@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 )
 
@@ -22,6 +23,10 @@ const (
 	getConnectorTasks     = "/connectors/%s/tasks"
 	restartConnectorTask  = "/connectors/%s/tasks/%d/restart"
 	listConnectors        = "/connectors"
+	stopConnector         = "/connectors/%s/stop"
+	connectorOffsets      = "/connectors/%s/offsets"
+	getConnectorTopics    = "/connectors/%s/topics"
+	resetConnectorTopics  = "/connectors/%s/topics/reset"
 )
 
 var (
@@ -41,13 +46,13 @@ func (c *Client) CreateConnector(ctx context.Context, data CreateConnectorReques
 		return nil, fmt.Errorf("CreateConnector.Marshal: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+postCreateConnectors, bytes.NewBuffer(d))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, postCreateConnectors, bytes.NewBuffer(d))
 	if err != nil {
 		return nil, fmt.Errorf("CreateConnector.NewRequestWithContext: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.cc.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("CreateConnector.Client.Do: %w", err)
 	}
@@ -76,13 +81,13 @@ func (c *Client) GetConnector(ctx context.Context, name string) (GetConnectorRes
 
 	var connectorResponse GetConnectorResponse
 
-	url := fmt.Sprintf(c.baseURL+getConnector, name)
+	url := fmt.Sprintf(getConnector, name)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return GetConnectorResponse{}, fmt.Errorf("GetConnector.NewRequestWithContext: %w", err)
 	}
 
-	resp, err := c.cc.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return GetConnectorResponse{}, fmt.Errorf("GetConnector.Client.Do: %w", err)
 	}
@@ -110,13 +115,13 @@ func (c *Client) GetConnectorStatus(ctx context.Context, name string) (Connector
 
 	var status ConnectorStatus
 
-	url := fmt.Sprintf(c.baseURL+getConnectorStatus, name)
+	url := fmt.Sprintf(getConnectorStatus, name)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return ConnectorStatus{}, fmt.Errorf("GetConnectorStatus.NewRequestWithContext: %w", err)
 	}
 
-	resp, err := c.cc.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return ConnectorStatus{}, fmt.Errorf("GetConnectorStatus.Client.Do: %w", err)
 	}
@@ -142,13 +147,13 @@ func (c *Client) DeleteConnector(ctx context.Context, name string) error {
 		return err
 	}
 
-	url := fmt.Sprintf(c.baseURL+deleteConnector, name)
+	url := fmt.Sprintf(deleteConnector, name)
 	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
 	if err != nil {
 		return fmt.Errorf("DeleteConnector.NewRequestWithContext: %w", err)
 	}
 
-	resp, err := c.cc.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("DeleteConnector.Client.Do: %w", err)
 	}
@@ -165,48 +170,70 @@ func (c *Client) DeleteConnector(ctx context.Context, name string) error {
 	return nil
 }
 
+// UpdateConnectorConfig replaces the config of a connector. Like
+// UpsertConnector, it creates the connector if it does not exist.
 func (c *Client) UpdateConnectorConfig(
 	ctx context.Context,
 	name string,
-	config map[string]interface{},
+	config ConnectorConfig,
 ) (GetConnectorResponse, error) {
+	resp, _, err := c.putConnectorConfig(ctx, "UpdateConnectorConfig", name, config)
+	return resp, err
+}
+
+// UpsertConnector creates the connector or replaces its config if it
+// exists, keeping its offsets. created reports which one happened.
+func (c *Client) UpsertConnector(
+	ctx context.Context,
+	name string,
+	config ConnectorConfig,
+) (resp GetConnectorResponse, created bool, err error) {
+	return c.putConnectorConfig(ctx, "UpsertConnector", name, config)
+}
+
+func (c *Client) putConnectorConfig(
+	ctx context.Context,
+	op string,
+	name string,
+	config ConnectorConfig,
+) (GetConnectorResponse, bool, error) {
 	if err := validateConnectorName(name); err != nil {
-		return GetConnectorResponse{}, err
+		return GetConnectorResponse{}, false, err
 	}
 
 	var connectorResponse GetConnectorResponse
 
-	data, err := json.Marshal(config)
+	data, err := json.Marshal(config.ToMap())
 	if err != nil {
-		return GetConnectorResponse{}, fmt.Errorf("UpdateConnectorConfig.Marshal: %w", err)
+		return GetConnectorResponse{}, false, fmt.Errorf("%s.Marshal: %w", op, err)
 	}
 
-	url := fmt.Sprintf(c.baseURL+updateConnectorConfig, name)
+	url := fmt.Sprintf(updateConnectorConfig, name)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewBuffer(data))
 	if err != nil {
-		return GetConnectorResponse{}, fmt.Errorf("UpdateConnectorConfig.NewRequestWithContext: %w", err)
+		return GetConnectorResponse{}, false, fmt.Errorf("%s.NewRequestWithContext: %w", op, err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.cc.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return GetConnectorResponse{}, fmt.Errorf("UpdateConnectorConfig.Client.Do: %w", err)
+		return GetConnectorResponse{}, false, fmt.Errorf("%s.Client.Do: %w", op, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		var errorResponse ErrorResponse
 		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
-			return GetConnectorResponse{}, fmt.Errorf("UpdateConnectorConfig.DecodeError: %w", err)
+			return GetConnectorResponse{}, false, fmt.Errorf("%s.DecodeError: %w", op, err)
 		}
-		return GetConnectorResponse{}, fmt.Errorf("UpdateConnectorConfig: %s", errorResponse.Message)
+		return GetConnectorResponse{}, false, fmt.Errorf("%s: %s", op, errorResponse.Message)
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&connectorResponse); err != nil {
-		return GetConnectorResponse{}, fmt.Errorf("UpdateConnectorConfig.UnmarshalJSON: %w", err)
+		return GetConnectorResponse{}, false, fmt.Errorf("%s.UnmarshalJSON: %w", op, err)
 	}
 
-	return connectorResponse, nil
+	return connectorResponse, resp.StatusCode == http.StatusCreated, nil
 }
 
 func (c *Client) PauseConnector(ctx context.Context, name string) error {
@@ -214,13 +241,13 @@ func (c *Client) PauseConnector(ctx context.Context, name string) error {
 		return err
 	}
 
-	url := fmt.Sprintf(c.baseURL+pauseConnector, name)
+	url := fmt.Sprintf(pauseConnector, name)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, nil)
 	if err != nil {
 		return fmt.Errorf("PauseConnector.NewRequestWithContext: %w", err)
 	}
 
-	resp, err := c.cc.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("PauseConnector.Client.Do: %w", err)
 	}
@@ -242,13 +269,13 @@ func (c *Client) ResumeConnector(ctx context.Context, name string) error {
 		return err
 	}
 
-	url := fmt.Sprintf(c.baseURL+resumeConnector, name)
+	url := fmt.Sprintf(resumeConnector, name)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, nil)
 	if err != nil {
 		return fmt.Errorf("ResumeConnector.NewRequestWithContext: %w", err)
 	}
 
-	resp, err := c.cc.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("ResumeConnector.Client.Do: %w", err)
 	}
@@ -270,13 +297,13 @@ func (c *Client) RestartConnector(ctx context.Context, name string) error {
 		return err
 	}
 
-	url := fmt.Sprintf(c.baseURL+restartConnector, name)
+	url := fmt.Sprintf(restartConnector, name)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
 		return fmt.Errorf("RestartConnector.NewRequestWithContext: %w", err)
 	}
 
-	resp, err := c.cc.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("RestartConnector.Client.Do: %w", err)
 	}
@@ -301,13 +328,13 @@ func (c *Client) GetConnectorTasks(ctx context.Context, name string) ([]TaskInfo
 
 	var tasks []TaskInfo
 
-	url := fmt.Sprintf(c.baseURL+getConnectorTasks, name)
+	url := fmt.Sprintf(getConnectorTasks, name)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("GetConnectorTasks.NewRequestWithContext: %w", err)
 	}
 
-	resp, err := c.cc.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("GetConnectorTasks.Client.Do: %w", err)
 	}
@@ -333,13 +360,13 @@ func (c *Client) RestartConnectorTask(ctx context.Context, name string, taskId i
 		return err
 	}
 
-	url := fmt.Sprintf(c.baseURL+restartConnectorTask, name, taskId)
+	url := fmt.Sprintf(restartConnectorTask, name, taskId)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
 	if err != nil {
 		return fmt.Errorf("RestartConnectorTask.NewRequestWithContext: %w", err)
 	}
 
-	resp, err := c.cc.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("RestartConnectorTask.Client.Do: %w", err)
 	}
@@ -360,7 +387,7 @@ func (c *Client) RestartConnectorTask(ctx context.Context, name string, taskId i
 func (c *Client) ListConnectors(ctx context.Context, expandStatus bool) (ListConnectorsResponse, error) {
 	var result ListConnectorsResponse
 
-	endpoint := c.baseURL + listConnectors
+	endpoint := listConnectors
 	if expandStatus {
 		endpoint += "?expand=status"
 	}
@@ -370,7 +397,7 @@ func (c *Client) ListConnectors(ctx context.Context, expandStatus bool) (ListCon
 		return ListConnectorsResponse{}, fmt.Errorf("ListConnectors.NewRequestWithContext: %w", err)
 	}
 
-	resp, err := c.cc.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return ListConnectorsResponse{}, fmt.Errorf("ListConnectors.Client.Do: %w", err)
 	}
@@ -385,16 +412,21 @@ func (c *Client) ListConnectors(ctx context.Context, expandStatus bool) (ListCon
 	}
 
 	if expandStatus {
-		var statusMap map[string]ConnectorStatus
-		if err := json.NewDecoder(resp.Body).Decode(&statusMap); err != nil {
+		// Kafka Connect nests each status under its expansion name.
+		var expanded map[string]struct {
+			Status ConnectorStatus `json:"status"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&expanded); err != nil {
 			return ListConnectorsResponse{}, fmt.Errorf("ListConnectors.UnmarshalJSON: %w", err)
 		}
 
-		result.Statuses = statusMap
-		result.Names = make([]string, 0, len(statusMap))
-		for name := range statusMap {
+		result.Statuses = make(map[string]ConnectorStatus, len(expanded))
+		result.Names = make([]string, 0, len(expanded))
+		for name, e := range expanded {
+			result.Statuses[name] = e.Status
 			result.Names = append(result.Names, name)
 		}
+		sort.Strings(result.Names)
 	} else {
 		var names []string
 		if err := json.NewDecoder(resp.Body).Decode(&names); err != nil {
@@ -405,3 +437,202 @@ func (c *Client) ListConnectors(ctx context.Context, expandStatus bool) (ListCon
 
 	return result, nil
 }
+
+// StopConnector stops the connector and shuts down its tasks without
+// deleting it. The connector must be stopped before its offsets can be
+// changed. Requires Kafka Connect 3.5 or later.
+func (c *Client) StopConnector(ctx context.Context, name string) error {
+	if err := validateConnectorName(name); err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf(stopConnector, name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, nil)
+	if err != nil {
+		return fmt.Errorf("StopConnector.NewRequestWithContext: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("StopConnector.Client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNoContent {
+		var errorResponse ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
+			return fmt.Errorf("StopConnector.DecodeError: %w", err)
+		}
+		return fmt.Errorf("StopConnector: %s", errorResponse.Message)
+	}
+
+	return nil
+}
+
+func (c *Client) GetConnectorOffsets(ctx context.Context, name string) (ConnectorOffsets, error) {
+	if err := validateConnectorName(name); err != nil {
+		return ConnectorOffsets{}, err
+	}
+
+	var offsets ConnectorOffsets
+
+	url := fmt.Sprintf(connectorOffsets, name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return ConnectorOffsets{}, fmt.Errorf("GetConnectorOffsets.NewRequestWithContext: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return ConnectorOffsets{}, fmt.Errorf("GetConnectorOffsets.Client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
+			return ConnectorOffsets{}, fmt.Errorf("GetConnectorOffsets.DecodeError: %w", err)
+		}
+		return ConnectorOffsets{}, fmt.Errorf("GetConnectorOffsets: %s", errorResponse.Message)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&offsets); err != nil {
+		return ConnectorOffsets{}, fmt.Errorf("GetConnectorOffsets.UnmarshalJSON: %w", err)
+	}
+
+	return offsets, nil
+}
+
+// AlterConnectorOffsets overwrites the offsets of the given partitions.
+// The connector must be stopped.
+func (c *Client) AlterConnectorOffsets(ctx context.Context, name string, offsets ConnectorOffsets) error {
+	if err := validateConnectorName(name); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(offsets)
+	if err != nil {
+		return fmt.Errorf("AlterConnectorOffsets.Marshal: %w", err)
+	}
+
+	url := fmt.Sprintf(connectorOffsets, name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewBuffer(data))
+	if err != nil {
+		return fmt.Errorf("AlterConnectorOffsets.NewRequestWithContext: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("AlterConnectorOffsets.Client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
+			return fmt.Errorf("AlterConnectorOffsets.DecodeError: %w", err)
+		}
+		return fmt.Errorf("AlterConnectorOffsets: %s", errorResponse.Message)
+	}
+
+	return nil
+}
+
+// ResetConnectorOffsets deletes all committed offsets of a stopped
+// connector, so it starts over as if newly created.
+func (c *Client) ResetConnectorOffsets(ctx context.Context, name string) error {
+	if err := validateConnectorName(name); err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf(connectorOffsets, name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("ResetConnectorOffsets.NewRequestWithContext: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("ResetConnectorOffsets.Client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
+			return fmt.Errorf("ResetConnectorOffsets.DecodeError: %w", err)
+		}
+		return fmt.Errorf("ResetConnectorOffsets: %s", errorResponse.Message)
+	}
+
+	return nil
+}
+
+// GetConnectorTopics returns the topics the connector has used since it was
+// created or its topics were last reset. Requires topic tracking, which is
+// enabled by default.
+func (c *Client) GetConnectorTopics(ctx context.Context, name string) ([]string, error) {
+	if err := validateConnectorName(name); err != nil {
+		return nil, err
+	}
+
+	var topics map[string]struct {
+		Topics []string `json:"topics"`
+	}
+
+	url := fmt.Sprintf(getConnectorTopics, name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("GetConnectorTopics.NewRequestWithContext: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GetConnectorTopics.Client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
+			return nil, fmt.Errorf("GetConnectorTopics.DecodeError: %w", err)
+		}
+		return nil, fmt.Errorf("GetConnectorTopics: %s", errorResponse.Message)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&topics); err != nil {
+		return nil, fmt.Errorf("GetConnectorTopics.UnmarshalJSON: %w", err)
+	}
+
+	return topics[name].Topics, nil
+}
+
+// ResetConnectorTopics clears the set of topics recorded for the connector.
+func (c *Client) ResetConnectorTopics(ctx context.Context, name string) error {
+	if err := validateConnectorName(name); err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf(resetConnectorTopics, name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, nil)
+	if err != nil {
+		return fmt.Errorf("ResetConnectorTopics.NewRequestWithContext: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("ResetConnectorTopics.Client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		var errorResponse ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
+			return fmt.Errorf("ResetConnectorTopics.DecodeError: %w", err)
+		}
+		return fmt.Errorf("ResetConnectorTopics: %s", errorResponse.Message)
+	}
+
+	return nil
+}
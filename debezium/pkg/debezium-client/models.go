@@ -21,6 +21,8 @@ type GetConnectorsResponse struct {
 type CreateConnectorRequest struct {
 	Name   string                `json:"name"`
 	Config CreateConnectorConfig `json:"config"`
+	// InitialState is RUNNING, PAUSED or STOPPED. Requires Kafka Connect 3.7 or later.
+	InitialState string `json:"initial_state,omitempty"`
 }
 
 type CreateConnectorConfig struct {
@@ -32,7 +34,7 @@ type CreateConnectorConfig struct {
 	DatabasePassword     string            `json:"database.password"`
 	DatabaseDbname       string            `json:"database.dbname"`
 	DatabaseServerName   string            `json:"database.server.name"`
-	AdditionalParameters map[string]string `json:"-,omitempty"`
+	AdditionalParameters map[string]string `json:"-"`
 }
 
 type CreateConnectorResponse struct {
@@ -62,6 +64,12 @@ type ConnectorStatus struct {
 	Type      string         `json:"type"`
 }
 
+const (
+	StateRunning = "RUNNING"
+	StatePaused  = "PAUSED"
+	StateStopped = "STOPPED"
+)
+
 type ConnectorState struct {
 	State    string `json:"state"`
 	WorkerId string `json:"worker_id"`
@@ -77,7 +85,7 @@ type TaskState struct {
 type UpdateConnectorConfigRequest struct {
 	ConnectorClass       string            `json:"connector.class"`
 	TasksMax             string            `json:"tasks.max"`
-	AdditionalParameters map[string]string `json:"-,omitempty"`
+	AdditionalParameters map[string]string `json:"-"`
 }
 
 type ErrorResponse struct {
@@ -89,3 +97,36 @@ type ListConnectorsResponse struct {
 	Names    []string
 	Statuses map[string]ConnectorStatus
 }
+
+type ConnectorOffsets struct {
+	Offsets []ConnectorOffset `json:"offsets"`
+}
+
+// ConnectorOffset is the source offset of one source partition. A nil
+// Offset removes the partition when altering offsets.
+type ConnectorOffset struct {
+	Partition map[string]any `json:"partition"`
+	Offset    map[string]any `json:"offset"`
+}
+
+// ServerInfo describes the worker that served the request.
+type ServerInfo struct {
+	Version        string `json:"version"`
+	Commit         string `json:"commit"`
+	KafkaClusterID string `json:"kafka_cluster_id"`
+}
+
+// LoggerLevel is the level of a logger. LastModified is the Unix time in
+// milliseconds of the last change through the REST API, or nil.
+type LoggerLevel struct {
+	Level        string `json:"level"`
+	LastModified *int64 `json:"last_modified,omitempty"`
+}
+
+const (
+	// LoggerScopeWorker changes the level on the worker that serves the request.
+	LoggerScopeWorker = "worker"
+	// LoggerScopeCluster changes the level on every worker. Requires Kafka
+	// Connect 3.7 or later.
+	LoggerScopeCluster = "cluster"
+)
@@ -0,0 +1,287 @@
+package debezium_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxRedirects = 5
+)
+
+var (
+	ErrNoWorkers = errors.New("no Kafka Connect workers configured")
+)
+
+type worker struct {
+	raw       string
+	base      *url.URL
+	parseErr  error
+	downUntil atomic.Int64
+}
+
+func (w *worker) down(now time.Time) bool {
+	return now.UnixNano() < w.downUntil.Load()
+}
+
+func (w *worker) markDown(cooldown time.Duration) {
+	w.downUntil.Store(time.Now().Add(cooldown).UnixNano())
+}
+
+func (w *worker) markUp() {
+	w.downUntil.Store(0)
+}
+
+// pool hands out the workers of a cluster in round-robin order.
+type pool struct {
+	cooldown time.Duration
+	workers  atomic.Pointer[[]*worker]
+	next     atomic.Uint64
+}
+
+func newPool(cooldown time.Duration) *pool {
+	p := &pool{cooldown: cooldown}
+	p.workers.Store(&[]*worker{})
+	return p
+}
+
+func (p *pool) set(baseURLs []string) {
+	workers := make([]*worker, 0, len(baseURLs))
+	for _, raw := range baseURLs {
+		raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err == nil && u.Path == "" {
+			// JoinPath drops the leading slash of an empty base path.
+			u.Path = "/"
+		}
+		workers = append(workers, &worker{raw: raw, base: u, parseErr: err})
+	}
+	p.workers.Store(&workers)
+}
+
+func (p *pool) all() []*worker {
+	return *p.workers.Load()
+}
+
+// known reports whether host is the host of one of the workers.
+func (p *pool) known(host string) bool {
+	for _, w := range p.all() {
+		if w.base != nil && w.base.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *pool) urls() []string {
+	workers := p.all()
+	out := make([]string, len(workers))
+	for i, w := range workers {
+		out[i] = w.raw
+	}
+	return out
+}
+
+// order returns the workers to try for one request: the healthy ones
+// starting at the next in rotation, then the ones in cooldown as a last
+// resort.
+func (p *pool) order() []*worker {
+	workers := p.all()
+	if len(workers) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	start := int(p.next.Add(1) % uint64(len(workers)))
+	up := make([]*worker, 0, len(workers))
+	var down []*worker
+	for i := range workers {
+		w := workers[(start+i)%len(workers)]
+		if w.down(now) {
+			down = append(down, w)
+		} else {
+			up = append(up, w)
+		}
+	}
+	return append(up, down...)
+}
+
+// do sends req, whose URL is a path relative to the worker base URL, to the
+// workers in turn until one responds. It fails over on connection errors,
+// and on any transport error for reads. A worker that fails is skipped for
+// the cooldown.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	workers := c.workers.order()
+	if len(workers) == 0 {
+		return nil, ErrNoWorkers
+	}
+
+	var errs []error
+	for i, w := range workers {
+		if i > 0 && req.Body != nil && req.GetBody == nil {
+			break
+		}
+
+		resp, err := c.send(req, w, i == 0)
+		if err == nil {
+			w.markUp()
+			return resp, nil
+		}
+		errs = append(errs, err)
+
+		if req.Context().Err() != nil || !canFailOver(req, err) {
+			break
+		}
+		w.markDown(c.workers.cooldown)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// send sends req to one worker and follows redirects, e.g. from a follower
+// to the leader, keeping the method and body. Once a redirect leaves the
+// cluster's workers, credentials are no longer sent.
+func (c *Client) send(req *http.Request, w *worker, first bool) (*http.Response, error) {
+	if w.parseErr != nil {
+		return nil, fmt.Errorf("worker %s: %w", w.raw, w.parseErr)
+	}
+
+	target := w.base.JoinPath(req.URL.EscapedPath())
+	target.RawQuery = req.URL.RawQuery
+
+	foreign := false
+	for hops := 0; ; hops++ {
+		ctx := req.Context()
+		if foreign {
+			ctx = context.WithValue(ctx, foreignHostKey{}, true)
+		}
+		r := req.Clone(ctx)
+		r.URL = target
+		r.Host = ""
+		if foreign {
+			stripCredentials(r.Header)
+		}
+		if req.GetBody != nil && (!first || hops > 0) {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("worker %s: %w", w.raw, err)
+			}
+			r.Body = body
+		}
+
+		resp, err := c.cc.Do(r)
+		if err != nil {
+			return nil, err
+		}
+
+		location := resp.Header.Get("Location")
+		if !isRedirect(resp.StatusCode) || location == "" {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		if hops == maxRedirects {
+			return nil, fmt.Errorf("worker %s: stopped after %d redirects", w.raw, maxRedirects)
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return nil, fmt.Errorf("worker %s: cannot resend the body to %s", w.raw, location)
+		}
+		if target, err = target.Parse(location); err != nil {
+			return nil, fmt.Errorf("worker %s: redirect: %w", w.raw, err)
+		}
+		foreign = foreign || !c.workers.known(target.Host)
+	}
+}
+
+// foreignHostKey marks a request redirected away from the cluster's workers.
+type foreignHostKey struct{}
+
+func toForeignHost(ctx context.Context) bool {
+	foreign, _ := ctx.Value(foreignHostKey{}).(bool)
+	return foreign
+}
+
+// stripCredentials removes the headers net/http also drops on a redirect to
+// another host.
+func stripCredentials(h http.Header) {
+	for _, k := range []string{"Authorization", "Www-Authenticate", "Cookie", "Cookie2"} {
+		h.Del(k)
+	}
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// canFailOver reports whether req may be retried on another worker. Writes
+// are only retried if the connection was never made, so they are not
+// applied twice.
+func canFailOver(req *http.Request, err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	return req.Method == http.MethodGet || req.Method == http.MethodHead
+}
+
+// healthCheck probes the root of every worker until Close is called.
+func (c *Client) healthCheck(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+
+		for _, w := range c.workers.all() {
+			if c.probe(w, interval) {
+				w.markUp()
+			} else {
+				w.markDown(c.workers.cooldown)
+			}
+		}
+	}
+}
+
+func (c *Client) probe(w *worker, timeout time.Duration) bool {
+	if w.parseErr != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.base.JoinPath("/").String(), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := c.cc.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode < http.StatusInternalServerError
+}
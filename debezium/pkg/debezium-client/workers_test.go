@@ -0,0 +1,290 @@
+package debezium_client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// hits records which test server handled each request.
+type hits struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (h *hits) add(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seen = append(h.seen, name)
+}
+
+func (h *hits) take() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	seen := h.seen
+	h.seen = nil
+	return seen
+}
+
+// connectorServer serves GET /connectors/{name} and PUT
+// /connectors/{name}/pause and records every request under name.
+func connectorServer(t *testing.T, name string, h *hits) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.add(name)
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Write([]byte(`{"name": "inventory", "config": {}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// droppingServer accepts the connection, records the request and closes the
+// connection without a response, so the client gets a transport error after
+// the request was sent.
+func droppingServer(t *testing.T, name string, h *hits) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.add(name)
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Hijack: %v", err)
+			return
+		}
+		conn.Close()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// deadURL returns the URL of a server that no longer listens.
+func deadURL(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func TestFailoverOrder(t *testing.T) {
+	ctx := context.Background()
+	h := &hits{}
+	b := connectorServer(t, "b", h)
+	c := connectorServer(t, "c", h)
+	client := New([]string{deadURL(t), b.URL, c.URL}, time.Second)
+	t.Cleanup(client.Close)
+
+	for i := 0; i < 6; i++ {
+		if _, err := client.GetConnector(ctx, "inventory"); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+
+	// Requests rotate over the workers. The third starts at the dead worker,
+	// fails over to b and puts the dead worker in cooldown, so the rotation
+	// goes on over b and c only.
+	want := []string{"b", "c", "b", "b", "c", "b"}
+	if got := h.take(); !slices.Equal(got, want) {
+		t.Errorf("requests went to %v, want %v", got, want)
+	}
+}
+
+func TestFailoverOfWrites(t *testing.T) {
+	ctx := context.Background()
+	h := &hits{}
+	a := connectorServer(t, "a", h)
+	dropping := droppingServer(t, "dropping", h)
+
+	tests := []struct {
+		name    string
+		workers []string
+		send    func(c *Client) error
+		want    []string
+		ok      bool
+	}{
+		{
+			name:    "read after a dropped connection",
+			workers: []string{a.URL, dropping.URL},
+			send: func(c *Client) error {
+				_, err := c.GetConnector(ctx, "inventory")
+				return err
+			},
+			want: []string{"dropping", "a"},
+			ok:   true,
+		},
+		{
+			name:    "write to a worker that is down",
+			workers: []string{a.URL, deadURL(t)},
+			send:    func(c *Client) error { return c.PauseConnector(ctx, "inventory") },
+			want:    []string{"a"},
+			ok:      true,
+		},
+		{
+			// The write may have been applied, so it is not sent again.
+			name:    "write after a dropped connection",
+			workers: []string{a.URL, dropping.URL},
+			send:    func(c *Client) error { return c.PauseConnector(ctx, "inventory") },
+			want:    []string{"dropping"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The first request of a client starts at the second worker.
+			client := New(tt.workers, time.Second)
+			t.Cleanup(client.Close)
+
+			err := tt.send(client)
+			if tt.ok != (err == nil) {
+				t.Errorf("got error %v, want ok=%t", err, tt.ok)
+			}
+			if got := h.take(); !slices.Equal(got, tt.want) {
+				t.Errorf("requests went to %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCooldownExpiry(t *testing.T) {
+	const cooldown = 300 * time.Millisecond
+	ctx := context.Background()
+	h := &hits{}
+	dropping := droppingServer(t, "dropping", h)
+	b := connectorServer(t, "b", h)
+	client := New([]string{dropping.URL, b.URL}, time.Second, WithCooldown(cooldown))
+	t.Cleanup(client.Close)
+
+	count := func(seen []string) int {
+		n := 0
+		for _, name := range seen {
+			if name == "dropping" {
+				n++
+			}
+		}
+		return n
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := client.GetConnector(ctx, "inventory"); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if n := count(h.take()); n != 1 {
+		t.Errorf("the failing worker got %d requests during its cooldown, want 1", n)
+	}
+
+	time.Sleep(cooldown)
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetConnector(ctx, "inventory"); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if n := count(h.take()); n != 1 {
+		t.Errorf("the failing worker got %d requests after its cooldown, want 1", n)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	// The cooldown outlasts the test, so only the probes mark the worker up.
+	client := New([]string{srv.URL}, time.Second, WithCooldown(time.Hour), WithHealthCheck(10*time.Millisecond))
+	t.Cleanup(client.Close)
+	w := client.workers.all()[0]
+
+	waitFor := func(down bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for w.down(time.Now()) != down {
+			if time.Now().After(deadline) {
+				t.Fatalf("the worker is not marked down=%t", down)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	waitFor(true)
+	healthy.Store(true)
+	waitFor(false)
+}
+
+func TestRedirectCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	type seen struct {
+		auth string
+		body string
+	}
+	record := func(got *seen) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			*got = seen{auth: r.Header.Get("Authorization"), body: string(body)}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"name": "inventory", "config": {}}`))
+		}
+	}
+	redirect := func(to *string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, *to+r.URL.Path, http.StatusTemporaryRedirect)
+		}
+	}
+
+	var leaderSeen, foreignSeen seen
+	leader := httptest.NewServer(record(&leaderSeen))
+	t.Cleanup(leader.Close)
+	foreign := httptest.NewServer(record(&foreignSeen))
+	t.Cleanup(foreign.Close)
+	var backTo string
+	bouncer := httptest.NewServer(redirect(&backTo))
+	t.Cleanup(bouncer.Close)
+
+	tests := []struct {
+		name   string
+		to     string
+		target *seen
+		auth   string
+	}{
+		{name: "to the leader", to: leader.URL, target: &leaderSeen, auth: "Bearer s3cret"},
+		{name: "to another host", to: foreign.URL, target: &foreignSeen},
+		{name: "back to the cluster via another host", to: bouncer.URL, target: &leaderSeen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaderSeen, foreignSeen = seen{}, seen{}
+			backTo = leader.URL
+			to := tt.to
+			follower := httptest.NewServer(redirect(&to))
+			t.Cleanup(follower.Close)
+
+			// The follower is the second worker, so it gets the first request.
+			client := New([]string{leader.URL, follower.URL}, time.Second, WithBearerToken("s3cret"))
+			t.Cleanup(client.Close)
+
+			_, err := client.CreateConnector(ctx, CreateConnectorRequest{Name: "inventory"})
+			if err != nil {
+				t.Fatalf("CreateConnector: %v", err)
+			}
+			if tt.target.auth != tt.auth {
+				t.Errorf("the target got Authorization %q, want %q", tt.target.auth, tt.auth)
+			}
+			if tt.target.body == "" {
+				t.Error("the body was not sent again after the redirect")
+			}
+		})
+	}
+}
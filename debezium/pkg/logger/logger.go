@@ -2,8 +2,12 @@ package logger
 
 import (
 	"context"
+	"debezium_server/pkg/redact"
+	"debezium_server/pkg/tracing"
+	"fmt"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 const (
@@ -11,65 +15,196 @@ const (
 	loggerTraceIDKey   = "x-trace-id"
 )
 
+type ctxKey int
+
+const (
+	requestIDCtxKey ctxKey = iota
+	traceIDCtxKey
+)
+
 type Logger interface {
 	Info(ctx context.Context, msg string, fields ...zap.Field)
+	Warn(ctx context.Context, msg string, fields ...zap.Field)
 	Error(ctx context.Context, msg string, fields ...zap.Field)
 	Debug(ctx context.Context, msg string, fields ...zap.Field)
+
+	// With returns a child logger that adds fields to every entry. The
+	// child shares the level of its parent.
+	With(fields ...zap.Field) Logger
+
+	Level() zapcore.Level
+	SetLevel(level zapcore.Level)
+
+	// Sync flushes buffered entries. Call it before the process exits.
+	Sync() error
+}
+
+type Config struct {
+	// Level overrides the level derived from the environment name.
+	Level string `yaml:"level" env:"LOG_LEVEL"`
 }
 
 type L struct {
-	z *zap.Logger
+	z     *zap.Logger
+	level zap.AtomicLevel
 }
 
-func NewLogger(env string) Logger {
-	loggerCfg := zap.NewProductionConfig()
-	loggerCfg.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
+type Option func(*options)
+
+type options struct {
+	redactor *redact.Redactor
+	level    string
+}
+
+// WithRedactor masks secret fields and secret keys of logged config maps.
+func WithRedactor(r *redact.Redactor) Option {
+	return func(o *options) {
+		o.redactor = r
+	}
+}
 
-	if env == "dev" {
-		loggerCfg.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+// WithLevel sets the initial level, e.g. "debug" or "warn". An empty level
+// keeps the default for the environment.
+func WithLevel(level string) Option {
+	return func(o *options) {
+		o.level = level
 	}
+}
 
-	logger, err := loggerCfg.Build()
+// NewLogger builds a JSON logger. Development environments log at debug
+// level and all others at info, unless WithLevel says otherwise.
+func NewLogger(env string, opts ...Option) (Logger, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	lvl, err := ParseLevel(env, o.level)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("NewLogger: %w", err)
+	}
+	level := zap.NewAtomicLevelAt(lvl)
+
+	loggerCfg := zap.NewProductionConfig()
+	loggerCfg.Level = level
+
+	// Skip the L wrapper so entries report the caller of Info, Error, etc.
+	zapOpts := []zap.Option{zap.AddCallerSkip(1)}
+	if o.redactor != nil {
+		zapOpts = append(zapOpts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return newRedactingCore(core, o.redactor)
+		}))
+	}
+
+	logger, err := loggerCfg.Build(zapOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("NewLogger.Build: %w", err)
+	}
+
+	return &L{
+		z:     logger,
+		level: level,
+	}, nil
+}
+
+// ParseLevel returns level if it is set and otherwise the default for env:
+// debug for development environments and info for all others.
+func ParseLevel(env, level string) (zapcore.Level, error) {
+	if level != "" {
+		lvl, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return lvl, fmt.Errorf("invalid level %q: %w", level, err)
+		}
+		return lvl, nil
 	}
-	defer logger.Sync()
 
-	lo := L{
-		logger,
+	if isDevelopment(env) {
+		return zapcore.DebugLevel, nil
 	}
+	return zapcore.InfoLevel, nil
+}
 
-	return &lo
+func isDevelopment(env string) bool {
+	switch env {
+	case "dev", "development", "local":
+		return true
+	default:
+		return false
+	}
 }
 
 func WithRequestID(ctx context.Context, requestID string) context.Context {
-	return context.WithValue(ctx, loggerRequestIDKey, requestID)
+	return context.WithValue(ctx, requestIDCtxKey, requestID)
+}
+
+// TraceIDFromContext returns the trace ID of the active span, falling back
+// to the one stored by WithTraceID.
+func TraceIDFromContext(ctx context.Context) string {
+	if id := tracing.TraceID(ctx); id != "" {
+		return id
+	}
+	id, _ := ctx.Value(traceIDCtxKey).(string)
+	return id
+}
+
+// RequestIDFromContext returns the ID stored by WithRequestID, or "" if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey).(string)
+	return id
 }
 
 func WithTraceID(ctx context.Context, traceID string) context.Context {
-	return context.WithValue(ctx, loggerTraceIDKey, traceID)
+	return context.WithValue(ctx, traceIDCtxKey, traceID)
 }
 
 func (l *L) Info(ctx context.Context, msg string, fields ...zap.Field) {
-	id := ctx.Value(loggerRequestIDKey).(string)
-
-	fields = append(fields, zap.String(loggerRequestIDKey, id))
+	l.z.Info(msg, append(fields, contextFields(ctx)...)...)
+}
 
-	l.z.Info(msg, fields...)
+func (l *L) Warn(ctx context.Context, msg string, fields ...zap.Field) {
+	l.z.Warn(msg, append(fields, contextFields(ctx)...)...)
 }
 
 func (l *L) Error(ctx context.Context, msg string, fields ...zap.Field) {
-	id := ctx.Value(loggerRequestIDKey).(string)
+	l.z.Error(msg, append(fields, contextFields(ctx)...)...)
+}
 
-	fields = append(fields, zap.String(loggerRequestIDKey, id))
+func (l *L) Debug(ctx context.Context, msg string, fields ...zap.Field) {
+	l.z.Debug(msg, append(fields, contextFields(ctx)...)...)
+}
 
-	l.z.Error(msg, fields...)
+func (l *L) With(fields ...zap.Field) Logger {
+	return &L{
+		z:     l.z.With(fields...),
+		level: l.level,
+	}
 }
 
-func (l *L) Debug(ctx context.Context, msg string, fields ...zap.Field) {
-	id := ctx.Value(loggerRequestIDKey).(string)
+func (l *L) Level() zapcore.Level {
+	return l.level.Level()
+}
 
-	fields = append(fields, zap.String(loggerRequestIDKey, id))
+func (l *L) SetLevel(level zapcore.Level) {
+	l.level.SetLevel(level)
+}
 
-	l.z.Debug(msg, fields...)
+func (l *L) Sync() error {
+	return l.z.Sync()
+}
+
+// contextFields returns the request and trace IDs found in ctx. Either may
+// be missing, e.g. for background work outside a request.
+func contextFields(ctx context.Context) []zap.Field {
+	if ctx == nil {
+		return nil
+	}
+
+	var fields []zap.Field
+	if id := RequestIDFromContext(ctx); id != "" {
+		fields = append(fields, zap.String(loggerRequestIDKey, id))
+	}
+	if id := TraceIDFromContext(ctx); id != "" {
+		fields = append(fields, zap.String(loggerTraceIDKey, id))
+	}
+	return fields
 }
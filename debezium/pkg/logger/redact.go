@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"debezium_server/pkg/redact"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactingCore masks secret fields before they reach the wrapped core.
+type redactingCore struct {
+	zapcore.Core
+	r *redact.Redactor
+}
+
+func newRedactingCore(core zapcore.Core, r *redact.Redactor) zapcore.Core {
+	return &redactingCore{Core: core, r: r}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redact(fields)), r: c.r}
+}
+
+func (c *redactingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.redact(fields))
+}
+
+func (c *redactingCore) redact(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		switch {
+		case c.r.IsSecret(f.Key):
+			out[i] = zap.String(f.Key, c.r.Mask())
+		case f.Type == zapcore.ReflectType:
+			out[i] = c.redactReflected(f)
+		default:
+			out[i] = f
+		}
+	}
+	return out
+}
+
+func (c *redactingCore) redactReflected(f zapcore.Field) zapcore.Field {
+	switch v := f.Interface.(type) {
+	case map[string]any:
+		return zap.Any(f.Key, c.r.Map(v))
+	case map[string]string:
+		return zap.Any(f.Key, c.r.StringMap(v))
+	default:
+		return f
+	}
+}
@@ -0,0 +1,317 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	versionsTable = "schema_migrations"
+
+	// advisoryLockID is an arbitrary constant shared by every migrate process.
+	advisoryLockID int64 = 7439125061
+
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
+var (
+	ErrDirty            = errors.New("database is dirty, fix the failed migration and run force")
+	ErrNoChange         = errors.New("no change")
+	ErrInvalidFileName  = errors.New("invalid migration file name")
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+	ErrUnknownVersion   = errors.New("unknown migration version")
+	ErrMissingDown      = errors.New("missing down migration")
+)
+
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+func New(db *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies every pending migration in version order.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w: version %d", ErrDirty, current)
+		}
+
+		applied := 0
+		for _, mg := range m.migrations {
+			if mg.Version <= current {
+				continue
+			}
+			if err := apply(ctx, conn, mg.Version, mg.Version, mg.Up); err != nil {
+				return fmt.Errorf("up %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			applied++
+		}
+
+		if applied == 0 {
+			return ErrNoChange
+		}
+		return nil
+	})
+}
+
+// Down rolls back the given number of applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w: version %d", ErrDirty, current)
+		}
+
+		rolledBack := 0
+		for i := len(m.migrations) - 1; i >= 0 && rolledBack < steps; i-- {
+			mg := m.migrations[i]
+			if mg.Version > current {
+				continue
+			}
+			if mg.Down == "" {
+				return fmt.Errorf("%w: %d_%s", ErrMissingDown, mg.Version, mg.Name)
+			}
+
+			var previous uint64
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			if err := apply(ctx, conn, mg.Version, previous, mg.Down); err != nil {
+				return fmt.Errorf("down %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			current = previous
+			rolledBack++
+		}
+
+		if rolledBack == 0 {
+			return ErrNoChange
+		}
+		return nil
+	})
+}
+
+// Version returns the current schema version and whether the last migration failed midway.
+func (m *Migrator) Version(ctx context.Context) (uint64, bool, error) {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("acquire: %w", err)
+	}
+	defer conn.Release()
+
+	if err := ensureVersionsTable(ctx, conn); err != nil {
+		return 0, false, err
+	}
+
+	return readVersion(ctx, conn)
+}
+
+// Force sets the schema version and clears the dirty flag without running any migration.
+func (m *Migrator) Force(ctx context.Context, version uint64) error {
+	if version != 0 && !m.known(version) {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		return writeVersion(ctx, conn, version, false)
+	})
+}
+
+func (m *Migrator) known(version uint64) bool {
+	for _, mg := range m.migrations {
+		if mg.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// withLock serializes concurrent runs with a session advisory lock held on a
+// dedicated connection for the duration of fn.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID); err != nil {
+		return fmt.Errorf("lock: %w", err)
+	}
+	defer func() {
+		_, _ = conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", advisoryLockID)
+	}()
+
+	if err := ensureVersionsTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func apply(ctx context.Context, conn *pgxpool.Conn, running, target uint64, sql string) error {
+	if err := writeVersion(ctx, conn, running, true); err != nil {
+		return err
+	}
+
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, sql)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeVersion(ctx, conn, target, false)
+}
+
+func ensureVersionsTable(ctx context.Context, conn *pgxpool.Conn) error {
+	query := "CREATE TABLE IF NOT EXISTS " + versionsTable +
+		" (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)"
+	if _, err := conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("create versions table: %w", err)
+	}
+	return nil
+}
+
+func readVersion(ctx context.Context, conn *pgxpool.Conn) (uint64, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+
+	err := conn.QueryRow(ctx, "SELECT version, dirty FROM "+versionsTable+" LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("read version: %w", err)
+	}
+
+	return uint64(version), dirty, nil
+}
+
+func writeVersion(ctx context.Context, conn *pgxpool.Conn, version uint64, dirty bool) error {
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DELETE FROM "+versionsTable); err != nil {
+			return err
+		}
+		if version == 0 && !dirty {
+			return nil
+		}
+		_, err := tx.Exec(ctx, "INSERT INTO "+versionsTable+" (version, dirty) VALUES ($1, $2)",
+			int64(version), dirty)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("write version: %w", err)
+	}
+	return nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("load: %w", err)
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, file := range files {
+		base := path.Base(file)
+
+		var (
+			name string
+			up   bool
+		)
+		switch {
+		case strings.HasSuffix(base, upSuffix):
+			name, up = strings.TrimSuffix(base, upSuffix), true
+		case strings.HasSuffix(base, downSuffix):
+			name = strings.TrimSuffix(base, downSuffix)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFileName, base)
+		}
+
+		prefix, title, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFileName, base)
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidFileName, base)
+		}
+
+		body, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", base, err)
+		}
+
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: title}
+			byVersion[version] = mg
+		}
+		if mg.Name != title {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+		}
+
+		if up {
+			if mg.Up != "" {
+				return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+			}
+			mg.Up = string(body)
+		} else {
+			if mg.Down != "" {
+				return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+			}
+			mg.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.Up == "" {
+			return nil, fmt.Errorf("%w: missing up migration for %d", ErrInvalidFileName, mg.Version)
+		}
+		migrations = append(migrations, *mg)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
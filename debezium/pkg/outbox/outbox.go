@@ -0,0 +1,133 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	DefaultTable = "outbox"
+)
+
+var (
+	ErrEmptyAggregateType = errors.New("aggregate type cannot be empty")
+	ErrEmptyAggregateID   = errors.New("aggregate id cannot be empty")
+	ErrEmptyEventType     = errors.New("event type cannot be empty")
+)
+
+// Event is a domain event stored in the outbox table and routed by
+// Debezium's EventRouter to a topic per aggregate type.
+type Event struct {
+	ID            uuid.UUID
+	AggregateType string
+	AggregateID   string
+	Type          string
+	Payload       json.RawMessage
+	CreatedAt     time.Time
+}
+
+// TxBeginner is satisfied by *pgxpool.Pool, *pgx.Conn and pgx.Tx.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type Store struct {
+	table   string
+	builder squirrel.StatementBuilderType
+}
+
+func NewEvent(aggregateType, aggregateID, eventType string, payload any) (Event, error) {
+	if aggregateType == "" {
+		return Event{}, ErrEmptyAggregateType
+	}
+	if aggregateID == "" {
+		return Event{}, ErrEmptyAggregateID
+	}
+	if eventType == "" {
+		return Event{}, ErrEmptyEventType
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("NewEvent.Marshal: %w", err)
+	}
+
+	return Event{
+		ID:            uuid.New(),
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Type:          eventType,
+		Payload:       data,
+		CreatedAt:     time.Now().UTC(),
+	}, nil
+}
+
+func NewStore(table string) *Store {
+	if table == "" {
+		table = DefaultTable
+	}
+
+	return &Store{
+		table:   table,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// Save inserts the events using the caller's transaction so they are
+// committed or rolled back together with the business change.
+func (s *Store) Save(ctx context.Context, tx pgx.Tx, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	insert := s.builder.Insert(s.table).
+		Columns("id", "aggregate_type", "aggregate_id", "type", "payload", "created_at")
+	for _, e := range events {
+		insert = insert.Values(e.ID, e.AggregateType, e.AggregateID, e.Type, e.Payload, e.CreatedAt)
+	}
+
+	query, args, err := insert.ToSql()
+	if err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+
+	return nil
+}
+
+// InTx runs fn in a new transaction and stores the events it returns before
+// committing. Any error rolls back both the business change and the events.
+func (s *Store) InTx(ctx context.Context, db TxBeginner, fn func(ctx context.Context, tx pgx.Tx) ([]Event, error)) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	events, err := fn(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Save(ctx, tx, events...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
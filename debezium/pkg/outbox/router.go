@@ -0,0 +1,91 @@
+package outbox
+
+const (
+	eventRouterClass = "io.debezium.transforms.outbox.EventRouter"
+
+	defaultTransformName  = "outbox"
+	defaultSchema         = "public"
+	defaultTopicTemplate  = "outbox.event.${routedByValue}"
+	defaultEventTypeField = "type:header:eventType"
+)
+
+// RouterConfig describes how the EventRouter SMT maps outbox rows to topics.
+type RouterConfig struct {
+	// TransformName is the alias used in the connector "transforms" list.
+	TransformName string
+	Schema        string
+	Table         string
+	// TopicTemplate is the route.topic.replacement pattern, where
+	// ${routedByValue} is replaced with the aggregate type.
+	TopicTemplate string
+	// ExpandJSONPayload makes the router emit the payload as a JSON object
+	// instead of a string.
+	ExpandJSONPayload bool
+}
+
+func (c RouterConfig) withDefaults() RouterConfig {
+	if c.TransformName == "" {
+		c.TransformName = defaultTransformName
+	}
+	if c.Schema == "" {
+		c.Schema = defaultSchema
+	}
+	if c.Table == "" {
+		c.Table = DefaultTable
+	}
+	if c.TopicTemplate == "" {
+		c.TopicTemplate = defaultTopicTemplate
+	}
+	return c
+}
+
+// ConnectorConfig returns the connector properties that capture the outbox
+// table and route each row to a topic named after its aggregate type.
+func (c RouterConfig) ConnectorConfig() map[string]string {
+	c = c.withDefaults()
+	prefix := "transforms." + c.TransformName + "."
+
+	expand := "false"
+	if c.ExpandJSONPayload {
+		expand = "true"
+	}
+
+	return map[string]string{
+		"table.include.list":                         c.Schema + "." + c.Table,
+		"tombstones.on.delete":                       "false",
+		"transforms":                                 c.TransformName,
+		prefix + "type":                              eventRouterClass,
+		prefix + "table.field.event.id":              "id",
+		prefix + "table.field.event.key":             "aggregate_id",
+		prefix + "table.field.event.payload":         "payload",
+		prefix + "table.fields.additional.placement": defaultEventTypeField,
+		prefix + "route.by.field":                    "aggregate_type",
+		prefix + "route.topic.replacement":           c.TopicTemplate,
+		prefix + "table.expand.json.payload":         expand,
+		prefix + "table.op.invalid.behavior":         "warn",
+		prefix + "route.tombstone.on.empty.payload":  "false",
+	}
+}
+
+// Apply merges the router properties into an existing connector config.
+// The outbox transform is appended to any transforms already configured and
+// the table include list is replaced, since the router only understands
+// outbox rows.
+func (c RouterConfig) Apply(config map[string]string) map[string]string {
+	c = c.withDefaults()
+
+	merged := make(map[string]string, len(config))
+	for k, v := range config {
+		merged[k] = v
+	}
+
+	routerCfg := c.ConnectorConfig()
+	if existing := merged["transforms"]; existing != "" {
+		routerCfg["transforms"] = existing + "," + c.TransformName
+	}
+	for k, v := range routerCfg {
+		merged[k] = v
+	}
+
+	return merged
+}
@@ -2,18 +2,62 @@ package postgres
 
 import (
 	"context"
-	"fmt"
+	"net"
+	"net/url"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq"
 )
 
+// Config is the database connection. SSLMode takes the libpq values
+// (disable, allow, prefer, require, verify-ca, verify-full); SSLRootCert,
+// SSLCert and SSLKey are file paths. Zero pool settings keep the pgxpool
+// defaults.
 type Config struct {
-	Username string `env:"POSTGRES_USER" env-default:"postgres"`
-	Password string `env:"POSTGRES_PASSWORD" env-default:"postgres"`
-	Host     string `env:"POSTGRES_HOST" env-default:"db"`
-	Port     string `env:"POSTGRES_PORT" env-default:"5432"`
-	DbName   string `env:"POSTGRES_DB" env-default:"postgres"`
+	Username string `yaml:"username" env:"POSTGRES_USER" env-default:"postgres"`
+	Password string `yaml:"password" env:"POSTGRES_PASSWORD" env-default:"postgres"`
+	Host     string `yaml:"host"     env:"POSTGRES_HOST" env-default:"db"`
+	Port     string `yaml:"port"     env:"POSTGRES_PORT" env-default:"5432"`
+	DbName   string `yaml:"db_name"  env:"POSTGRES_DB" env-default:"postgres"`
+
+	SSLMode     string `yaml:"sslmode"     env:"POSTGRES_SSLMODE" env-default:"disable"`
+	SSLRootCert string `yaml:"sslrootcert" env:"POSTGRES_SSLROOTCERT"`
+	SSLCert     string `yaml:"sslcert"     env:"POSTGRES_SSLCERT"`
+	SSLKey      string `yaml:"sslkey"      env:"POSTGRES_SSLKEY"`
+
+	MaxConns          int32         `yaml:"max_conns"           env:"POSTGRES_MAX_CONNS"`
+	MinConns          int32         `yaml:"min_conns"           env:"POSTGRES_MIN_CONNS"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime"   env:"POSTGRES_MAX_CONN_LIFETIME"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time"  env:"POSTGRES_MAX_CONN_IDLE_TIME"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period" env:"POSTGRES_HEALTH_CHECK_PERIOD"`
+}
+
+// SSLModes are the accepted values of Config.SSLMode.
+var SSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// DSN returns the connection URL, with the user and password escaped.
+func (c Config) DSN() string {
+	q := url.Values{}
+	q.Set("sslmode", c.SSLMode)
+	if c.SSLRootCert != "" {
+		q.Set("sslrootcert", c.SSLRootCert)
+	}
+	if c.SSLCert != "" {
+		q.Set("sslcert", c.SSLCert)
+	}
+	if c.SSLKey != "" {
+		q.Set("sslkey", c.SSLKey)
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.Username, c.Password),
+		Host:     net.JoinHostPort(c.Host, c.Port),
+		Path:     "/" + c.DbName,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
 }
 
 type Database struct {
@@ -21,10 +65,28 @@ type Database struct {
 }
 
 func New(config Config) (*Database, error) {
-	dataSource := fmt.Sprintf("postgres://%v:%v@%v:%v/%v?sslmode=disable",
-		config.Username, config.Password, config.Host, config.Port, config.DbName)
+	poolConfig, err := pgxpool.ParseConfig(config.DSN())
+	if err != nil {
+		return nil, err
+	}
+	poolConfig.ConnConfig.Tracer = queryTracer{}
+	if config.MaxConns > 0 {
+		poolConfig.MaxConns = config.MaxConns
+	}
+	if config.MinConns > 0 {
+		poolConfig.MinConns = config.MinConns
+	}
+	if config.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = config.MaxConnLifetime
+	}
+	if config.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.MaxConnIdleTime
+	}
+	if config.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = config.HealthCheckPeriod
+	}
 
-	pool, err := pgxpool.New(context.Background(), dataSource)
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, err
 	}
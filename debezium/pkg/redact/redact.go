@@ -0,0 +1,144 @@
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	DefaultMask = "********"
+)
+
+var (
+	ErrEmptyMask = errors.New("redaction mask cannot be empty")
+)
+
+type Config struct {
+	KeyPatterns []string `yaml:"key_patterns" env:"REDACT_KEY_PATTERNS" env-separator:"," env-default:"*password*,*secret*,*token*,*jaas.config,*credentials*,*api.key*,*private.key*"`
+	Mask        string   `yaml:"mask"         env:"REDACT_MASK"         env-default:"********"`
+}
+
+// Redactor masks the values of config keys that match any of its glob
+// patterns. Matching is case-insensitive.
+type Redactor struct {
+	patterns []string
+	mask     string
+}
+
+func New(cfg Config) (*Redactor, error) {
+	if cfg.Mask == "" {
+		return nil, ErrEmptyMask
+	}
+
+	patterns := make([]string, 0, len(cfg.KeyPatterns))
+	for _, p := range cfg.KeyPatterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid key pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, p)
+	}
+
+	return &Redactor{
+		patterns: patterns,
+		mask:     cfg.Mask,
+	}, nil
+}
+
+func (r *Redactor) Mask() string {
+	return r.mask
+}
+
+func (r *Redactor) IsSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range r.patterns {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns the mask for secret keys and the value unchanged otherwise.
+func (r *Redactor) Value(key, value string) string {
+	if value != "" && r.IsSecret(key) {
+		return r.mask
+	}
+	return value
+}
+
+// Map returns a copy of a connector config with secret values masked,
+// including those of objects nested in the values.
+func (r *Redactor) Map(config map[string]any) map[string]any {
+	if config == nil {
+		return nil
+	}
+
+	out := make(map[string]any, len(config))
+	for k, v := range config {
+		if v != nil && v != "" && r.IsSecret(k) {
+			out[k] = r.mask
+			continue
+		}
+		out[k] = r.nested(v)
+	}
+	return out
+}
+
+// nested masks the secrets of the objects within a value.
+func (r *Redactor) nested(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return r.Map(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = r.nested(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// StringMap is Map for configs with string values.
+func (r *Redactor) StringMap(config map[string]string) map[string]string {
+	if config == nil {
+		return nil
+	}
+
+	out := make(map[string]string, len(config))
+	for k, v := range config {
+		out[k] = r.Value(k, v)
+	}
+	return out
+}
+
+// Restore replaces masked secret values in an incoming config with the
+// values currently stored, so a config read from the API can be sent back
+// unchanged. It returns the keys that are masked but have no stored value.
+func (r *Redactor) Restore(incoming map[string]string, stored map[string]any) (map[string]string, []string) {
+	out := make(map[string]string, len(incoming))
+	var missing []string
+
+	for k, v := range incoming {
+		if v != r.mask || !r.IsSecret(k) {
+			out[k] = v
+			continue
+		}
+
+		prev, ok := stored[k]
+		if !ok || prev == nil {
+			missing = append(missing, k)
+			continue
+		}
+		out[k] = fmt.Sprint(prev)
+	}
+
+	return out, missing
+}
@@ -0,0 +1,168 @@
+package redact
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// defaultPatterns are the env-default of Config.KeyPatterns.
+var defaultPatterns = []string{
+	"*password*", "*secret*", "*token*", "*jaas.config", "*credentials*", "*api.key*", "*private.key*",
+}
+
+func newRedactor(t *testing.T) *Redactor {
+	t.Helper()
+	r, err := New(Config{KeyPatterns: defaultPatterns, Mask: DefaultMask})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return r
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		ok   bool
+	}{
+		{name: "defaults", cfg: Config{KeyPatterns: defaultPatterns, Mask: DefaultMask}, ok: true},
+		{name: "blank patterns", cfg: Config{KeyPatterns: []string{" ", ""}, Mask: "x"}, ok: true},
+		{name: "empty mask", cfg: Config{KeyPatterns: defaultPatterns}},
+		{name: "invalid pattern", cfg: Config{KeyPatterns: []string{"*password[*"}, Mask: "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); tt.ok != (err == nil) {
+				t.Errorf("New returned %v, want ok=%t", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIsSecret(t *testing.T) {
+	r := newRedactor(t)
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "database.password", want: true},
+		{key: "Database.PASSWORD", want: true},
+		{key: "database.history.kafka.sasl.jaas.config", want: true},
+		{key: "producer.override.sasl.jaas.config", want: true},
+		{key: "schema.history.internal.consumer.ssl.keystore.password", want: true},
+		{key: "aws.secret.access.key", want: true},
+		{key: "ssl.private.key", want: true},
+		{key: "gcp.credentials.json", want: true},
+		{key: "sasl.jaas.config.extra"},
+		{key: "database.user"},
+		{key: "database.hostname"},
+		{key: "topic.prefix"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := r.IsSecret(tt.key); got != tt.want {
+				t.Errorf("IsSecret(%q) = %t, want %t", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMap(t *testing.T) {
+	r := newRedactor(t)
+	config := map[string]any{
+		"database.user":     "postgres",
+		"database.password": "s3cret",
+		"empty.password":    "",
+		"null.token":        nil,
+		"tasks.max":         float64(1),
+		"transforms.outbox": map[string]any{
+			"type":       "io.debezium.transforms.outbox.EventRouter",
+			"sasl.token": "t0ken",
+			"brokers":    []any{map[string]any{"host": "kafka", "password": "p4ss"}, "kafka-2"},
+		},
+		"credentials": map[string]any{"user": "svc"},
+	}
+
+	want := map[string]any{
+		"database.user":     "postgres",
+		"database.password": DefaultMask,
+		"empty.password":    "",
+		"null.token":        nil,
+		"tasks.max":         float64(1),
+		"transforms.outbox": map[string]any{
+			"type":       "io.debezium.transforms.outbox.EventRouter",
+			"sasl.token": DefaultMask,
+			"brokers":    []any{map[string]any{"host": "kafka", "password": DefaultMask}, "kafka-2"},
+		},
+		"credentials": DefaultMask,
+	}
+	if got := r.Map(config); !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+	if config["database.password"] != "s3cret" || config["transforms.outbox"].(map[string]any)["sasl.token"] != "t0ken" {
+		t.Error("Map changed its input")
+	}
+	if r.Map(nil) != nil {
+		t.Error("Map(nil) is not nil")
+	}
+}
+
+func TestStringMap(t *testing.T) {
+	r := newRedactor(t)
+	got := r.StringMap(map[string]string{"database.password": "s3cret", "database.user": "postgres", "api.key.empty": ""})
+	want := map[string]string{"database.password": DefaultMask, "database.user": "postgres", "api.key.empty": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	r := newRedactor(t)
+	stored := map[string]any{"database.password": "s3cret", "sasl.jaas.config": "org.apache...;"}
+
+	tests := []struct {
+		name     string
+		incoming map[string]string
+		want     map[string]string
+		missing  []string
+	}{
+		{
+			name:     "masked secret",
+			incoming: map[string]string{"database.password": DefaultMask, "database.user": "postgres"},
+			want:     map[string]string{"database.password": "s3cret", "database.user": "postgres"},
+		},
+		{
+			name:     "new secret",
+			incoming: map[string]string{"database.password": "n3w"},
+			want:     map[string]string{"database.password": "n3w"},
+		},
+		{
+			// Only secret keys are restored; the mask is a real value elsewhere.
+			name:     "mask in a plain key",
+			incoming: map[string]string{"database.user": DefaultMask},
+			want:     map[string]string{"database.user": DefaultMask},
+		},
+		{
+			name:     "masked secret without a stored value",
+			incoming: map[string]string{"database.password": DefaultMask, "ssl.truststore.password": DefaultMask},
+			want:     map[string]string{"database.password": "s3cret"},
+			missing:  []string{"ssl.truststore.password"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, missing := r.Restore(tt.incoming, stored)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			slices.Sort(missing)
+			if !slices.Equal(missing, tt.missing) {
+				t.Errorf("missing %v, want %v", missing, tt.missing)
+			}
+		})
+	}
+}
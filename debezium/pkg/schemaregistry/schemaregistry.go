@@ -0,0 +1,208 @@
+// Package schemaregistry reads schemas from a Confluent Schema Registry.
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const (
+	TypeAvro = "AVRO"
+
+	defaultTimeout = 10 * time.Second
+
+	// errSubjectNotFound and errSchemaNotFound are the error_code values of
+	// the registry's 404 responses.
+	errSubjectNotFound = 40401
+	errSchemaNotFound  = 40403
+)
+
+var (
+	ErrSchemaNotFound = errors.New("schema not found")
+)
+
+type Config struct {
+	URL      string        `yaml:"url"      env:"SCHEMA_REGISTRY_URL"`
+	Username string        `yaml:"username" env:"SCHEMA_REGISTRY_USERNAME"`
+	Password string        `yaml:"password" env:"SCHEMA_REGISTRY_PASSWORD"`
+	Timeout  time.Duration `yaml:"timeout"  env:"SCHEMA_REGISTRY_TIMEOUT" env-default:"10s"`
+}
+
+// Schema is a registered schema. Type is empty for Avro, as the registry
+// omits it.
+type Schema struct {
+	ID     int    `json:"id"`
+	Type   string `json:"schemaType,omitempty"`
+	Schema string `json:"schema"`
+}
+
+// Avro reports whether the schema is an Avro schema.
+func (s Schema) Avro() bool {
+	return s.Type == "" || s.Type == TypeAvro
+}
+
+// Registry looks up schemas by the ID written in front of every message.
+// IDs never change their schema, so callers may cache the result.
+type Registry interface {
+	SchemaByID(ctx context.Context, id int) (Schema, error)
+}
+
+// Client is the REST client of a Schema Registry.
+type Client struct {
+	hc       *http.Client
+	base     *url.URL
+	username string
+	password string
+}
+
+var _ Registry = (*Client)(nil)
+
+func New(cfg Config) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(cfg.URL, "/"))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("schema registry url %q must be an http or https URL", cfg.URL)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return &Client{
+		hc: &http.Client{
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+		base:     base,
+		username: cfg.Username,
+		password: cfg.Password,
+	}, nil
+}
+
+func (c *Client) SchemaByID(ctx context.Context, id int) (Schema, error) {
+	var s Schema
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &s); err != nil {
+		return Schema{}, fmt.Errorf("SchemaByID: %w", err)
+	}
+	s.ID = id
+	return s, nil
+}
+
+// CheckCompatibility reports whether schema can be registered as the next
+// version of subject under the subject's compatibility level. A subject
+// without versions accepts any schema.
+func (c *Client) CheckCompatibility(ctx context.Context, subject, schema string) (bool, error) {
+	body, err := json.Marshal(Schema{Schema: schema})
+	if err != nil {
+		return false, fmt.Errorf("CheckCompatibility: %w", err)
+	}
+
+	var resp struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	path := "/compatibility/subjects/" + url.PathEscape(subject) + "/versions/latest"
+	err = c.do(ctx, http.MethodPost, path, body, &resp)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Code == errSubjectNotFound {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("CheckCompatibility: %w", err)
+	}
+	return resp.IsCompatible, nil
+}
+
+// APIError is an error response of the registry.
+type APIError struct {
+	Status  int
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("schema registry: %d %s (error code %d)", e.Status, e.Message, e.Code)
+}
+
+func (e *APIError) Unwrap() error {
+	if e.Code == errSchemaNotFound {
+		return ErrSchemaNotFound
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	u := *c.base
+	u.Path += path
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{Status: resp.StatusCode}
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// FileRegistry is a Registry backed by a directory with one <id>.avsc file
+// per schema. It stands in for a registry in tests and local runs.
+type FileRegistry struct {
+	dir string
+}
+
+var _ Registry = (*FileRegistry)(nil)
+
+func NewFileRegistry(dir string) *FileRegistry {
+	return &FileRegistry{dir: dir}
+}
+
+func (r *FileRegistry) SchemaByID(_ context.Context, id int) (Schema, error) {
+	data, err := os.ReadFile(filepath.Join(r.dir, strconv.Itoa(id)+".avsc"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Schema{}, fmt.Errorf("SchemaByID: %w: %d", ErrSchemaNotFound, id)
+	}
+	if err != nil {
+		return Schema{}, fmt.Errorf("SchemaByID: %w", err)
+	}
+	return Schema{ID: id, Type: TypeAvro, Schema: string(data)}, nil
+}
@@ -0,0 +1,94 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileRegistry(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "7.avsc"), []byte(`"string"`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := NewFileRegistry(dir)
+
+	s, err := r.SchemaByID(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("SchemaByID: %v", err)
+	}
+	if s.ID != 7 || !s.Avro() || s.Schema != `"string"` {
+		t.Errorf("got %+v", s)
+	}
+
+	if _, err := r.SchemaByID(context.Background(), 8); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("got error %v for a missing file, want ErrSchemaNotFound", err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "reader" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /schemas/ids/1":
+			w.Write([]byte(`{"schema":"\"string\""}`))
+		case "GET /schemas/ids/2":
+			w.Write([]byte(`{"schemaType":"PROTOBUF","schema":"syntax = \"proto3\";"}`))
+		case "POST /compatibility/subjects/orders-value/versions/latest":
+			w.Write([]byte(`{"is_compatible":false}`))
+		case "POST /compatibility/subjects/new-value/versions/latest":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40401,"message":"Subject 'new-value' not found."}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40403,"message":"Schema not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(Config{URL: srv.URL + "/", Username: "reader", Password: "secret"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := context.Background()
+
+	s, err := c.SchemaByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("SchemaByID: %v", err)
+	}
+	if s.ID != 1 || !s.Avro() || s.Schema != `"string"` {
+		t.Errorf("schema 1 is %+v", s)
+	}
+	if s, err = c.SchemaByID(ctx, 2); err != nil || s.Avro() {
+		t.Errorf("schema 2 is %+v, %v; want a Protobuf schema", s, err)
+	}
+
+	_, err = c.SchemaByID(ctx, 3)
+	var apiErr *APIError
+	if !errors.Is(err, ErrSchemaNotFound) || !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound {
+		t.Errorf("got error %v for an unknown ID, want a 404 ErrSchemaNotFound", err)
+	}
+
+	if ok, err := c.CheckCompatibility(ctx, "orders-value", `"int"`); err != nil || ok {
+		t.Errorf("CheckCompatibility = %t, %v; want false", ok, err)
+	}
+	if ok, err := c.CheckCompatibility(ctx, "new-value", `"int"`); err != nil || !ok {
+		t.Errorf("CheckCompatibility of a new subject = %t, %v; want true", ok, err)
+	}
+}
+
+func TestNewRejectsInvalidURL(t *testing.T) {
+	for _, u := range []string{"", "schema-registry:8081", "ftp://registry"} {
+		if _, err := New(Config{URL: u}); err == nil {
+			t.Errorf("New accepted %q", u)
+		}
+	}
+}
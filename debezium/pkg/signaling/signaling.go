@@ -0,0 +1,321 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Type string
+
+const (
+	TypeExecuteSnapshot Type = "execute-snapshot"
+	TypeStopSnapshot    Type = "stop-snapshot"
+	TypePauseSnapshot   Type = "pause-snapshot"
+	TypeResumeSnapshot  Type = "resume-snapshot"
+
+	// TypeWindowOpen and TypeWindowClose are written by the connector itself
+	// around every incremental snapshot chunk.
+	TypeWindowOpen  Type = "snapshot-window-open"
+	TypeWindowClose Type = "snapshot-window-close"
+)
+
+type SnapshotType string
+
+const (
+	SnapshotIncremental SnapshotType = "incremental"
+	SnapshotBlocking    SnapshotType = "blocking"
+)
+
+const (
+	defaultSchema = "public"
+
+	// DataCollectionProperty is the connector property naming its signal table.
+	DataCollectionProperty = "signal.data.collection"
+)
+
+var (
+	ErrNoDataCollections   = errors.New("at least one data collection is required")
+	ErrUnknownSnapshotType = errors.New("unknown snapshot type")
+	ErrConditionCollection = errors.New("additional condition refers to a data collection that is not snapshotted")
+	ErrSignalNotFound      = errors.New("signal not found")
+	ErrNoSignalDatabase    = errors.New("no signal database configured for the cluster")
+	ErrNoSignalTable       = errors.New("connector has no " + DataCollectionProperty)
+)
+
+// Config is the signaling channel of the default cluster. DSN points at the
+// source database captured by its connectors, not at the server's own
+// database. Table overrides the connector's signal.data.collection.
+type Config struct {
+	DSN   string `yaml:"dsn"   env:"SIGNAL_DSN"`
+	Table string `yaml:"table" env:"SIGNAL_TABLE"`
+}
+
+type Signal struct {
+	ID   string `json:"id"`
+	Type Type   `json:"type"`
+	Data string `json:"data,omitempty"`
+}
+
+type AdditionalCondition struct {
+	DataCollection string `json:"data-collection"`
+	Filter         string `json:"filter"`
+}
+
+// SnapshotData is the payload of execute-snapshot and stop-snapshot signals.
+type SnapshotData struct {
+	DataCollections      []string              `json:"data-collections"`
+	Type                 SnapshotType          `json:"type"`
+	AdditionalConditions []AdditionalCondition `json:"additional-conditions,omitempty"`
+}
+
+// Signaler writes Debezium signals to the source signaling table. The pool
+// must point at the database captured by the connector.
+type Signaler struct {
+	db      *pgxpool.Pool
+	schema  string
+	table   string
+	builder squirrel.StatementBuilderType
+}
+
+func New(db *pgxpool.Pool, table string) *Signaler {
+	schema, name, ok := strings.Cut(table, ".")
+	if !ok {
+		schema, name = defaultSchema, table
+	}
+
+	return &Signaler{
+		db:      db,
+		schema:  schema,
+		table:   name,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// Channel hands out the Signalers of one source database. A connector is
+// signalled through the configured table or, by default, the table in its
+// signal.data.collection property.
+type Channel struct {
+	db    *pgxpool.Pool
+	table string
+
+	mu        sync.Mutex
+	signalers map[string]*Signaler
+}
+
+// NewChannel returns a channel on db, which may be nil if the cluster has no
+// signal database; every For call then fails with ErrNoSignalDatabase.
+func NewChannel(db *pgxpool.Pool, table string) *Channel {
+	return &Channel{db: db, table: table, signalers: make(map[string]*Signaler)}
+}
+
+// For returns the Signaler of the connector with the given config.
+func (c *Channel) For(config map[string]any) (*Signaler, error) {
+	if c.db == nil {
+		return nil, ErrNoSignalDatabase
+	}
+
+	table := c.table
+	if table == "" {
+		table, _ = config[DataCollectionProperty].(string)
+	}
+	if table == "" {
+		return nil, ErrNoSignalTable
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.signalers[table]
+	if !ok {
+		s = New(c.db, table)
+		c.signalers[table] = s
+	}
+	return s, nil
+}
+
+// Close closes the signal database pool.
+func (c *Channel) Close() {
+	if c.db != nil {
+		c.db.Close()
+	}
+}
+
+// DataCollection returns the value for the connector signal.data.collection property.
+func (s *Signaler) DataCollection() string {
+	return s.schema + "." + s.table
+}
+
+// ConnectorConfig returns the connector properties that enable the source
+// signaling channel on this table.
+func (s *Signaler) ConnectorConfig() map[string]string {
+	return map[string]string{
+		"signal.enabled.channels": "source",
+		DataCollectionProperty:    s.DataCollection(),
+	}
+}
+
+// EnsureTable creates the signaling table if it does not exist. Debezium
+// requires exactly the id, type and data columns in this order.
+func (s *Signaler) EnsureTable(ctx context.Context) error {
+	query := "CREATE TABLE IF NOT EXISTS " + s.ident() +
+		" (id VARCHAR(42) PRIMARY KEY, type VARCHAR(32) NOT NULL, data VARCHAR(2048) NULL)"
+
+	if _, err := s.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("ensure table: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Signaler) ExecuteSnapshot(ctx context.Context, data SnapshotData) (Signal, error) {
+	if err := data.validate(); err != nil {
+		return Signal{}, err
+	}
+	if data.Type == "" {
+		data.Type = SnapshotIncremental
+	}
+
+	return s.send(ctx, TypeExecuteSnapshot, data)
+}
+
+// StopSnapshot stops the snapshot of the given data collections, or of all
+// of them when the list is empty.
+func (s *Signaler) StopSnapshot(ctx context.Context, data SnapshotData) (Signal, error) {
+	if data.DataCollections == nil {
+		data.DataCollections = []string{}
+	}
+	if data.Type == "" {
+		data.Type = SnapshotIncremental
+	}
+	if err := data.validateType(); err != nil {
+		return Signal{}, err
+	}
+
+	return s.send(ctx, TypeStopSnapshot, data)
+}
+
+func (s *Signaler) PauseSnapshot(ctx context.Context) (Signal, error) {
+	return s.send(ctx, TypePauseSnapshot, nil)
+}
+
+func (s *Signaler) ResumeSnapshot(ctx context.Context) (Signal, error) {
+	return s.send(ctx, TypeResumeSnapshot, nil)
+}
+
+func (s *Signaler) Get(ctx context.Context, id string) (Signal, error) {
+	query, args, err := s.builder.Select("id", "type", "data").
+		From(s.ident()).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return Signal{}, fmt.Errorf("get: %w", err)
+	}
+
+	var (
+		signal Signal
+		data   *string
+	)
+	err = s.db.QueryRow(ctx, query, args...).Scan(&signal.ID, &signal.Type, &data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Signal{}, ErrSignalNotFound
+	}
+	if err != nil {
+		return Signal{}, fmt.Errorf("get: %w", err)
+	}
+	if data != nil {
+		signal.Data = *data
+	}
+
+	return signal, nil
+}
+
+// CountWindows returns how many snapshot chunks the connector has opened and
+// closed in the signaling table so far.
+func (s *Signaler) CountWindows(ctx context.Context) (int, int, error) {
+	query, args, err := s.builder.Select().
+		Column(squirrel.Expr("COUNT(*) FILTER (WHERE type = ?)", string(TypeWindowOpen))).
+		Column(squirrel.Expr("COUNT(*) FILTER (WHERE type = ?)", string(TypeWindowClose))).
+		From(s.ident()).
+		ToSql()
+	if err != nil {
+		return 0, 0, fmt.Errorf("count windows: %w", err)
+	}
+
+	var opened, closed int
+	if err := s.db.QueryRow(ctx, query, args...).Scan(&opened, &closed); err != nil {
+		return 0, 0, fmt.Errorf("count windows: %w", err)
+	}
+
+	return opened, closed, nil
+}
+
+func (s *Signaler) send(ctx context.Context, signalType Type, data any) (Signal, error) {
+	signal := Signal{
+		ID:   uuid.NewString(),
+		Type: signalType,
+	}
+
+	var payload *string
+	if data != nil {
+		d, err := json.Marshal(data)
+		if err != nil {
+			return Signal{}, fmt.Errorf("send.Marshal: %w", err)
+		}
+		signal.Data = string(d)
+		payload = &signal.Data
+	}
+
+	query, args, err := s.builder.Insert(s.ident()).
+		Columns("id", "type", "data").
+		Values(signal.ID, string(signal.Type), payload).
+		ToSql()
+	if err != nil {
+		return Signal{}, fmt.Errorf("send: %w", err)
+	}
+
+	if _, err := s.db.Exec(ctx, query, args...); err != nil {
+		return Signal{}, fmt.Errorf("send: %w", err)
+	}
+
+	return signal, nil
+}
+
+func (s *Signaler) ident() string {
+	return pgx.Identifier{s.schema, s.table}.Sanitize()
+}
+
+func (d SnapshotData) validate() error {
+	if len(d.DataCollections) == 0 {
+		return ErrNoDataCollections
+	}
+	if err := d.validateType(); err != nil {
+		return err
+	}
+
+	for _, cond := range d.AdditionalConditions {
+		if !slices.Contains(d.DataCollections, cond.DataCollection) {
+			return fmt.Errorf("%w: %s", ErrConditionCollection, cond.DataCollection)
+		}
+	}
+
+	return nil
+}
+
+func (d SnapshotData) validateType() error {
+	switch d.Type {
+	case "", SnapshotIncremental, SnapshotBlocking:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownSnapshotType, d.Type)
+	}
+}
@@ -40,6 +40,13 @@ CREATE TABLE inventory.order_items (
     price DECIMAL(10, 2) NOT NULL
 );
 
+-- Create signaling table for incremental snapshots
+CREATE TABLE inventory.debezium_signal (
+    id VARCHAR(42) PRIMARY KEY,
+    type VARCHAR(32) NOT NULL,
+    data VARCHAR(2048) NULL
+);
+
 -- Insert test data into products
 INSERT INTO inventory.products (name, description, price, quantity) VALUES
 ('Laptop', 'High-performance laptop with 16GB RAM', 1299.99, 50),
@@ -1,5 +1,12 @@
 #!/bin/bash
 
+for tool in curl jq; do
+    if ! command -v "$tool" >/dev/null 2>&1; then
+        echo "$tool is required but not installed (e.g. apt-get install $tool or brew install $tool)." >&2
+        exit 1
+    fi
+done
+
 # Wait for Debezium Connect to be ready
 echo "Waiting for Debezium Connect to start..."
 until curl -s -o /dev/null -w "%{http_code}" http://localhost:8083/connectors | grep -q "200"; do
@@ -9,23 +16,19 @@ done
 
 echo "Debezium Connect is ready!"
 
-# Check if connector already exists
-CONNECTOR_EXISTS=$(curl -s -o /dev/null -w "%{http_code}" http://localhost:8083/connectors/postgres-connector)
-
-if [ "$CONNECTOR_EXISTS" = "200" ]; then
-    echo "Connector 'postgres-connector' already exists. Deleting it first..."
-    curl -X DELETE http://localhost:8083/connectors/postgres-connector
-    sleep 2
-fi
-
-# Register the PostgreSQL connector
+# Create the PostgreSQL connector, or update its config if it already
+# exists. Updating in place keeps the connector's offsets.
 echo "Registering PostgreSQL connector..."
-curl -X POST http://localhost:8083/connectors \
+STATUS=$(jq '.config' postgres-connector.json | curl -s -o /dev/null -w "%{http_code}" \
+    -X PUT http://localhost:8083/connectors/postgres-connector/config \
     -H "Content-Type: application/json" \
-    -d @postgres-connector.json
+    -d @-)
 
-echo ""
-echo "Connector registered successfully!"
+case "$STATUS" in
+    201) echo "Connector 'postgres-connector' created." ;;
+    200) echo "Connector 'postgres-connector' updated." ;;
+    *)   echo "Failed to register connector (HTTP $STATUS)."; exit 1 ;;
+esac
 echo ""
 
 # Check connector status